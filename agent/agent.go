@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"stresspulse/config"
+	"stresspulse/control"
 	"stresspulse/load"
 	"stresspulse/logger"
 	"stresspulse/memory"
@@ -24,7 +26,12 @@ type Agent struct {
 	memGenerator  *memory.MemoryGenerator
 	httpGenerator *network.HTTPGenerator
 	wsGenerator   *network.WebSocketGenerator
+	sseGenerator  *network.SSEGenerator
 	grpcGenerator *network.GRPCGenerator
+	scenarioGenerator *network.ScenarioGenerator
+	authProvider  network.AuthProvider
+	source        *network.SourceBinder
+	captures      *network.CaptureStore
 	fakeLogGen    *logs.FakeLogGenerator
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -52,6 +59,16 @@ type AgentConfig struct {
 		Pattern string            `json:"pattern"`
 		Headers map[string]string `json:"headers"`
 		Body    string            `json:"body"`
+		Requests []*network.RequestTemplate `json:"requests"`
+		Checks   []*network.ResponseCheck   `json:"checks"`
+		OpenAPI  struct {
+			Spec json.RawMessage `json:"spec"`
+			network.OpenAPIOptions
+		} `json:"openapi"`
+		GraphQL        *network.GraphQLMix           `json:"graphql"`
+		MaxConcurrency int `json:"maxConcurrency"`
+		Connection     network.HTTPConnectionOptions `json:"connection"`
+		Proxy          network.ProxyOptions          `json:"proxy"`
 	} `json:"http"`
 	WebSocket struct {
 		Enabled         bool   `json:"enabled"`
@@ -60,7 +77,22 @@ type AgentConfig struct {
 		Pattern         string `json:"pattern"`
 		MessageInterval int    `json:"messageInterval"`
 		MessageSize     int    `json:"messageSize"`
+		Message         string `json:"message"`
+		Script          *network.WebSocketScript `json:"script"`
+		MaxConcurrency  int    `json:"maxConcurrency"`
+		Proxy           network.ProxyOptions `json:"proxy"`
 	} `json:"websocket"`
+	SSE struct {
+		Enabled        bool              `json:"enabled"`
+		URL            string            `json:"url"`
+		CPS            int               `json:"cps"`
+		Pattern        string            `json:"pattern"`
+		Streams        int               `json:"streams"`
+		Hold           network.Duration  `json:"hold"`
+		ReconnectDelay network.Duration  `json:"reconnectDelay"`
+		Headers        map[string]string `json:"headers"`
+		MaxConcurrency int               `json:"maxConcurrency"`
+	} `json:"sse"`
 	GRPC struct {
 		Enabled bool   `json:"enabled"`
 		Address string `json:"address"`
@@ -69,7 +101,24 @@ type AgentConfig struct {
 		Pattern string `json:"pattern"`
 		Secure  bool   `json:"secure"`
 		Service string `json:"service"`
+		MaxConcurrency int `json:"maxConcurrency"`
 	} `json:"grpc"`
+	Scenario struct {
+		Enabled bool   `json:"enabled"`
+		Users   int    `json:"users"`
+		Pattern string `json:"pattern"`
+		BaseURL string `json:"baseUrl"`
+		network.Scenario
+	} `json:"scenario"`
+	Data struct {
+		Mode string              `json:"mode"`
+		Rows []map[string]string `json:"rows"`
+	} `json:"data"`
+	TLS             network.TLSOptions `json:"tls"`
+	Auth            network.AuthOptions `json:"auth"`
+	DNS             network.ResolveOptions `json:"dns"`
+	Source          network.SourceOptions `json:"source"`
+	Capture         network.CaptureOptions `json:"capture"`
 	FakeLogsEnabled bool   `json:"fakeLogsEnabled"`
 	FakeLogsType    string `json:"fakeLogsType"`
 }
@@ -87,8 +136,10 @@ func (a *Agent) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/start", a.handleStart)
 	mux.HandleFunc("/api/stop", a.handleStop)
+	mux.HandleFunc("/api/run", a.handleRun)
 	mux.HandleFunc("/api/stats", a.handleStats)
 	mux.HandleFunc("/api/health", a.handleHealth)
+	mux.HandleFunc("/api/captures", a.handleCaptures)
 
 	a.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", a.port),
@@ -160,12 +211,30 @@ func (a *Agent) validateAgentConfig(config *AgentConfig) error {
 	}
 
 	if config.HTTP.Enabled {
-		if config.HTTP.URL == "" {
+		if config.HTTP.URL == "" && len(config.HTTP.Requests) == 0 && len(config.HTTP.OpenAPI.Spec) == 0 {
 			return fmt.Errorf("HTTP URL cannot be empty")
 		}
+		if len(config.HTTP.Requests) > 0 && len(config.HTTP.OpenAPI.Spec) > 0 {
+			return fmt.Errorf("HTTP requests and OpenAPI spec cannot be used together")
+		}
+		if config.HTTP.GraphQL != nil && (len(config.HTTP.Requests) > 0 || len(config.HTTP.OpenAPI.Spec) > 0) {
+			return fmt.Errorf("HTTP GraphQL operations cannot be combined with requests or OpenAPI spec")
+		}
+		if _, _, err := network.SplitUnixURL(config.HTTP.URL); err != nil {
+			return err
+		}
 		if config.HTTP.RPS <= 0 {
 			return fmt.Errorf("HTTP RPS must be positive")
 		}
+		if config.HTTP.MaxConcurrency < 0 {
+			return fmt.Errorf("HTTP max concurrency must be non-negative")
+		}
+		if err := config.HTTP.Connection.Validate(); err != nil {
+			return err
+		}
+		if err := config.HTTP.Proxy.Validate(); err != nil {
+			return err
+		}
 	}
 
 	if config.WebSocket.Enabled {
@@ -175,6 +244,41 @@ func (a *Agent) validateAgentConfig(config *AgentConfig) error {
 		if config.WebSocket.CPS <= 0 {
 			return fmt.Errorf("WebSocket CPS must be positive")
 		}
+		if config.WebSocket.MaxConcurrency < 0 {
+			return fmt.Errorf("WebSocket max concurrency must be non-negative")
+		}
+		if err := config.WebSocket.Proxy.Validate(); err != nil {
+			return err
+		}
+		if config.WebSocket.Script != nil && config.WebSocket.Message != "" {
+			return fmt.Errorf("WebSocket script and message cannot be used together")
+		}
+	}
+
+	if config.SSE.Enabled {
+		if config.SSE.URL == "" {
+			return fmt.Errorf("SSE URL cannot be empty")
+		}
+		if config.SSE.CPS <= 0 {
+			return fmt.Errorf("SSE CPS must be positive")
+		}
+		if config.SSE.Streams < 0 || config.SSE.MaxConcurrency < 0 {
+			return fmt.Errorf("SSE streams and max concurrency must be non-negative")
+		}
+		if config.SSE.Hold < 0 || config.SSE.ReconnectDelay < 0 {
+			return fmt.Errorf("SSE hold and reconnect delay must be non-negative")
+		}
+		validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+		valid := false
+		for _, pattern := range validPatterns {
+			if config.SSE.Pattern == pattern {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid SSE pattern: %s", config.SSE.Pattern)
+		}
 	}
 
 	if config.GRPC.Enabled {
@@ -184,6 +288,45 @@ func (a *Agent) validateAgentConfig(config *AgentConfig) error {
 		if config.GRPC.RPS <= 0 {
 			return fmt.Errorf("gRPC RPS must be positive")
 		}
+		if config.GRPC.MaxConcurrency < 0 {
+			return fmt.Errorf("gRPC max concurrency must be non-negative")
+		}
+	}
+
+	if config.Scenario.Enabled {
+		if config.Scenario.Users <= 0 && len(config.Scenario.Stages) == 0 {
+			return fmt.Errorf("scenario users must be positive")
+		}
+		if config.Scenario.Pattern != "" {
+			validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+			valid := false
+			for _, pattern := range validPatterns {
+				if config.Scenario.Pattern == pattern {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("invalid scenario pattern: %s", config.Scenario.Pattern)
+			}
+		}
+		if len(config.Scenario.Steps) == 0 {
+			return fmt.Errorf("scenario must contain at least one step")
+		}
+	}
+
+	if len(config.Data.Rows) > 0 && config.Data.Mode != "" {
+		validModes := []string{"sequential", "random", "unique"}
+		valid := false
+		for _, mode := range validModes {
+			if config.Data.Mode == mode {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid data mode: %s", config.Data.Mode)
+		}
 	}
 
 	return nil
@@ -208,13 +351,56 @@ func (a *Agent) handleStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tlsConfig, err := agentConfig.TLS.Build()
+	if err != nil {
+		logger.Error("Agent: Invalid TLS options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid TLS options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	authProvider, err := network.NewAuthProvider(&agentConfig.Auth, tlsConfig)
+	if err != nil {
+		logger.Error("Agent: Invalid auth options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid auth options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	resolver, err := network.NewResolver(&agentConfig.DNS)
+	if err != nil {
+		logger.Error("Agent: Invalid DNS options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid DNS options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	source, err := network.NewSourceBinder(&agentConfig.Source)
+	if err != nil {
+		logger.Error("Agent: Invalid source addresses: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid source addresses: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	captures, err := network.NewCaptureStore(&agentConfig.Capture)
+	if err != nil {
+		logger.Error("Agent: Invalid capture options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid capture options: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	a.stopAllGenerators()
+	a.authProvider = authProvider
+	a.source = source
+	a.captures = captures
 
 	var startErrors []string
 
+	var dataFeeder *network.DataFeeder
+	if len(agentConfig.Data.Rows) > 0 {
+		dataFeeder = network.NewDataFeeder(agentConfig.Data.Rows, agentConfig.Data.Mode)
+	}
+
 	if agentConfig.CPU.Enabled {
 		cpuConfig := &config.Config{
 			TargetCPUPercent: agentConfig.CPU.Load,
@@ -240,8 +426,44 @@ func (a *Agent) handleStart(w http.ResponseWriter, r *http.Request) {
 		if agentConfig.HTTP.Body != "" {
 			a.httpGenerator.SetBody(agentConfig.HTTP.Body)
 		}
-		a.httpGenerator.Start(a.ctx)
-		logger.Info("Agent: HTTP load test started: %s %s at %d RPS", agentConfig.HTTP.Method, agentConfig.HTTP.URL, agentConfig.HTTP.RPS)
+		if dataFeeder != nil {
+			a.httpGenerator.SetDataFeeder(dataFeeder)
+		}
+		a.httpGenerator.SetTLSConfig(tlsConfig)
+		a.httpGenerator.SetAuth(authProvider)
+		a.httpGenerator.SetResolver(resolver)
+		a.httpGenerator.SetSource(source)
+		a.httpGenerator.SetCaptures(captures)
+		if agentConfig.HTTP.MaxConcurrency > 0 {
+			a.httpGenerator.SetMaxConcurrency(agentConfig.HTTP.MaxConcurrency)
+		}
+		templatesErr := a.httpGenerator.SetConnectionOptions(&agentConfig.HTTP.Connection)
+		if templatesErr == nil {
+			templatesErr = a.httpGenerator.SetProxy(&agentConfig.HTTP.Proxy)
+		}
+		if templatesErr == nil && len(agentConfig.HTTP.Checks) > 0 {
+			templatesErr = a.httpGenerator.SetChecks(agentConfig.HTTP.Checks)
+		}
+		if templatesErr == nil && len(agentConfig.HTTP.Requests) > 0 {
+			templatesErr = a.httpGenerator.SetRequestTemplates(agentConfig.HTTP.Requests)
+		}
+		if templatesErr == nil && len(agentConfig.HTTP.OpenAPI.Spec) > 0 {
+			var templates []*network.RequestTemplate
+			templates, templatesErr = network.BuildOpenAPIRequests(agentConfig.HTTP.OpenAPI.Spec, &agentConfig.HTTP.OpenAPI.OpenAPIOptions)
+			if templatesErr == nil {
+				templatesErr = a.httpGenerator.SetRequestTemplates(templates)
+			}
+		}
+		if templatesErr == nil && agentConfig.HTTP.GraphQL != nil {
+			templatesErr = a.httpGenerator.SetGraphQLOperations(agentConfig.HTTP.GraphQL)
+		}
+		if templatesErr != nil {
+			a.httpGenerator = nil
+			startErrors = append(startErrors, fmt.Sprintf("HTTP: %v", templatesErr))
+		} else {
+			a.httpGenerator.Start(a.ctx)
+			logger.Info("Agent: HTTP load test started: %s %s at %d RPS", agentConfig.HTTP.Method, agentConfig.HTTP.URL, agentConfig.HTTP.RPS)
+		}
 	}
 
 	if agentConfig.WebSocket.Enabled {
@@ -252,8 +474,54 @@ func (a *Agent) handleStart(w http.ResponseWriter, r *http.Request) {
 			time.Duration(agentConfig.WebSocket.MessageInterval)*time.Second,
 			agentConfig.WebSocket.MessageSize,
 		)
-		a.wsGenerator.Start(a.ctx)
-		logger.Info("Agent: WebSocket load test started: %s at %d CPS", agentConfig.WebSocket.URL, agentConfig.WebSocket.CPS)
+		if dataFeeder != nil {
+			a.wsGenerator.SetDataFeeder(dataFeeder)
+		}
+		a.wsGenerator.SetTLSConfig(tlsConfig)
+		a.wsGenerator.SetAuth(authProvider)
+		a.wsGenerator.SetResolver(resolver)
+		a.wsGenerator.SetSource(source)
+		if agentConfig.WebSocket.MaxConcurrency > 0 {
+			a.wsGenerator.SetMaxConcurrency(agentConfig.WebSocket.MaxConcurrency)
+		}
+		wsErr := a.wsGenerator.SetProxy(&agentConfig.WebSocket.Proxy)
+		if wsErr == nil && agentConfig.WebSocket.Message != "" {
+			wsErr = a.wsGenerator.SetMessageTemplate(agentConfig.WebSocket.Message)
+		}
+		if wsErr == nil && agentConfig.WebSocket.Script != nil {
+			wsErr = a.wsGenerator.SetScript(agentConfig.WebSocket.Script)
+		}
+		if wsErr != nil {
+			a.wsGenerator = nil
+			startErrors = append(startErrors, fmt.Sprintf("WebSocket: %v", wsErr))
+		} else {
+			a.wsGenerator.Start(a.ctx)
+			logger.Info("Agent: WebSocket load test started: %s at %d CPS", agentConfig.WebSocket.URL, agentConfig.WebSocket.CPS)
+		}
+	}
+
+	if agentConfig.SSE.Enabled {
+		streams := agentConfig.SSE.Streams
+		if streams == 0 {
+			streams = network.DefaultSSEMaxStreams
+		}
+		a.sseGenerator = network.NewSSEGenerator(agentConfig.SSE.URL, agentConfig.SSE.CPS, agentConfig.SSE.Pattern, streams)
+		if agentConfig.SSE.Headers != nil {
+			a.sseGenerator.SetHeaders(agentConfig.SSE.Headers)
+		}
+		a.sseGenerator.SetHoldDuration(time.Duration(agentConfig.SSE.Hold))
+		if agentConfig.SSE.ReconnectDelay > 0 {
+			a.sseGenerator.SetReconnectDelay(time.Duration(agentConfig.SSE.ReconnectDelay))
+		}
+		a.sseGenerator.SetTLSConfig(tlsConfig)
+		a.sseGenerator.SetAuth(authProvider)
+		a.sseGenerator.SetResolver(resolver)
+		a.sseGenerator.SetSource(source)
+		if agentConfig.SSE.MaxConcurrency > 0 {
+			a.sseGenerator.SetMaxConcurrency(agentConfig.SSE.MaxConcurrency)
+		}
+		a.sseGenerator.Start(a.ctx)
+		logger.Info("Agent: SSE load test started: %s at %d CPS, %d streams", agentConfig.SSE.URL, agentConfig.SSE.CPS, streams)
 	}
 
 	if agentConfig.GRPC.Enabled {
@@ -265,6 +533,16 @@ func (a *Agent) handleStart(w http.ResponseWriter, r *http.Request) {
 			agentConfig.GRPC.Method,
 			agentConfig.GRPC.Secure,
 		)
+		if dataFeeder != nil {
+			a.grpcGenerator.SetDataFeeder(dataFeeder)
+		}
+		a.grpcGenerator.SetTLSConfig(tlsConfig)
+		a.grpcGenerator.SetAuth(authProvider)
+		a.grpcGenerator.SetResolver(resolver)
+		a.grpcGenerator.SetSource(source)
+		if agentConfig.GRPC.MaxConcurrency > 0 {
+			a.grpcGenerator.SetMaxConcurrency(agentConfig.GRPC.MaxConcurrency)
+		}
 		if err := a.grpcGenerator.Start(a.ctx); err != nil {
 			logger.Error("Agent: Failed to start gRPC generator: %v", err)
 			startErrors = append(startErrors, fmt.Sprintf("gRPC: %v", err))
@@ -273,6 +551,28 @@ func (a *Agent) handleStart(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if agentConfig.Scenario.Enabled {
+		a.scenarioGenerator = network.NewScenarioGenerator(
+			&agentConfig.Scenario.Scenario,
+			agentConfig.Scenario.BaseURL,
+			agentConfig.Scenario.Users,
+			10*time.Second,
+		)
+		if dataFeeder != nil {
+			a.scenarioGenerator.SetDataFeeder(dataFeeder)
+		}
+		a.scenarioGenerator.SetTLSConfig(tlsConfig)
+		a.scenarioGenerator.SetAuth(authProvider)
+		a.scenarioGenerator.SetUserPattern(agentConfig.Scenario.Pattern)
+		if err := a.scenarioGenerator.Start(a.ctx); err != nil {
+			logger.Error("Agent: Failed to start scenario generator: %v", err)
+			startErrors = append(startErrors, fmt.Sprintf("Scenario: %v", err))
+			a.scenarioGenerator = nil
+		} else {
+			logger.Info("Agent: Scenario started: %s with %d virtual users", agentConfig.Scenario.Name, agentConfig.Scenario.Users)
+		}
+	}
+
 	if agentConfig.FakeLogsEnabled {
 		a.fakeLogGen = logs.NewFakeLogGenerator(agentConfig.FakeLogsType, 1*time.Second, logger.GetLogger())
 		a.fakeLogGen.Start(a.ctx)
@@ -307,6 +607,48 @@ func (a *Agent) handleStop(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
 }
 
+// handleRun меняет параметры запущенных генераторов без их перезапуска.
+func (a *Agent) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var update control.RunUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	generators := &control.Generators{
+		CPU:       a.cpuGenerator,
+		Memory:    a.memGenerator,
+		HTTP:      a.httpGenerator,
+		WebSocket: a.wsGenerator,
+		SSE:       a.sseGenerator,
+		GRPC:      a.grpcGenerator,
+		Scenario:  a.scenarioGenerator,
+	}
+
+	applied, err := control.Apply(generators, &update)
+	if err != nil {
+		logger.Error("Agent: run update rejected: %v", err)
+		http.Error(w, fmt.Sprintf("Run update rejected: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Agent: run updated: %s", strings.Join(applied, ", "))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "updated",
+		"applied": applied,
+	})
+}
+
 func (a *Agent) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -365,6 +707,25 @@ func (a *Agent) handleStats(w http.ResponseWriter, r *http.Request) {
 			"FailedRequests":    httpStats.FailedRequests,
 			"SuccessRate":       a.httpGenerator.GetSuccessRate(),
 			"AverageResponseTime": a.httpGenerator.GetAverageResponseTime().String(),
+			"Latency":           httpStats.Latency,
+			"LatencyHistogram":  a.httpGenerator.GetLatencyHistogram().Data(),
+			"ServiceTime":       httpStats.ServiceTime,
+			"DroppedRequests":   httpStats.DroppedRequests,
+			"LateRequests":      httpStats.LateRequests,
+			"Workers":           httpStats.Workers,
+			"StatusCodes":       httpStats.StatusCodes,
+			"ErrorClasses":      httpStats.ErrorClasses,
+			"Endpoints":         httpStats.Endpoints,
+			"Checks":            httpStats.Checks,
+			"CheckFailures":     httpStats.CheckFailures,
+			"Phases":            httpStats.Phases,
+			"Protocols":         httpStats.Protocols,
+			"Addresses":         httpStats.Addresses,
+			"NewConnections":    httpStats.NewConnections,
+			"ReusedConnections": httpStats.ReusedConnections,
+			"ConnectionReuse":   httpStats.ConnectionReuse,
+			"BytesSent":         httpStats.BytesSent,
+			"BytesReceived":     httpStats.BytesReceived,
 			"StartTime":         httpStats.StartTime,
 		}
 	}
@@ -376,20 +737,88 @@ func (a *Agent) handleStats(w http.ResponseWriter, r *http.Request) {
 			"ActiveConnections": wsStats.ActiveConnections,
 			"TotalConnections":  wsStats.TotalConnections,
 			"SuccessRate":       a.wsGenerator.GetSuccessRate(),
+			"Latency":           wsStats.Latency,
+			"LatencyHistogram":  a.wsGenerator.GetLatencyHistogram().Data(),
+			"ServiceTime":       wsStats.ServiceTime,
+			"ProxyConnect":      wsStats.ProxyConnect,
+			"Addresses":         wsStats.Addresses,
+			"DroppedConnections": wsStats.DroppedConnections,
+			"LateConnections":   wsStats.LateConnections,
+			"Workers":           wsStats.Workers,
+			"Steps":             wsStats.Steps,
 			"StartTime":         wsStats.StartTime,
 		}
 	}
 
+	if a.sseGenerator != nil {
+		sseStats := a.sseGenerator.GetStats()
+		stats["sse"] = map[string]interface{}{
+			"CurrentCPS":         sseStats.CurrentCPS,
+			"ActiveStreams":      sseStats.ActiveStreams,
+			"TotalConnections":   sseStats.TotalConnections,
+			"SuccessRate":        a.sseGenerator.GetSuccessRate(),
+			"EventsReceived":     sseStats.EventsReceived,
+			"Reconnects":         sseStats.Reconnects,
+			"BytesReceived":      sseStats.BytesReceived,
+			"Latency":            sseStats.Latency,
+			"LatencyHistogram":   a.sseGenerator.GetLatencyHistogram().Data(),
+			"ServiceTime":        sseStats.ServiceTime,
+			"InterArrival":       sseStats.InterArrival,
+			"EventTypes":         sseStats.EventTypes,
+			"DroppedConnections": sseStats.DroppedConnections,
+			"LateConnections":    sseStats.LateConnections,
+			"Workers":            sseStats.Workers,
+			"StartTime":          sseStats.StartTime,
+		}
+	}
+
 	if a.grpcGenerator != nil {
 		grpcStats := a.grpcGenerator.GetStats()
 		stats["grpc"] = map[string]interface{}{
 			"CurrentRPS":    grpcStats.CurrentRPS,
 			"TotalRequests": grpcStats.TotalRequests,
 			"SuccessRate":   a.grpcGenerator.GetSuccessRate(),
+			"Latency":       grpcStats.Latency,
+			"LatencyHistogram": a.grpcGenerator.GetLatencyHistogram().Data(),
+			"ServiceTime":   grpcStats.ServiceTime,
+			"Addresses":     grpcStats.Addresses,
+			"DroppedRequests": grpcStats.DroppedRequests,
+			"LateRequests":  grpcStats.LateRequests,
+			"Workers":       grpcStats.Workers,
 			"StartTime":     grpcStats.StartTime,
 		}
 	}
 
+	if a.scenarioGenerator != nil {
+		scenarioStats := a.scenarioGenerator.GetStats()
+		stats["scenario"] = map[string]interface{}{
+			"ActiveUsers":      scenarioStats.ActiveUsers,
+			"TargetUsers":      scenarioStats.TargetUsers,
+			"CurrentIPS":       scenarioStats.CurrentIPS,
+			"CurrentRPS":       scenarioStats.CurrentRPS,
+			"AvgIPS":           scenarioStats.AvgIPS,
+			"AvgRPS":           scenarioStats.AvgRPS,
+			"TotalIterations":  scenarioStats.TotalIterations,
+			"FailedIterations": scenarioStats.FailedIterations,
+			"TotalRequests":    scenarioStats.TotalRequests,
+			"SuccessRate":      a.scenarioGenerator.GetSuccessRate(),
+			"Iteration":        scenarioStats.Iteration,
+			"ErrorClasses":     scenarioStats.ErrorClasses,
+			"Steps":            scenarioStats.Steps,
+			"Checks":           scenarioStats.Checks,
+			"CheckFailures":    scenarioStats.CheckFailures,
+			"StartTime":        scenarioStats.StartTime,
+		}
+	}
+
+	if a.authProvider != nil {
+		stats["auth"] = a.authProvider.Stats()
+	}
+
+	if a.source != nil {
+		stats["sources"] = a.source.GetStats()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
@@ -404,6 +833,31 @@ func (a *Agent) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
+// handleCaptures отдаёт сохранённые запросы последнего теста; reason=failed или slow фильтрует по причине.
+func (a *Agent) handleCaptures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reason := r.URL.Query().Get("reason")
+	if reason != "" && reason != network.CaptureFailed && reason != network.CaptureSlow {
+		http.Error(w, "Invalid reason, expected failed or slow", http.StatusBadRequest)
+		return
+	}
+
+	a.mu.RLock()
+	captures := a.captures
+	a.mu.RUnlock()
+	if captures == nil {
+		http.Error(w, "Request capture is not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(captures.Snapshot(reason))
+}
+
 func (a *Agent) stopAllGenerators() {
 	if a.cpuGenerator != nil {
 		a.cpuGenerator.Stop()
@@ -418,6 +872,12 @@ func (a *Agent) stopAllGenerators() {
 	if a.httpGenerator != nil {
 		a.httpGenerator.Stop()
 		a.httpGenerator = nil
+
+		if a.captures != nil && a.captures.File() != "" {
+			if err := a.captures.WriteFile(a.captures.File()); err != nil {
+				logger.Error("Agent: Failed to write captures to %s: %v", a.captures.File(), err)
+			}
+		}
 	}
 
 	if a.wsGenerator != nil {
@@ -425,11 +885,24 @@ func (a *Agent) stopAllGenerators() {
 		a.wsGenerator = nil
 	}
 
+	if a.sseGenerator != nil {
+		a.sseGenerator.Stop()
+		a.sseGenerator = nil
+	}
+
 	if a.grpcGenerator != nil {
 		a.grpcGenerator.Stop()
 		a.grpcGenerator = nil
 	}
 
+	if a.scenarioGenerator != nil {
+		a.scenarioGenerator.Stop()
+		a.scenarioGenerator = nil
+	}
+
+	a.authProvider = nil
+	a.source = nil
+
 	if a.fakeLogGen != nil {
 		a.fakeLogGen.Stop()
 		a.fakeLogGen = nil
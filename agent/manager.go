@@ -8,10 +8,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"stresspulse/logger"
+	"stresspulse/network"
 )
 
 type AgentManager struct {
@@ -32,6 +34,22 @@ type AgentStats struct {
 	Stats   map[string]interface{} `json:"stats"`
 }
 
+// AggregatedLatency - задержки одного генератора по всем агентам, посчитанные по сложенным гистограммам.
+type AggregatedLatency struct {
+	Agents  []string                   `json:"agents"`
+	Count   int64                      `json:"count"`
+	Latency network.LatencyPercentiles `json:"latency"`
+}
+
+// AggregatedStats - сводка задержек агентов; агенты, с которых не удалось получить статистику, перечислены в Failed.
+type AggregatedStats struct {
+	Generators map[string]*AggregatedLatency `json:"generators"`
+	Failed     map[string]string             `json:"failed,omitempty"`
+}
+
+// aggregatedGenerators - секции /api/stats агента, в которых есть гистограмма задержек.
+var aggregatedGenerators = []string{"http", "websocket", "sse", "grpc"}
+
 type AgentsData struct {
 	Agents map[string]*AgentInfo `json:"agents"`
 }
@@ -270,6 +288,67 @@ func (am *AgentManager) GetStats(ctx context.Context, agentID string) (*AgentSta
 	}, nil
 }
 
+// AggregateLatency собирает гистограммы задержек со всех агентов и считает общие перцентили по каждому генератору.
+// Перцентили агентов нельзя усреднить, поэтому складываются бакеты гистограмм.
+func (am *AgentManager) AggregateLatency(ctx context.Context) *AggregatedStats {
+	am.mu.RLock()
+	agentIDs := make([]string, 0, len(am.agents))
+	for agentID := range am.agents {
+		agentIDs = append(agentIDs, agentID)
+	}
+	am.mu.RUnlock()
+	sort.Strings(agentIDs)
+
+	result := &AggregatedStats{Generators: make(map[string]*AggregatedLatency), Failed: make(map[string]string)}
+	histograms := make(map[string]*network.LatencyHistogram)
+
+	for _, agentID := range agentIDs {
+		stats, err := am.GetStats(ctx, agentID)
+		if err != nil {
+			result.Failed[agentID] = err.Error()
+			continue
+		}
+
+		for _, generator := range aggregatedGenerators {
+			section, ok := stats.Stats[generator].(map[string]interface{})
+			if !ok || section["LatencyHistogram"] == nil {
+				continue
+			}
+			histogram, err := decodeHistogram(section["LatencyHistogram"])
+			if err != nil {
+				result.Failed[agentID] = fmt.Sprintf("%s latency histogram: %v", generator, err)
+				continue
+			}
+
+			if histograms[generator] == nil {
+				histograms[generator] = network.NewLatencyHistogram()
+				result.Generators[generator] = &AggregatedLatency{}
+			}
+			histograms[generator].Merge(histogram)
+			result.Generators[generator].Agents = append(result.Generators[generator].Agents, agentID)
+		}
+	}
+
+	for generator, histogram := range histograms {
+		result.Generators[generator].Count = histogram.Count()
+		result.Generators[generator].Latency = histogram.Percentiles()
+	}
+	return result
+}
+
+// decodeHistogram разбирает гистограмму из уже декодированного JSON ответа агента.
+func decodeHistogram(value interface{}) (*network.LatencyHistogram, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var data network.HistogramData
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, err
+	}
+	return data.Histogram()
+}
+
 func (am *AgentManager) CheckHealth(ctx context.Context) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
@@ -2,7 +2,10 @@ package config
 
 import (
 	"flag"
+	"regexp"
 	"time"
+
+	"stresspulse/network"
 )
 
 type Config struct {
@@ -34,6 +37,28 @@ type Config struct {
 	HTTPTimeout       time.Duration
 	HTTPHeaders       string
 	HTTPBody          string
+	HTTPRequestsFile  string
+	HTTPOpenAPIFile   string
+	HTTPOpenAPIURL    string
+	HTTPOpenAPITags   string
+	HTTPOpenAPIOperations string
+	HTTPGraphQLFile   string
+	HTTPGraphQLAPQ    bool
+	HTTPCheckStatus   string
+	HTTPCheckBody     string
+	HTTPCheckRegex    string
+	HTTPCheckJSON     string
+	HTTPCheckHeader   string
+	HTTPMaxLatency    time.Duration
+	HTTPMaxConcurrency int
+	HTTPProtocol       string
+	HTTPKeepAlive      bool
+	HTTPMaxConnsPerHost int
+	HTTPConnMaxLifetime time.Duration
+	HTTPMaxRequestsPerConn int
+	HTTPMaxStreamsPerConn  int
+	HTTPProxy              string
+	HTTPNoProxy            string
 
 	WebSocketEnabled         bool
 	WebSocketTargetURL       string
@@ -42,6 +67,21 @@ type Config struct {
 	WebSocketMessageInterval time.Duration
 	WebSocketMessageSize     int
 	WebSocketHeaders         string
+	WebSocketMessage         string
+	WebSocketScript          string
+	WebSocketMaxConcurrency  int
+	WebSocketProxy           string
+	WebSocketNoProxy         string
+
+	SSEEnabled        bool
+	SSETargetURL      string
+	SSETargetCPS      int
+	SSEPattern        string
+	SSEMaxStreams     int
+	SSEHoldDuration   time.Duration
+	SSEReconnectDelay time.Duration
+	SSEHeaders        string
+	SSEMaxConcurrency int
 
 	GRPCEnabled      bool
 	GRPCTargetAddr   string
@@ -51,10 +91,72 @@ type Config struct {
 	GRPCMethodType   string
 	GRPCUseSecure    bool
 	GRPCMetadata     string
+	GRPCMaxConcurrency int
+
+	ChurnEnabled      bool
+	ChurnTargetAddr   string
+	ChurnTargetCPS    int
+	ChurnPattern      string
+	ChurnUseTLS       bool
+	ChurnHoldDuration time.Duration
+	ChurnTimeout      time.Duration
+
+	ScenarioFile    string
+	ScenarioUsers   int
+	ScenarioBaseURL string
+	ScenarioPattern string
+	ScenarioStages  string
+	ScenarioThinkTime string
+
+	DataFile string
+	DataMode string
+
+	TLSCAFile     string
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSServerName string
+	TLSMinVersion string
+	TLSMaxVersion string
+	TLSCiphers    string
+	TLSALPN       string
+	TLSInsecure   bool
+	TLSResumption bool
+
+	DNSResolve []string
+	DNSServer  string
+	DNSPolicy  string
+	DNSPinIP   string
+
+	SourceAddresses string
+	SourceInterface string
+
+	CaptureEnabled  bool
+	CaptureFirst    int
+	CaptureSample   int
+	CaptureSlow     time.Duration
+	CaptureBodySize int
+	CaptureFile     string
+
+	AuthType          string
+	AuthToken         string
+	AuthHeader        string
+	AuthScheme        string
+	AuthUsername      string
+	AuthPassword      string
+	AuthTokenURL      string
+	AuthClientID      string
+	AuthClientSecret  string
+	AuthScopes        string
+	AuthHMACKeyID     string
+	AuthHMACSecret    string
+	AuthHMACHeaders   string
+	AuthHMACAlgorithm string
 
 	WebEnabled       bool
 	WebPort          int
 
+	ControlSocket string
+
 	AgentMode bool `json:"agent_mode"`
 	AgentPort int  `json:"agent_port"`
 }
@@ -87,6 +189,26 @@ func NewConfig() *Config {
 		HTTPTimeout:      5 * time.Second,
 		HTTPHeaders:      "",
 		HTTPBody:         "",
+		HTTPRequestsFile: "",
+		HTTPOpenAPIFile:  "",
+		HTTPOpenAPIURL:   "",
+		HTTPOpenAPITags:  "",
+		HTTPOpenAPIOperations: "",
+		HTTPGraphQLFile:  "",
+		HTTPGraphQLAPQ:   false,
+		HTTPCheckStatus:  "",
+		HTTPCheckBody:    "",
+		HTTPCheckRegex:   "",
+		HTTPCheckJSON:    "",
+		HTTPCheckHeader:  "",
+		HTTPMaxLatency:   0,
+		HTTPMaxConcurrency: network.DefaultHTTPMaxConcurrency,
+		HTTPProtocol:       network.HTTPProtocolAuto,
+		HTTPKeepAlive:      true,
+		HTTPMaxConnsPerHost: 0,
+		HTTPConnMaxLifetime: 0,
+		HTTPMaxRequestsPerConn: 0,
+		HTTPMaxStreamsPerConn:  0,
 
 		WebSocketEnabled:         false,
 		WebSocketTargetURL:       "ws://localhost:8080/ws",
@@ -95,6 +217,19 @@ func NewConfig() *Config {
 		WebSocketMessageInterval: 1 * time.Second,
 		WebSocketMessageSize:     256,
 		WebSocketHeaders:         "",
+		WebSocketMessage:         "",
+		WebSocketScript:          "",
+		WebSocketMaxConcurrency:  network.DefaultWebSocketMaxConcurrency,
+
+		SSEEnabled:        false,
+		SSETargetURL:      "http://localhost:8080/events",
+		SSETargetCPS:      10,
+		SSEPattern:        "constant",
+		SSEMaxStreams:     network.DefaultSSEMaxStreams,
+		SSEHoldDuration:   0,
+		SSEReconnectDelay: network.DefaultSSEReconnectDelay,
+		SSEHeaders:        "",
+		SSEMaxConcurrency: network.DefaultSSEMaxConcurrency,
 
 		GRPCEnabled:     false,
 		GRPCTargetAddr:  "localhost:9000",
@@ -104,6 +239,65 @@ func NewConfig() *Config {
 		GRPCMethodType:  "health_check",
 		GRPCUseSecure:   false,
 		GRPCMetadata:    "",
+		GRPCMaxConcurrency: network.DefaultGRPCMaxConcurrency,
+
+		ChurnEnabled:      false,
+		ChurnTargetAddr:   "localhost:8080",
+		ChurnTargetCPS:    100,
+		ChurnPattern:      "constant",
+		ChurnUseTLS:       false,
+		ChurnHoldDuration: 0,
+		ChurnTimeout:      5 * time.Second,
+
+		ScenarioFile:    "",
+		ScenarioUsers:   10,
+		ScenarioBaseURL: "",
+		ScenarioPattern: "constant",
+		ScenarioStages:  "",
+		ScenarioThinkTime: "",
+
+		DataFile: "",
+		DataMode: "sequential",
+
+		TLSCAFile:     "",
+		TLSCertFile:   "",
+		TLSKeyFile:    "",
+		TLSServerName: "",
+		TLSMinVersion: "",
+		TLSMaxVersion: "",
+		TLSCiphers:    "",
+		TLSALPN:       "",
+		TLSInsecure:   false,
+		TLSResumption: true,
+
+		DNSServer: "",
+		DNSPolicy: network.DNSPolicyDefault,
+		DNSPinIP:  "",
+
+		SourceAddresses: "",
+		SourceInterface: "",
+
+		CaptureEnabled:  false,
+		CaptureFirst:    network.DefaultCaptureFirst,
+		CaptureSample:   network.DefaultCaptureSample,
+		CaptureSlow:     0,
+		CaptureBodySize: network.DefaultCaptureBodySize,
+		CaptureFile:     "",
+
+		AuthType:          "",
+		AuthToken:         "",
+		AuthHeader:        "Authorization",
+		AuthScheme:        "Bearer",
+		AuthUsername:      "",
+		AuthPassword:      "",
+		AuthTokenURL:      "",
+		AuthClientID:      "",
+		AuthClientSecret:  "",
+		AuthScopes:        "",
+		AuthHMACKeyID:     "",
+		AuthHMACSecret:    "",
+		AuthHMACHeaders:   "(request-target),date",
+		AuthHMACAlgorithm: "sha256",
 
 		WebEnabled:      false,
 		WebPort:         8080,
@@ -140,6 +334,28 @@ func (c *Config) ParseFlags() {
 	flag.DurationVar(&c.HTTPTimeout, "http-timeout", c.HTTPTimeout, "Таймаут HTTP запросов")
 	flag.StringVar(&c.HTTPHeaders, "http-headers", c.HTTPHeaders, "HTTP заголовки в формате 'Key1:Value1,Key2:Value2'")
 	flag.StringVar(&c.HTTPBody, "http-body", c.HTTPBody, "Тело HTTP запроса")
+	flag.StringVar(&c.HTTPRequestsFile, "http-requests", c.HTTPRequestsFile, "JSON файл со взвешенным набором HTTP запросов")
+	flag.StringVar(&c.HTTPOpenAPIFile, "http-openapi", c.HTTPOpenAPIFile, "OpenAPI 3 спецификация (JSON), по операциям которой строится набор запросов")
+	flag.StringVar(&c.HTTPOpenAPIURL, "http-openapi-url", c.HTTPOpenAPIURL, "Базовый адрес для OpenAPI запросов вместо servers[0]")
+	flag.StringVar(&c.HTTPOpenAPITags, "http-openapi-tags", c.HTTPOpenAPITags, "Брать только операции с этими тегами, через запятую")
+	flag.StringVar(&c.HTTPOpenAPIOperations, "http-openapi-operations", c.HTTPOpenAPIOperations, "Брать только эти operationId, через запятую")
+	flag.StringVar(&c.HTTPGraphQLFile, "http-graphql", c.HTTPGraphQLFile, "JSON файл со взвешенным набором GraphQL операций, отправляемых POST на -http-url")
+	flag.BoolVar(&c.HTTPGraphQLAPQ, "http-graphql-apq", c.HTTPGraphQLAPQ, "Отправлять GraphQL операции как automatic persisted queries (sha256 хеш вместо текста)")
+	flag.StringVar(&c.HTTPCheckStatus, "http-check-status", c.HTTPCheckStatus, "Допустимые статусы ответа, например '200,201'")
+	flag.StringVar(&c.HTTPCheckBody, "http-check-body", c.HTTPCheckBody, "Подстрока, которая должна быть в теле ответа")
+	flag.StringVar(&c.HTTPCheckRegex, "http-check-regex", c.HTTPCheckRegex, "Регулярное выражение для тела ответа")
+	flag.StringVar(&c.HTTPCheckJSON, "http-check-json", c.HTTPCheckJSON, "Проверка JSON ответа в формате 'path=value' или 'path'")
+	flag.StringVar(&c.HTTPCheckHeader, "http-check-header", c.HTTPCheckHeader, "Заголовок, который должен быть в ответе")
+	flag.DurationVar(&c.HTTPMaxLatency, "http-max-latency", c.HTTPMaxLatency, "Бюджет времени ответа, медленные ответы считаются ошибкой")
+	flag.IntVar(&c.HTTPMaxConcurrency, "http-max-concurrency", c.HTTPMaxConcurrency, "Максимум одновременных HTTP запросов, до которого растёт пул воркеров")
+	flag.StringVar(&c.HTTPProtocol, "http-protocol", c.HTTPProtocol, "Протокол HTTP: auto, h1, h2 (HTTP/2 по TLS), h2c (HTTP/2 без TLS)")
+	flag.BoolVar(&c.HTTPKeepAlive, "http-keep-alive", c.HTTPKeepAlive, "Переиспользовать соединения; false - новое соединение на каждый запрос")
+	flag.IntVar(&c.HTTPMaxConnsPerHost, "http-max-conns-per-host", c.HTTPMaxConnsPerHost, "Максимум соединений к одному хосту (0 - без ограничения)")
+	flag.DurationVar(&c.HTTPConnMaxLifetime, "http-conn-max-lifetime", c.HTTPConnMaxLifetime, "Время жизни соединения, после которого оно пересоздаётся (0 - без ограничения)")
+	flag.IntVar(&c.HTTPMaxRequestsPerConn, "http-max-requests-per-conn", c.HTTPMaxRequestsPerConn, "Запросов на соединение, после которых оно пересоздаётся (0 - без ограничения)")
+	flag.IntVar(&c.HTTPMaxStreamsPerConn, "http-max-streams-per-conn", c.HTTPMaxStreamsPerConn, "Максимум одновременных запросов в одном HTTP/2 соединении для h2 и h2c (0 - как разрешит сервер)")
+	flag.StringVar(&c.HTTPProxy, "http-proxy", c.HTTPProxy, "Прокси для HTTP запросов: http://, https:// (CONNECT), socks5://, socks5h://, с user:pass@ для авторизации")
+	flag.StringVar(&c.HTTPNoProxy, "http-no-proxy", c.HTTPNoProxy, "Адреса в обход прокси через запятую, как NO_PROXY: хост, .домен, IP, CIDR, host:port или *")
 	
 	flag.BoolVar(&c.WebSocketEnabled, "websocket", c.WebSocketEnabled, "Включение WebSocket нагрузочного тестирования")
 	flag.StringVar(&c.WebSocketTargetURL, "websocket-url", c.WebSocketTargetURL, "URL для WebSocket соединений")
@@ -148,6 +364,21 @@ func (c *Config) ParseFlags() {
 	flag.DurationVar(&c.WebSocketMessageInterval, "websocket-message-interval", c.WebSocketMessageInterval, "Интервал отправки сообщений")
 	flag.IntVar(&c.WebSocketMessageSize, "websocket-message-size", c.WebSocketMessageSize, "Размер сообщений в байтах")
 	flag.StringVar(&c.WebSocketHeaders, "websocket-headers", c.WebSocketHeaders, "WebSocket заголовки в формате 'Key1:Value1,Key2:Value2'")
+	flag.StringVar(&c.WebSocketMessage, "websocket-message", c.WebSocketMessage, "Шаблон WebSocket сообщения вместо заполнителя")
+	flag.StringVar(&c.WebSocketScript, "websocket-script", c.WebSocketScript, "JSON файл скрипта диалога: отправка сообщений, ожидание ответов и извлечение значений на каждом соединении")
+	flag.IntVar(&c.WebSocketMaxConcurrency, "websocket-max-concurrency", c.WebSocketMaxConcurrency, "Максимум одновременно открываемых WebSocket соединений, до которого растёт пул воркеров")
+	flag.StringVar(&c.WebSocketProxy, "websocket-proxy", c.WebSocketProxy, "Прокси для WebSocket соединений: http://, https:// (CONNECT), socks5://, socks5h://, с user:pass@ для авторизации")
+	flag.StringVar(&c.WebSocketNoProxy, "websocket-no-proxy", c.WebSocketNoProxy, "Адреса в обход WebSocket прокси через запятую, как NO_PROXY")
+	
+	flag.BoolVar(&c.SSEEnabled, "sse", c.SSEEnabled, "Включение нагрузки Server-Sent Events (долгие text/event-stream потоки)")
+	flag.StringVar(&c.SSETargetURL, "sse-url", c.SSETargetURL, "URL потока событий")
+	flag.IntVar(&c.SSETargetCPS, "sse-cps", c.SSETargetCPS, "Сколько новых потоков открывать в секунду")
+	flag.StringVar(&c.SSEPattern, "sse-pattern", c.SSEPattern, "Паттерн открытия потоков (constant, spike, cycle, ramp, random)")
+	flag.IntVar(&c.SSEMaxStreams, "sse-streams", c.SSEMaxStreams, "Сколько потоков держать открытыми одновременно")
+	flag.DurationVar(&c.SSEHoldDuration, "sse-hold", c.SSEHoldDuration, "Время жизни потока, после которого он закрывается (0 - до конца теста)")
+	flag.DurationVar(&c.SSEReconnectDelay, "sse-reconnect-delay", c.SSEReconnectDelay, "Пауза перед переподключением, если сервер не прислал retry")
+	flag.StringVar(&c.SSEHeaders, "sse-headers", c.SSEHeaders, "SSE заголовки в формате 'Key1:Value1,Key2:Value2'")
+	flag.IntVar(&c.SSEMaxConcurrency, "sse-max-concurrency", c.SSEMaxConcurrency, "Максимум одновременно открываемых SSE соединений, до которого растёт пул воркеров")
 	
 	flag.BoolVar(&c.GRPCEnabled, "grpc", c.GRPCEnabled, "Включение gRPC нагрузочного тестирования")
 	flag.StringVar(&c.GRPCTargetAddr, "grpc-addr", c.GRPCTargetAddr, "Адрес gRPC сервера")
@@ -157,9 +388,73 @@ func (c *Config) ParseFlags() {
 	flag.StringVar(&c.GRPCMethodType, "grpc-method", c.GRPCMethodType, "Тип gRPC метода (health_check, unary, server_stream, client_stream, bidi_stream)")
 	flag.BoolVar(&c.GRPCUseSecure, "grpc-secure", c.GRPCUseSecure, "Использовать TLS для gRPC соединений")
 	flag.StringVar(&c.GRPCMetadata, "grpc-metadata", c.GRPCMetadata, "gRPC метаданные в формате 'Key1:Value1,Key2:Value2'")
+	flag.IntVar(&c.GRPCMaxConcurrency, "grpc-max-concurrency", c.GRPCMaxConcurrency, "Максимум одновременных gRPC вызовов, до которого растёт пул воркеров")
+	
+	flag.BoolVar(&c.ChurnEnabled, "churn", c.ChurnEnabled, "Включение генератора TCP соединений (connection churn)")
+	flag.StringVar(&c.ChurnTargetAddr, "churn-addr", c.ChurnTargetAddr, "Адрес host:port для открытия соединений")
+	flag.IntVar(&c.ChurnTargetCPS, "churn-cps", c.ChurnTargetCPS, "Целевое количество новых соединений в секунду")
+	flag.StringVar(&c.ChurnPattern, "churn-pattern", c.ChurnPattern, "Паттерн открытия соединений (constant, spike, cycle, ramp, random)")
+	flag.BoolVar(&c.ChurnUseTLS, "churn-tls", c.ChurnUseTLS, "Выполнять TLS handshake после установки соединения")
+	flag.DurationVar(&c.ChurnHoldDuration, "churn-hold", c.ChurnHoldDuration, "Сколько держать соединение открытым (0 - закрывать сразу)")
+	flag.DurationVar(&c.ChurnTimeout, "churn-timeout", c.ChurnTimeout, "Таймаут установки соединения")
+	
+	flag.StringVar(&c.ScenarioFile, "scenario", c.ScenarioFile, "JSON файл сценария виртуальных пользователей")
+	flag.IntVar(&c.ScenarioUsers, "scenario-users", c.ScenarioUsers, "Количество виртуальных пользователей сценария")
+	flag.StringVar(&c.ScenarioBaseURL, "scenario-url", c.ScenarioBaseURL, "Базовый URL для относительных путей в шагах сценария")
+	flag.StringVar(&c.ScenarioPattern, "scenario-pattern", c.ScenarioPattern, "Паттерн числа пользователей: constant, spike, cycle, ramp, random")
+	flag.StringVar(&c.ScenarioStages, "scenario-stages", c.ScenarioStages, "Ступени числа пользователей (например: 30s:50,2m:500,30s:0)")
+	flag.StringVar(&c.ScenarioThinkTime, "scenario-think-time", c.ScenarioThinkTime, "Пауза между шагами: 2s, uniform:2s-5s, exponential:3s, normal:3s,500ms")
+	
+	flag.StringVar(&c.DataFile, "data-file", c.DataFile, "CSV или JSON файл с данными для шаблонов запросов")
+	flag.StringVar(&c.DataMode, "data-mode", c.DataMode, "Порядок выдачи строк данных (sequential, random, unique)")
+	
+	flag.StringVar(&c.TLSCAFile, "tls-ca", c.TLSCAFile, "PEM файл с CA сертификатами для проверки сервера")
+	flag.StringVar(&c.TLSCertFile, "tls-cert", c.TLSCertFile, "PEM файл клиентского сертификата (mTLS)")
+	flag.StringVar(&c.TLSKeyFile, "tls-key", c.TLSKeyFile, "PEM файл ключа клиентского сертификата")
+	flag.StringVar(&c.TLSServerName, "tls-server-name", c.TLSServerName, "Имя сервера для SNI и проверки сертификата")
+	flag.StringVar(&c.TLSMinVersion, "tls-min-version", c.TLSMinVersion, "Минимальная версия TLS (1.0, 1.1, 1.2, 1.3)")
+	flag.StringVar(&c.TLSMaxVersion, "tls-max-version", c.TLSMaxVersion, "Максимальная версия TLS (1.0, 1.1, 1.2, 1.3)")
+	flag.StringVar(&c.TLSCiphers, "tls-ciphers", c.TLSCiphers, "Список шифров TLS 1.2 через запятую (например: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)")
+	flag.StringVar(&c.TLSALPN, "tls-alpn", c.TLSALPN, "Протоколы ALPN через запятую (например: h2,http/1.1)")
+	flag.BoolVar(&c.TLSInsecure, "tls-insecure", c.TLSInsecure, "Не проверять сертификат сервера")
+	flag.BoolVar(&c.TLSResumption, "tls-resumption", c.TLSResumption, "Возобновлять TLS сессии (false - каждый handshake полный)")
+	
+	flag.Func("resolve", "Адреса хоста в формате curl --resolve: host:port:addr[,addr], порт * - любой; флаг можно повторять", func(value string) error {
+		c.DNSResolve = append(c.DNSResolve, value)
+		return nil
+	})
+	flag.StringVar(&c.DNSServer, "dns-server", c.DNSServer, "DNS сервер host:port вместо системного резолвера")
+	flag.StringVar(&c.DNSPolicy, "dns-policy", c.DNSPolicy, "Выбор IP из нескольких адресов: default, round-robin, random, pin")
+	flag.StringVar(&c.DNSPinIP, "dns-pin-ip", c.DNSPinIP, "IP, к которому привязать соединения при -dns-policy pin (по умолчанию первый адрес)")
+	flag.StringVar(&c.SourceAddresses, "source-addresses", c.SourceAddresses, "Локальные IP через запятую, с которых по кругу открываются соединения HTTP, WebSocket, SSE, gRPC и churn")
+	flag.StringVar(&c.SourceInterface, "source-interface", c.SourceInterface, "Сетевой интерфейс, с адресов которого открываются соединения (вместо -source-addresses)")
+	
+	flag.BoolVar(&c.CaptureEnabled, "capture", c.CaptureEnabled, "Сохранять проваленные HTTP запросы (и медленные при -capture-slow) для просмотра в /api/captures")
+	flag.IntVar(&c.CaptureFirst, "capture-first", c.CaptureFirst, "Сколько первых запросов каждой причины сохранять целиком")
+	flag.IntVar(&c.CaptureSample, "capture-sample", c.CaptureSample, "Размер случайной выборки из остальных запросов каждой причины")
+	flag.DurationVar(&c.CaptureSlow, "capture-slow", c.CaptureSlow, "Сохранять успешные запросы не быстрее этого времени (0 - не сохранять)")
+	flag.IntVar(&c.CaptureBodySize, "capture-body-size", c.CaptureBodySize, "Сколько байт тела запроса и ответа сохранять")
+	flag.StringVar(&c.CaptureFile, "capture-file", c.CaptureFile, "JSON файл, в который записать сохранённые запросы по окончании теста")
+	
+	flag.StringVar(&c.AuthType, "auth-type", c.AuthType, "Аутентификация запросов: static, basic, oauth2, hmac")
+	flag.StringVar(&c.AuthToken, "auth-token", c.AuthToken, "Токен для static аутентификации")
+	flag.StringVar(&c.AuthHeader, "auth-header", c.AuthHeader, "Заголовок для static токена")
+	flag.StringVar(&c.AuthScheme, "auth-scheme", c.AuthScheme, "Схема перед static токеном (пусто - без схемы)")
+	flag.StringVar(&c.AuthUsername, "auth-user", c.AuthUsername, "Пользователь для basic аутентификации")
+	flag.StringVar(&c.AuthPassword, "auth-password", c.AuthPassword, "Пароль для basic аутентификации")
+	flag.StringVar(&c.AuthTokenURL, "auth-token-url", c.AuthTokenURL, "Token endpoint для OAuth2 client credentials")
+	flag.StringVar(&c.AuthClientID, "auth-client-id", c.AuthClientID, "OAuth2 client ID")
+	flag.StringVar(&c.AuthClientSecret, "auth-client-secret", c.AuthClientSecret, "OAuth2 client secret")
+	flag.StringVar(&c.AuthScopes, "auth-scopes", c.AuthScopes, "OAuth2 scopes через запятую")
+	flag.StringVar(&c.AuthHMACKeyID, "auth-hmac-key-id", c.AuthHMACKeyID, "Идентификатор ключа для HMAC подписи")
+	flag.StringVar(&c.AuthHMACSecret, "auth-hmac-secret", c.AuthHMACSecret, "Секрет для HMAC подписи")
+	flag.StringVar(&c.AuthHMACHeaders, "auth-hmac-headers", c.AuthHMACHeaders, "Подписываемые заголовки через запятую ((request-target), date, digest, host, ...)")
+	flag.StringVar(&c.AuthHMACAlgorithm, "auth-hmac-algorithm", c.AuthHMACAlgorithm, "Алгоритм HMAC: sha256, sha512")
 	
 	flag.BoolVar(&c.WebEnabled, "web", c.WebEnabled, "Включить веб-интерфейс управления")
 	flag.IntVar(&c.WebPort, "web-port", c.WebPort, "Порт веб-интерфейса (1024-65535)")
+
+	flag.StringVar(&c.ControlSocket, "control-socket", c.ControlSocket, "Путь к unix-сокету для изменения параметров запущенного теста (PATCH /run)")
 	
 	flag.BoolVar(&c.AgentMode, "agent", c.AgentMode, "Run in agent mode")
 	flag.IntVar(&c.AgentPort, "agent-port", c.AgentPort, "Port for agent mode")
@@ -222,6 +517,9 @@ func (c *Config) Validate() error {
 		if c.HTTPTargetURL == "" {
 			return ErrInvalidHTTPURL
 		}
+		if _, _, err := network.SplitUnixURL(c.HTTPTargetURL); err != nil {
+			return ErrInvalidHTTPUnixURL
+		}
 		if c.HTTPTargetRPS <= 0 {
 			return ErrInvalidHTTPRPS
 		}
@@ -247,6 +545,43 @@ func (c *Config) Validate() error {
 		if !valid {
 			return ErrInvalidHTTPPattern
 		}
+		if c.HTTPCheckStatus != "" {
+			if _, err := network.ParseCheckStatus(c.HTTPCheckStatus); err != nil {
+				return ErrInvalidHTTPCheckStatus
+			}
+		}
+		if c.HTTPCheckRegex != "" {
+			if _, err := regexp.Compile(c.HTTPCheckRegex); err != nil {
+				return ErrInvalidHTTPCheckRegex
+			}
+		}
+		if c.HTTPMaxLatency < 0 {
+			return ErrInvalidHTTPMaxLatency
+		}
+		if c.HTTPOpenAPIFile != "" && c.HTTPRequestsFile != "" {
+			return ErrHTTPOpenAPIWithRequests
+		}
+		if c.HTTPGraphQLFile != "" && (c.HTTPRequestsFile != "" || c.HTTPOpenAPIFile != "") {
+			return ErrHTTPGraphQLWithRequests
+		}
+		if c.HTTPGraphQLAPQ && c.HTTPGraphQLFile == "" {
+			return ErrHTTPGraphQLAPQWithoutFile
+		}
+		if c.HTTPMaxConcurrency <= 0 {
+			return ErrInvalidMaxConcurrency
+		}
+		if err := c.HTTPConnectionOptions().Validate(); err != nil {
+			if c.HTTPMaxStreamsPerConn > 0 && c.HTTPProtocol != network.HTTPProtocolH2 && c.HTTPProtocol != network.HTTPProtocolH2C {
+				return ErrHTTPStreamsWithoutH2
+			}
+			if c.HTTPMaxConnsPerHost < 0 || c.HTTPConnMaxLifetime < 0 || c.HTTPMaxRequestsPerConn < 0 || c.HTTPMaxStreamsPerConn < 0 {
+				return ErrInvalidHTTPConnectionLimits
+			}
+			return ErrInvalidHTTPProtocol
+		}
+		if err := c.HTTPProxyOptions().Validate(); err != nil {
+			return ErrInvalidProxy
+		}
 	}
 	if c.WebSocketEnabled {
 		if c.WebSocketTargetURL == "" {
@@ -266,6 +601,43 @@ func (c *Config) Validate() error {
 		if !valid {
 			return ErrInvalidWebSocketPattern
 		}
+		if c.WebSocketMaxConcurrency <= 0 {
+			return ErrInvalidMaxConcurrency
+		}
+		if err := c.WebSocketProxyOptions().Validate(); err != nil {
+			return ErrInvalidProxy
+		}
+		if c.WebSocketScript != "" && c.WebSocketMessage != "" {
+			return ErrWebSocketScriptWithMessage
+		}
+	}
+	if c.SSEEnabled {
+		if c.SSETargetURL == "" {
+			return ErrInvalidSSEURL
+		}
+		if c.SSETargetCPS <= 0 {
+			return ErrInvalidSSECPS
+		}
+		validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+		valid := false
+		for _, pattern := range validPatterns {
+			if c.SSEPattern == pattern {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrInvalidSSEPattern
+		}
+		if c.SSEMaxStreams <= 0 {
+			return ErrInvalidSSEStreams
+		}
+		if c.SSEHoldDuration < 0 || c.SSEReconnectDelay < 0 {
+			return ErrInvalidSSEDuration
+		}
+		if c.SSEMaxConcurrency <= 0 {
+			return ErrInvalidMaxConcurrency
+		}
 	}
 	if c.GRPCEnabled {
 		if c.GRPCTargetAddr == "" {
@@ -296,9 +668,107 @@ func (c *Config) Validate() error {
 		if !valid {
 			return ErrInvalidGRPCMethodType
 		}
+		if c.GRPCMaxConcurrency <= 0 {
+			return ErrInvalidMaxConcurrency
+		}
+	}
+	
+	if c.ChurnEnabled {
+		if c.ChurnTargetAddr == "" {
+			return ErrInvalidChurnAddress
+		}
+		if c.ChurnTargetCPS <= 0 {
+			return ErrInvalidChurnCPS
+		}
+		if c.ChurnHoldDuration < 0 {
+			return ErrInvalidChurnHold
+		}
+		if c.ChurnTimeout <= 0 {
+			return ErrInvalidChurnTimeout
+		}
+		validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+		valid := false
+		for _, pattern := range validPatterns {
+			if c.ChurnPattern == pattern {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrInvalidChurnPattern
+		}
 	}
 	
 	// Web Interface validation
+	if c.ScenarioFile != "" {
+		if c.ScenarioUsers <= 0 {
+			return ErrInvalidScenarioUsers
+		}
+		validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+		valid := false
+		for _, pattern := range validPatterns {
+			if c.ScenarioPattern == pattern {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrInvalidScenarioPattern
+		}
+		if stages, err := network.ParseStages(c.ScenarioStages); err != nil || (c.ScenarioStages != "" && len(stages) == 0) {
+			return ErrInvalidScenarioStages
+		}
+		if _, err := network.ParseThinkTime(c.ScenarioThinkTime); err != nil {
+			return ErrInvalidThinkTime
+		}
+	}
+	if c.DataFile != "" {
+		validModes := []string{"sequential", "random", "unique"}
+		valid := false
+		for _, mode := range validModes {
+			if c.DataMode == mode {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrInvalidDataMode
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return ErrInvalidTLSCertPair
+	}
+	if err := c.TLSOptions().Validate(); err != nil {
+		if _, cipherErr := network.ParseCipherSuites(network.SplitList(c.TLSCiphers)); cipherErr != nil {
+			return ErrInvalidTLSCipher
+		}
+		return ErrInvalidTLSVersion
+	}
+	if err := c.ResolveOptions().Validate(); err != nil {
+		return ErrInvalidDNSOptions
+	}
+	if err := c.SourceOptions().Validate(); err != nil {
+		return ErrInvalidSourceAddress
+	}
+	if err := c.CaptureOptions().Validate(); err != nil {
+		return ErrInvalidCapture
+	}
+	if c.AuthType != "" {
+		validTypes := []string{"static", "basic", "oauth2", "hmac"}
+		valid := false
+		for _, authType := range validTypes {
+			if c.AuthType == authType {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrInvalidAuthType
+		}
+		if err := c.AuthOptions().Validate(); err != nil {
+			return ErrInvalidAuthOptions
+		}
+	}
 	if c.WebEnabled {
 		if c.WebPort < 1024 || c.WebPort > 65535 {
 			return ErrInvalidWebPort
@@ -312,4 +782,98 @@ func (c *Config) Validate() error {
 	}
 	
 	return nil
-} 
\ No newline at end of file
+} 
+
+// TLSOptions собирает общие TLS настройки сетевых генераторов из флагов.
+func (c *Config) TLSOptions() *network.TLSOptions {
+	return &network.TLSOptions{
+		CAFile:             c.TLSCAFile,
+		CertFile:           c.TLSCertFile,
+		KeyFile:            c.TLSKeyFile,
+		ServerName:         c.TLSServerName,
+		MinVersion:         c.TLSMinVersion,
+		MaxVersion:         c.TLSMaxVersion,
+		CipherSuites:       network.SplitList(c.TLSCiphers),
+		ALPN:               network.SplitList(c.TLSALPN),
+		InsecureSkipVerify: c.TLSInsecure,
+		DisableResumption:  !c.TLSResumption,
+	}
+}
+
+// ResolveOptions собирает настройки разрешения имён сетевых генераторов из флагов.
+func (c *Config) ResolveOptions() *network.ResolveOptions {
+	return &network.ResolveOptions{
+		Overrides: c.DNSResolve,
+		Server:    c.DNSServer,
+		Policy:    c.DNSPolicy,
+		PinIP:     c.DNSPinIP,
+	}
+}
+
+// SourceOptions собирает локальные адреса сетевых генераторов из флагов.
+func (c *Config) SourceOptions() *network.SourceOptions {
+	return &network.SourceOptions{
+		Addresses: network.SplitList(c.SourceAddresses),
+		Interface: c.SourceInterface,
+	}
+}
+
+// CaptureOptions собирает настройки сохранения запросов из флагов.
+func (c *Config) CaptureOptions() *network.CaptureOptions {
+	return &network.CaptureOptions{
+		Enabled:  c.CaptureEnabled,
+		First:    c.CaptureFirst,
+		Sample:   c.CaptureSample,
+		Slow:     network.Duration(c.CaptureSlow),
+		BodySize: c.CaptureBodySize,
+		File:     c.CaptureFile,
+	}
+}
+
+// HTTPConnectionOptions собирает протокол и политику соединений HTTP генератора из флагов.
+func (c *Config) HTTPConnectionOptions() *network.HTTPConnectionOptions {
+	return &network.HTTPConnectionOptions{
+		Protocol:           c.HTTPProtocol,
+		DisableKeepAlive:   !c.HTTPKeepAlive,
+		MaxConnsPerHost:    c.HTTPMaxConnsPerHost,
+		MaxConnLifetime:    network.Duration(c.HTTPConnMaxLifetime),
+		MaxRequestsPerConn: c.HTTPMaxRequestsPerConn,
+		MaxStreamsPerConn:  c.HTTPMaxStreamsPerConn,
+	}
+}
+
+// HTTPProxyOptions собирает настройки прокси HTTP генератора из флагов.
+func (c *Config) HTTPProxyOptions() *network.ProxyOptions {
+	return &network.ProxyOptions{
+		URL:     c.HTTPProxy,
+		NoProxy: network.SplitList(c.HTTPNoProxy),
+	}
+}
+
+// WebSocketProxyOptions собирает настройки прокси WebSocket генератора из флагов.
+func (c *Config) WebSocketProxyOptions() *network.ProxyOptions {
+	return &network.ProxyOptions{
+		URL:     c.WebSocketProxy,
+		NoProxy: network.SplitList(c.WebSocketNoProxy),
+	}
+}
+
+// AuthOptions собирает настройки аутентификации запросов из флагов.
+func (c *Config) AuthOptions() *network.AuthOptions {
+	return &network.AuthOptions{
+		Type:          c.AuthType,
+		Token:         c.AuthToken,
+		Header:        c.AuthHeader,
+		Scheme:        c.AuthScheme,
+		Username:      c.AuthUsername,
+		Password:      c.AuthPassword,
+		TokenURL:      c.AuthTokenURL,
+		ClientID:      c.AuthClientID,
+		ClientSecret:  c.AuthClientSecret,
+		Scopes:        network.SplitList(c.AuthScopes),
+		KeyID:         c.AuthHMACKeyID,
+		Secret:        c.AuthHMACSecret,
+		Algorithm:     c.AuthHMACAlgorithm,
+		SignedHeaders: network.SplitList(c.AuthHMACHeaders),
+	}
+}
@@ -16,22 +16,68 @@ var (
 	ErrInvalidMemoryPattern = errors.New("invalid memory pattern")
 	ErrInvalidMemoryInterval = errors.New("memory interval must be positive")
 	ErrInvalidHTTPURL = errors.New("HTTP URL cannot be empty")
+	ErrInvalidHTTPUnixURL = errors.New("invalid unix socket HTTP URL, expected unix:///path/to.sock[:/request/path]")
 	ErrInvalidHTTPRPS = errors.New("HTTP RPS must be positive")
 	ErrInvalidHTTPPattern = errors.New("invalid HTTP pattern")
 	ErrInvalidHTTPMethod = errors.New("invalid HTTP method")
 	ErrInvalidHTTPTimeout = errors.New("HTTP timeout must be positive")
+	ErrInvalidHTTPCheckStatus = errors.New("HTTP check status must be a list of status codes")
+	ErrInvalidHTTPCheckRegex = errors.New("invalid HTTP check regex")
+	ErrInvalidHTTPMaxLatency = errors.New("HTTP max latency must be non-negative")
+	ErrHTTPOpenAPIWithRequests = errors.New("-http-openapi and -http-requests cannot be used together")
+	ErrHTTPGraphQLWithRequests = errors.New("-http-graphql cannot be combined with -http-requests or -http-openapi")
+	ErrHTTPGraphQLAPQWithoutFile = errors.New("-http-graphql-apq requires -http-graphql")
+	ErrInvalidHTTPProtocol = errors.New("invalid HTTP protocol, expected auto, h1, h2 or h2c")
+	ErrInvalidHTTPConnectionLimits = errors.New("HTTP connection limits must be non-negative")
+	ErrHTTPStreamsWithoutH2 = errors.New("-http-max-streams-per-conn requires -http-protocol h2 or h2c")
 
 	ErrInvalidWebSocketURL = errors.New("WebSocket URL cannot be empty")
 	ErrInvalidWebSocketCPS = errors.New("WebSocket CPS must be positive")
 	ErrInvalidWebSocketPattern = errors.New("invalid WebSocket pattern")
 	ErrInvalidWebSocketMessageInterval = errors.New("WebSocket message interval must be positive")
 	ErrInvalidWebSocketMessageSize = errors.New("WebSocket message size must be positive")
+	ErrWebSocketScriptWithMessage = errors.New("-websocket-script and -websocket-message cannot be used together")
+
+	ErrInvalidSSEURL = errors.New("SSE URL cannot be empty")
+	ErrInvalidSSECPS = errors.New("SSE CPS must be positive")
+	ErrInvalidSSEPattern = errors.New("invalid SSE pattern")
+	ErrInvalidSSEStreams = errors.New("SSE streams must be positive")
+	ErrInvalidSSEDuration = errors.New("SSE hold and reconnect delay must be non-negative")
 
 	ErrInvalidGRPCAddress = errors.New("gRPC address cannot be empty")
 	ErrInvalidGRPCRPS = errors.New("gRPC RPS must be positive")
 	ErrInvalidGRPCPattern = errors.New("invalid gRPC pattern")
 	ErrInvalidGRPCMethodType = errors.New("invalid gRPC method type")
 
+	ErrInvalidMaxConcurrency = errors.New("max concurrency must be positive")
+
+	ErrInvalidChurnAddress = errors.New("churn address cannot be empty")
+	ErrInvalidChurnCPS = errors.New("churn CPS must be positive")
+	ErrInvalidChurnPattern = errors.New("invalid churn pattern")
+	ErrInvalidChurnHold = errors.New("churn hold duration must be non-negative")
+	ErrInvalidChurnTimeout = errors.New("churn timeout must be positive")
+
+	ErrInvalidScenarioUsers = errors.New("scenario users must be positive")
+	ErrInvalidScenarioPattern = errors.New("invalid scenario users pattern")
+	ErrInvalidScenarioStages = errors.New("invalid scenario stages, expected duration:users list")
+	ErrInvalidThinkTime = errors.New("invalid think time")
+	ErrInvalidDataMode = errors.New("invalid data mode")
+
+	ErrInvalidTLSCertPair = errors.New("TLS client certificate and key must be set together")
+	ErrInvalidTLSVersion = errors.New("invalid TLS version, expected 1.0, 1.1, 1.2 or 1.3 with min not above max")
+	ErrInvalidTLSCipher = errors.New("unknown TLS cipher suite")
+
+	ErrInvalidProxy = errors.New("invalid proxy, expected http://, https://, socks5:// or socks5h:// url with host and valid no-proxy CIDRs")
+
+	ErrInvalidDNSOptions = errors.New("invalid DNS options: -resolve expects host:port:addr[,addr], -dns-server host:port, -dns-policy default, round-robin, random or pin, -dns-pin-ip needs policy pin")
+
+	ErrInvalidSourceAddress = errors.New("invalid source addresses: -source-addresses expects comma-separated IPs and cannot be combined with -source-interface")
+
+	ErrInvalidCapture = errors.New("invalid capture options: -capture-first, -capture-sample, -capture-slow and -capture-body-size must be non-negative")
+
+	ErrInvalidAuthType = errors.New("invalid auth type, expected static, basic, oauth2 or hmac")
+	ErrInvalidAuthOptions = errors.New("auth options are incomplete: static needs -auth-token, basic -auth-user, oauth2 -auth-token-url and -auth-client-id, hmac -auth-hmac-key-id and -auth-hmac-secret")
+
 	ErrInvalidWebPort = errors.New("web port must be between 1024 and 65535")
 	ErrInvalidAgentPort = errors.New("agent port must be between 1024 and 65535")
 ) 
\ No newline at end of file
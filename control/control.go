@@ -0,0 +1,350 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"stresspulse/config"
+	"stresspulse/load"
+	"stresspulse/memory"
+	"stresspulse/network"
+)
+
+var ErrEmptyUpdate = errors.New("update contains no changes")
+
+var (
+	cpuPatterns     = []string{"sine", "square", "sawtooth", "random"}
+	memoryPatterns  = []string{"constant", "leak", "spike", "cycle", "random"}
+	networkPatterns = []string{"constant", "spike", "cycle", "ramp", "random"}
+)
+
+// RunUpdate - изменения параметров запущенных генераторов. Поля, которых нет в запросе, не меняются.
+// Имена полей совпадают с конфигурацией /api/start.
+type RunUpdate struct {
+	CPU       *CPUUpdate       `json:"cpu,omitempty"`
+	Memory    *MemoryUpdate    `json:"memory,omitempty"`
+	HTTP      *HTTPUpdate      `json:"http,omitempty"`
+	WebSocket *WebSocketUpdate `json:"websocket,omitempty"`
+	SSE       *SSEUpdate       `json:"sse,omitempty"`
+	GRPC      *GRPCUpdate      `json:"grpc,omitempty"`
+	Scenario  *ScenarioUpdate  `json:"scenario,omitempty"`
+}
+
+type CPUUpdate struct {
+	Load    *float64 `json:"load,omitempty"`
+	Pattern *string  `json:"pattern,omitempty"`
+}
+
+type MemoryUpdate struct {
+	Target  *int    `json:"target,omitempty"`
+	Pattern *string `json:"pattern,omitempty"`
+}
+
+type HTTPUpdate struct {
+	URL            *string `json:"url,omitempty"`
+	RPS            *int    `json:"rps,omitempty"`
+	Pattern        *string `json:"pattern,omitempty"`
+	MaxConcurrency *int    `json:"maxConcurrency,omitempty"`
+}
+
+type WebSocketUpdate struct {
+	URL            *string `json:"url,omitempty"`
+	CPS            *int    `json:"cps,omitempty"`
+	Pattern        *string `json:"pattern,omitempty"`
+	MaxConcurrency *int    `json:"maxConcurrency,omitempty"`
+}
+
+type SSEUpdate struct {
+	URL            *string `json:"url,omitempty"`
+	CPS            *int    `json:"cps,omitempty"`
+	Pattern        *string `json:"pattern,omitempty"`
+	MaxConcurrency *int    `json:"maxConcurrency,omitempty"`
+}
+
+type GRPCUpdate struct {
+	RPS            *int    `json:"rps,omitempty"`
+	Pattern        *string `json:"pattern,omitempty"`
+	MaxConcurrency *int    `json:"maxConcurrency,omitempty"`
+}
+
+// ScenarioUpdate - users отключает ступени, дальше число пользователей задают users и паттерн.
+type ScenarioUpdate struct {
+	Users   *int    `json:"users,omitempty"`
+	Pattern *string `json:"pattern,omitempty"`
+}
+
+// Generators - генераторы, к которым применяются изменения; nil означает, что генератор не запущен.
+type Generators struct {
+	CPU       *load.Generator
+	Memory    *memory.MemoryGenerator
+	HTTP      *network.HTTPGenerator
+	WebSocket *network.WebSocketGenerator
+	SSE       *network.SSEGenerator
+	GRPC      *network.GRPCGenerator
+	Scenario  *network.ScenarioGenerator
+}
+
+// applyMutex не даёт двум обновлениям перемешаться: каждое проверяется и применяется целиком.
+var applyMutex sync.Mutex
+
+// Apply проверяет всё обновление и только потом применяет его, так что при ошибке ничего не меняется.
+// Генераторы продолжают работу: соединения, воркеры и накопленная статистика сохраняются.
+// Возвращает список применённых изменений для логов.
+func Apply(generators *Generators, update *RunUpdate) ([]string, error) {
+	applyMutex.Lock()
+	defer applyMutex.Unlock()
+
+	if err := update.validate(generators); err != nil {
+		return nil, err
+	}
+
+	var applied []string
+
+	// Смена HTTP URL - единственная операция, которая может не пройти (шаблоны запросов),
+	// поэтому она идёт первой: при ошибке остальное ещё не тронуто.
+	if u := update.HTTP; u != nil && u.URL != nil {
+		if err := generators.HTTP.SetTargetURL(*u.URL); err != nil {
+			return nil, fmt.Errorf("HTTP url: %v", err)
+		}
+		applied = append(applied, "HTTP url="+*u.URL)
+	}
+
+	if u := update.CPU; u != nil {
+		if u.Load != nil {
+			generators.CPU.SetTargetCPU(*u.Load)
+			applied = append(applied, fmt.Sprintf("CPU load=%.1f%%", *u.Load))
+		}
+		if u.Pattern != nil {
+			generators.CPU.SetPattern(*u.Pattern)
+			applied = append(applied, "CPU pattern="+*u.Pattern)
+		}
+	}
+
+	if u := update.Memory; u != nil {
+		if u.Target != nil {
+			generators.Memory.SetTargetMB(*u.Target)
+			applied = append(applied, fmt.Sprintf("memory target=%dMB", *u.Target))
+		}
+		if u.Pattern != nil {
+			generators.Memory.SetPattern(*u.Pattern)
+			applied = append(applied, "memory pattern="+*u.Pattern)
+		}
+	}
+
+	if u := update.HTTP; u != nil {
+		if u.RPS != nil {
+			generators.HTTP.SetTargetRPS(*u.RPS)
+			applied = append(applied, fmt.Sprintf("HTTP rps=%d", *u.RPS))
+		}
+		if u.Pattern != nil {
+			generators.HTTP.SetPattern(*u.Pattern)
+			applied = append(applied, "HTTP pattern="+*u.Pattern)
+		}
+		if u.MaxConcurrency != nil {
+			generators.HTTP.SetMaxConcurrency(*u.MaxConcurrency)
+			applied = append(applied, fmt.Sprintf("HTTP maxConcurrency=%d", *u.MaxConcurrency))
+		}
+	}
+
+	if u := update.WebSocket; u != nil {
+		if u.URL != nil {
+			generators.WebSocket.SetTargetURL(*u.URL)
+			applied = append(applied, "WebSocket url="+*u.URL)
+		}
+		if u.CPS != nil {
+			generators.WebSocket.SetTargetCPS(*u.CPS)
+			applied = append(applied, fmt.Sprintf("WebSocket cps=%d", *u.CPS))
+		}
+		if u.Pattern != nil {
+			generators.WebSocket.SetPattern(*u.Pattern)
+			applied = append(applied, "WebSocket pattern="+*u.Pattern)
+		}
+		if u.MaxConcurrency != nil {
+			generators.WebSocket.SetMaxConcurrency(*u.MaxConcurrency)
+			applied = append(applied, fmt.Sprintf("WebSocket maxConcurrency=%d", *u.MaxConcurrency))
+		}
+	}
+
+	if u := update.SSE; u != nil {
+		if u.URL != nil {
+			generators.SSE.SetTargetURL(*u.URL)
+			applied = append(applied, "SSE url="+*u.URL)
+		}
+		if u.CPS != nil {
+			generators.SSE.SetTargetCPS(*u.CPS)
+			applied = append(applied, fmt.Sprintf("SSE cps=%d", *u.CPS))
+		}
+		if u.Pattern != nil {
+			generators.SSE.SetPattern(*u.Pattern)
+			applied = append(applied, "SSE pattern="+*u.Pattern)
+		}
+		if u.MaxConcurrency != nil {
+			generators.SSE.SetMaxConcurrency(*u.MaxConcurrency)
+			applied = append(applied, fmt.Sprintf("SSE maxConcurrency=%d", *u.MaxConcurrency))
+		}
+	}
+
+	if u := update.GRPC; u != nil {
+		if u.RPS != nil {
+			generators.GRPC.SetTargetRPS(*u.RPS)
+			applied = append(applied, fmt.Sprintf("gRPC rps=%d", *u.RPS))
+		}
+		if u.Pattern != nil {
+			generators.GRPC.SetPattern(*u.Pattern)
+			applied = append(applied, "gRPC pattern="+*u.Pattern)
+		}
+		if u.MaxConcurrency != nil {
+			generators.GRPC.SetMaxConcurrency(*u.MaxConcurrency)
+			applied = append(applied, fmt.Sprintf("gRPC maxConcurrency=%d", *u.MaxConcurrency))
+		}
+	}
+
+	if u := update.Scenario; u != nil {
+		if u.Users != nil {
+			generators.Scenario.SetUsers(*u.Users)
+			applied = append(applied, fmt.Sprintf("scenario users=%d", *u.Users))
+		}
+		if u.Pattern != nil {
+			generators.Scenario.SetUserPattern(*u.Pattern)
+			applied = append(applied, "scenario pattern="+*u.Pattern)
+		}
+	}
+
+	return applied, nil
+}
+
+func (u *RunUpdate) validate(generators *Generators) error {
+	if u.CPU == nil && u.Memory == nil && u.HTTP == nil && u.WebSocket == nil && u.SSE == nil && u.GRPC == nil && u.Scenario == nil {
+		return ErrEmptyUpdate
+	}
+
+	if c := u.CPU; c != nil {
+		if generators.CPU == nil {
+			return notRunning("CPU")
+		}
+		if c.Load != nil && (*c.Load < 0 || *c.Load > 100) {
+			return config.ErrInvalidCPUPercentage
+		}
+		if c.Pattern != nil && !contains(cpuPatterns, *c.Pattern) {
+			return config.ErrInvalidPatternType
+		}
+	}
+
+	if m := u.Memory; m != nil {
+		if generators.Memory == nil {
+			return notRunning("memory")
+		}
+		if m.Target != nil && *m.Target <= 0 {
+			return config.ErrInvalidMemoryTarget
+		}
+		if m.Pattern != nil && !contains(memoryPatterns, *m.Pattern) {
+			return config.ErrInvalidMemoryPattern
+		}
+	}
+
+	if h := u.HTTP; h != nil {
+		if generators.HTTP == nil {
+			return notRunning("HTTP")
+		}
+		if h.URL != nil && !validURL(*h.URL, "http", "https") && !network.IsUnixURL(*h.URL) {
+			return fmt.Errorf("invalid HTTP url %q, expected http(s)://host or unix:///path", *h.URL)
+		}
+		if h.RPS != nil && *h.RPS <= 0 {
+			return config.ErrInvalidHTTPRPS
+		}
+		if h.Pattern != nil && !contains(networkPatterns, *h.Pattern) {
+			return config.ErrInvalidHTTPPattern
+		}
+		if h.MaxConcurrency != nil && *h.MaxConcurrency <= 0 {
+			return config.ErrInvalidMaxConcurrency
+		}
+	}
+
+	if w := u.WebSocket; w != nil {
+		if generators.WebSocket == nil {
+			return notRunning("WebSocket")
+		}
+		if w.URL != nil && !validURL(*w.URL, "ws", "wss") {
+			return fmt.Errorf("invalid WebSocket url %q, expected ws(s)://host", *w.URL)
+		}
+		if w.CPS != nil && *w.CPS <= 0 {
+			return config.ErrInvalidWebSocketCPS
+		}
+		if w.Pattern != nil && !contains(networkPatterns, *w.Pattern) {
+			return config.ErrInvalidWebSocketPattern
+		}
+		if w.MaxConcurrency != nil && *w.MaxConcurrency <= 0 {
+			return config.ErrInvalidMaxConcurrency
+		}
+	}
+
+	if e := u.SSE; e != nil {
+		if generators.SSE == nil {
+			return notRunning("SSE")
+		}
+		if e.URL != nil && !validURL(*e.URL, "http", "https") {
+			return fmt.Errorf("invalid SSE url %q, expected http(s)://host", *e.URL)
+		}
+		if e.CPS != nil && *e.CPS <= 0 {
+			return config.ErrInvalidSSECPS
+		}
+		if e.Pattern != nil && !contains(networkPatterns, *e.Pattern) {
+			return config.ErrInvalidSSEPattern
+		}
+		if e.MaxConcurrency != nil && *e.MaxConcurrency <= 0 {
+			return config.ErrInvalidMaxConcurrency
+		}
+	}
+
+	if g := u.GRPC; g != nil {
+		if generators.GRPC == nil {
+			return notRunning("gRPC")
+		}
+		if g.RPS != nil && *g.RPS <= 0 {
+			return config.ErrInvalidGRPCRPS
+		}
+		if g.Pattern != nil && !contains(networkPatterns, *g.Pattern) {
+			return config.ErrInvalidGRPCPattern
+		}
+		if g.MaxConcurrency != nil && *g.MaxConcurrency <= 0 {
+			return config.ErrInvalidMaxConcurrency
+		}
+	}
+
+	if s := u.Scenario; s != nil {
+		if generators.Scenario == nil {
+			return notRunning("scenario")
+		}
+		if s.Users != nil && *s.Users <= 0 {
+			return config.ErrInvalidScenarioUsers
+		}
+		if s.Pattern != nil && !contains(networkPatterns, *s.Pattern) {
+			return config.ErrInvalidScenarioPattern
+		}
+	}
+
+	return nil
+}
+
+func notRunning(name string) error {
+	return fmt.Errorf("%s generator is not running", name)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validURL(rawURL string, schemes ...string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return contains(schemes, parsed.Scheme)
+}
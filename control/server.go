@@ -0,0 +1,104 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"stresspulse/logger"
+)
+
+// Server принимает PATCH /run на unix-сокете, чтобы менять параметры CLI-запуска без перезапуска:
+//
+//	curl --unix-socket /tmp/stresspulse.sock -X PATCH http://localhost/run -d '{"http":{"rps":500}}'
+type Server struct {
+	path       string
+	generators *Generators
+	server     *http.Server
+	listener   net.Listener
+}
+
+func NewServer(path string, generators *Generators) *Server {
+	s := &Server{
+		path:       path,
+		generators: generators,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run", s.handleRun)
+
+	s.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+func (s *Server) Start() error {
+	// Сокет, оставшийся от аварийно завершённого запуска, мешает bind.
+	if info, err := os.Stat(s.path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(s.path)
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("control socket listen error: %v", err)
+	}
+	s.listener = listener
+
+	logger.Info("Control socket listening on %s", s.path)
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Control socket error: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	os.Remove(s.path)
+	if err != nil {
+		return fmt.Errorf("control socket shutdown error: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var update RunUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	applied, err := Apply(s.generators, &update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Run updated: %s", strings.Join(applied, ", "))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "updated",
+		"applied": applied,
+	})
+}
@@ -5,6 +5,8 @@ go 1.21
 require (
 	github.com/gorilla/websocket v1.5.1
 	github.com/prometheus/client_golang v1.18.0
+	golang.org/x/net v0.17.0
+	golang.org/x/sys v0.15.0
 	google.golang.org/grpc v1.60.1
 )
 
@@ -16,8 +18,6 @@ require (
 	github.com/prometheus/client_model v0.5.0 // indirect
 	github.com/prometheus/common v0.45.0 // indirect
 	github.com/prometheus/procfs v0.12.0 // indirect
-	golang.org/x/net v0.17.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231002182017-d307bd883b97 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
@@ -21,6 +21,11 @@ type Generator struct {
 	done    chan struct{}
 	stats   *Stats
 	pattern patterns.Pattern
+	// mu защищает цель, паттерн и список воркеров, которые меняются на ходу.
+	mu           sync.RWMutex
+	ctx          context.Context
+	stops        []chan struct{}
+	patternStart time.Time
 }
 
 type Stats struct {
@@ -47,18 +52,16 @@ func NewGenerator(cfg *config.Config) *Generator {
 }
 
 func (g *Generator) Start(ctx context.Context) {
-	workUnits := int(float64(runtime.NumCPU()) * g.config.TargetCPUPercent / 100.0)
-	if workUnits < 1 {
-		workUnits = 1
-	}
+	workUnits := workUnitsFor(g.config.TargetCPUPercent)
 
 	logger.Info("Starting CPU stress test with %d workers", workUnits)
 	logger.Debug("Configuration: %+v", g.config)
 
-	g.wg.Add(workUnits)
-	for i := 0; i < workUnits; i++ {
-		go g.worker(ctx, i)
-	}
+	g.mu.Lock()
+	g.ctx = ctx
+	g.patternStart = time.Now()
+	g.scaleWorkers(workUnits)
+	g.mu.Unlock()
 
 	if g.config.MetricsEnabled {
 		logger.Info("Metrics collection enabled on port %d", g.config.MetricsPort)
@@ -76,15 +79,70 @@ func (g *Generator) Stop() {
 	}
 }
 
+// SetTargetCPU меняет целевую загрузку на ходу: число воркеров подстраивается, статистика сохраняется.
+func (g *Generator) SetTargetCPU(percent float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.config.TargetCPUPercent = percent
+	if g.ctx != nil {
+		g.scaleWorkers(workUnitsFor(percent))
+	}
+}
+
+// SetPattern меняет паттерн дрейфа нагрузки на ходу.
+func (g *Generator) SetPattern(patternType string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.config.PatternType = patternType
+	g.pattern = patterns.NewPattern(patternType)
+	g.patternStart = time.Now()
+}
+
+func (g *Generator) TargetCPU() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.config.TargetCPUPercent
+}
+
+func workUnitsFor(percent float64) int {
+	workUnits := int(float64(runtime.NumCPU()) * percent / 100.0)
+	if workUnits < 1 {
+		workUnits = 1
+	}
+	return workUnits
+}
+
+// scaleWorkers запускает недостающих воркеров или останавливает лишних. Вызывается под mu.
+func (g *Generator) scaleWorkers(target int) {
+	for len(g.stops) < target {
+		stop := make(chan struct{})
+		g.stops = append(g.stops, stop)
+		g.wg.Add(1)
+		go g.worker(g.ctx, stop, len(g.stops)-1)
+	}
+	for len(g.stops) > target {
+		last := len(g.stops) - 1
+		close(g.stops[last])
+		g.stops = g.stops[:last]
+	}
+}
+
+func (g *Generator) settings() (float64, float64, patterns.Pattern, time.Time) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.config.TargetCPUPercent, g.config.DriftAmplitude, g.pattern, g.patternStart
+}
+
 func (g *Generator) GetStats() *Stats {
 	g.stats.mu.RLock()
 	defer g.stats.mu.RUnlock()
 	return g.stats
 }
 
-func (g *Generator) worker(ctx context.Context, workerID int) {
+func (g *Generator) worker(ctx context.Context, stop chan struct{}, workerID int) {
 	defer g.wg.Done()
-	startTime := time.Now()
 	logger.Debug("Worker %d started", workerID)
 
 	for {
@@ -95,9 +153,12 @@ func (g *Generator) worker(ctx context.Context, workerID int) {
 		case <-g.done:
 			logger.Debug("Worker %d stopped by done signal", workerID)
 			return
+		case <-stop:
+			logger.Debug("Worker %d stopped after target change", workerID)
+			return
 		default:
-			elapsed := time.Since(startTime)
-			currentLoad := g.pattern.GetLoad(elapsed, g.config.TargetCPUPercent, g.config.DriftAmplitude)
+			targetPercent, driftAmplitude, pattern, patternStart := g.settings()
+			currentLoad := pattern.GetLoad(time.Since(patternStart), targetPercent, driftAmplitude)
 
 			if currentLoad < 0 {
 				currentLoad = 0
@@ -2,13 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
 	"stresspulse/config"
+	"stresspulse/control"
 	"stresspulse/load"
 	"stresspulse/logs"
 	"stresspulse/logger"
@@ -17,9 +24,15 @@ import (
 	"stresspulse/network"
 	"stresspulse/web"
 	"stresspulse/agent"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import-har" {
+		os.Exit(runImportHAR(os.Args[2:]))
+	}
+
 	cfg := config.NewConfig()
 	cfg.ParseFlags()
 
@@ -73,9 +86,52 @@ func main() {
 		memoryGenerator = memory.NewMemoryGenerator(cfg.MemoryTargetMB, cfg.MemoryPattern, cfg.MemoryInterval)
 	}
 
+	var dataFeeder *network.DataFeeder
+	if cfg.DataFile != "" {
+		var err error
+		dataFeeder, err = network.LoadDataFeeder(cfg.DataFile, cfg.DataMode)
+		if err != nil {
+			logger.Error("Configuration error: %v", err)
+			os.Exit(1)
+		}
+		logger.Info("Loaded %d data rows from %s (%s)", dataFeeder.Len(), cfg.DataFile, cfg.DataMode)
+	}
+
+	tlsOptions := cfg.TLSOptions()
+	tlsConfig, err := tlsOptions.Build()
+	if err != nil {
+		logger.Error("Configuration error: %v", err)
+		os.Exit(1)
+	}
+
+	authProvider, err := network.NewAuthProvider(cfg.AuthOptions(), tlsConfig)
+	if err != nil {
+		logger.Error("Configuration error: %v", err)
+		os.Exit(1)
+	}
+
+	resolver, err := network.NewResolver(cfg.ResolveOptions())
+	if err != nil {
+		logger.Error("Configuration error: %v", err)
+		os.Exit(1)
+	}
+
+	source, err := network.NewSourceBinder(cfg.SourceOptions())
+	if err != nil {
+		logger.Error("Configuration error: %v", err)
+		os.Exit(1)
+	}
+
+	captures, err := network.NewCaptureStore(cfg.CaptureOptions())
+	if err != nil {
+		logger.Error("Configuration error: %v", err)
+		os.Exit(1)
+	}
+
 	var httpGenerator *network.HTTPGenerator
 	if cfg.HTTPEnabled {
 		httpGenerator = network.NewHTTPGenerator(cfg.HTTPTargetURL, cfg.HTTPTargetRPS, cfg.HTTPPattern, cfg.HTTPMethod, cfg.HTTPTimeout)
+		httpGenerator.SetMaxConcurrency(cfg.HTTPMaxConcurrency)
 		
 		if cfg.HTTPHeaders != "" {
 			headers := parseHTTPHeaders(cfg.HTTPHeaders)
@@ -85,26 +141,186 @@ func main() {
 		if cfg.HTTPBody != "" {
 			httpGenerator.SetBody(cfg.HTTPBody)
 		}
+		
+		if dataFeeder != nil {
+			httpGenerator.SetDataFeeder(dataFeeder)
+		}
+		
+		httpGenerator.SetTLSConfig(tlsConfig)
+		httpGenerator.SetAuth(authProvider)
+		httpGenerator.SetResolver(resolver)
+		httpGenerator.SetSource(source)
+		httpGenerator.SetCaptures(captures)
+		if err := httpGenerator.SetConnectionOptions(cfg.HTTPConnectionOptions()); err != nil {
+			logger.Error("Configuration error: %v", err)
+			os.Exit(1)
+		}
+		if err := httpGenerator.SetProxy(cfg.HTTPProxyOptions()); err != nil {
+			logger.Error("Configuration error: %v", err)
+			os.Exit(1)
+		}
+		
+		if checks := buildHTTPChecks(cfg); len(checks) > 0 {
+			if err := httpGenerator.SetChecks(checks); err != nil {
+				logger.Error("Configuration error: %v", err)
+				os.Exit(1)
+			}
+		}
+		
+		if cfg.HTTPRequestsFile != "" {
+			templates, err := network.LoadRequestTemplates(cfg.HTTPRequestsFile)
+			if err == nil {
+				err = httpGenerator.SetRequestTemplates(templates)
+			}
+			if err != nil {
+				logger.Error("Configuration error: %v", err)
+				os.Exit(1)
+			}
+		}
+		
+		if cfg.HTTPOpenAPIFile != "" {
+			templates, err := network.LoadOpenAPIRequests(cfg.HTTPOpenAPIFile, &network.OpenAPIOptions{
+				BaseURL:    cfg.HTTPOpenAPIURL,
+				Tags:       network.SplitList(cfg.HTTPOpenAPITags),
+				Operations: network.SplitList(cfg.HTTPOpenAPIOperations),
+			})
+			if err == nil {
+				err = httpGenerator.SetRequestTemplates(templates)
+			}
+			if err != nil {
+				logger.Error("Configuration error: %v", err)
+				os.Exit(1)
+			}
+			logger.Info("Loaded %d operations from OpenAPI spec %s", len(templates), cfg.HTTPOpenAPIFile)
+		}
+		
+		if cfg.HTTPGraphQLFile != "" {
+			mix, err := network.LoadGraphQLOperations(cfg.HTTPGraphQLFile)
+			if err == nil {
+				mix.APQ = mix.APQ || cfg.HTTPGraphQLAPQ
+				err = httpGenerator.SetGraphQLOperations(mix)
+			}
+			if err != nil {
+				logger.Error("Configuration error: %v", err)
+				os.Exit(1)
+			}
+			logger.Info("Loaded %d GraphQL operations from %s, persisted queries: %t", len(mix.Operations), cfg.HTTPGraphQLFile, mix.APQ)
+		}
 	}
 
 	var websocketGenerator *network.WebSocketGenerator
 	if cfg.WebSocketEnabled {
 		websocketGenerator = network.NewWebSocketGenerator(cfg.WebSocketTargetURL, cfg.WebSocketTargetCPS, cfg.WebSocketPattern, cfg.WebSocketMessageInterval, cfg.WebSocketMessageSize)
+		websocketGenerator.SetMaxConcurrency(cfg.WebSocketMaxConcurrency)
 		
 		if cfg.WebSocketHeaders != "" {
 			headers := parseHTTPHeaders(cfg.WebSocketHeaders)
 			websocketGenerator.SetHeaders(headers)
 		}
+		
+		if dataFeeder != nil {
+			websocketGenerator.SetDataFeeder(dataFeeder)
+		}
+		
+		websocketGenerator.SetTLSConfig(tlsConfig)
+		websocketGenerator.SetAuth(authProvider)
+		websocketGenerator.SetResolver(resolver)
+		websocketGenerator.SetSource(source)
+		if err := websocketGenerator.SetProxy(cfg.WebSocketProxyOptions()); err != nil {
+			logger.Error("Configuration error: %v", err)
+			os.Exit(1)
+		}
+		
+		if cfg.WebSocketMessage != "" {
+			if err := websocketGenerator.SetMessageTemplate(cfg.WebSocketMessage); err != nil {
+				logger.Error("Configuration error: %v", err)
+				os.Exit(1)
+			}
+		}
+		
+		if cfg.WebSocketScript != "" {
+			script, err := network.LoadWebSocketScript(cfg.WebSocketScript)
+			if err == nil {
+				err = websocketGenerator.SetScript(script)
+			}
+			if err != nil {
+				logger.Error("Configuration error: %v", err)
+				os.Exit(1)
+			}
+			logger.Info("Loaded WebSocket script with %d steps from %s", len(script.Steps), cfg.WebSocketScript)
+		}
+	}
+
+	var sseGenerator *network.SSEGenerator
+	if cfg.SSEEnabled {
+		sseGenerator = network.NewSSEGenerator(cfg.SSETargetURL, cfg.SSETargetCPS, cfg.SSEPattern, cfg.SSEMaxStreams)
+		sseGenerator.SetMaxConcurrency(cfg.SSEMaxConcurrency)
+		sseGenerator.SetHoldDuration(cfg.SSEHoldDuration)
+		sseGenerator.SetReconnectDelay(cfg.SSEReconnectDelay)
+		
+		if cfg.SSEHeaders != "" {
+			headers := parseHTTPHeaders(cfg.SSEHeaders)
+			sseGenerator.SetHeaders(headers)
+		}
+		
+		sseGenerator.SetTLSConfig(tlsConfig)
+		sseGenerator.SetAuth(authProvider)
+		sseGenerator.SetResolver(resolver)
+		sseGenerator.SetSource(source)
 	}
 
 	var grpcGenerator *network.GRPCGenerator
 	if cfg.GRPCEnabled {
 		grpcGenerator = network.NewGRPCGenerator(cfg.GRPCTargetAddr, cfg.GRPCTargetRPS, cfg.GRPCPattern, cfg.GRPCServiceName, cfg.GRPCMethodType, cfg.GRPCUseSecure)
+		grpcGenerator.SetMaxConcurrency(cfg.GRPCMaxConcurrency)
 		
 		if cfg.GRPCMetadata != "" {
 			metadata := parseHTTPHeaders(cfg.GRPCMetadata)
 			grpcGenerator.SetMetadata(metadata)
 		}
+		
+		if dataFeeder != nil {
+			grpcGenerator.SetDataFeeder(dataFeeder)
+		}
+		
+		grpcGenerator.SetTLSConfig(tlsConfig)
+		grpcGenerator.SetAuth(authProvider)
+		grpcGenerator.SetResolver(resolver)
+		grpcGenerator.SetSource(source)
+	}
+
+	var churnGenerator *network.ConnChurnGenerator
+	if cfg.ChurnEnabled {
+		churnGenerator = network.NewConnChurnGenerator(cfg.ChurnTargetAddr, cfg.ChurnTargetCPS, cfg.ChurnPattern, cfg.ChurnUseTLS, cfg.ChurnHoldDuration, cfg.ChurnTimeout)
+		churnGenerator.SetTLSConfig(tlsConfig)
+		churnGenerator.SetSource(source)
+	}
+
+	var scenarioGenerator *network.ScenarioGenerator
+	if cfg.ScenarioFile != "" {
+		scenario, err := network.LoadScenario(cfg.ScenarioFile)
+		if err == nil {
+			scenarioGenerator = network.NewScenarioGenerator(scenario, cfg.ScenarioBaseURL, cfg.ScenarioUsers, cfg.HTTPTimeout)
+			if dataFeeder != nil {
+				scenarioGenerator.SetDataFeeder(dataFeeder)
+			}
+			scenarioGenerator.SetTLSConfig(tlsConfig)
+			scenarioGenerator.SetAuth(authProvider)
+			scenarioGenerator.SetUserPattern(cfg.ScenarioPattern)
+			if cfg.ScenarioStages != "" {
+				stages, _ := network.ParseStages(cfg.ScenarioStages)
+				scenarioGenerator.SetStages(stages)
+			}
+			if cfg.ScenarioThinkTime != "" {
+				thinkTime, _ := network.ParseThinkTime(cfg.ScenarioThinkTime)
+				scenarioGenerator.SetThinkTime(thinkTime)
+			}
+			err = scenarioGenerator.Prepare()
+		}
+		if err != nil {
+			logger.Error("Configuration error: %v", err)
+			os.Exit(1)
+		}
 	}
 
 	if cfg.MetricsEnabled {
@@ -125,7 +341,7 @@ func main() {
 					case <-ctx.Done():
 						return
 					case <-ticker.C:
-						updateMemoryMetrics(memoryGenerator, cfg.MemoryTargetMB)
+						updateMemoryMetrics(memoryGenerator, memoryGenerator.TargetMB())
 					}
 				}
 			}()
@@ -141,7 +357,7 @@ func main() {
 					case <-ctx.Done():
 						return
 					case <-ticker.C:
-						updateHTTPMetrics(httpGenerator, cfg.HTTPTargetRPS)
+						updateHTTPMetrics(httpGenerator, httpGenerator.TargetRPS())
 					}
 				}
 			}()
@@ -157,7 +373,7 @@ func main() {
 					case <-ctx.Done():
 						return
 					case <-ticker.C:
-						updateWebSocketMetrics(websocketGenerator, cfg.WebSocketTargetCPS)
+						updateWebSocketMetrics(websocketGenerator, websocketGenerator.TargetCPS())
 					}
 				}
 			}()
@@ -173,7 +389,39 @@ func main() {
 					case <-ctx.Done():
 						return
 					case <-ticker.C:
-						updateGRPCMetrics(grpcGenerator, cfg.GRPCTargetRPS)
+						updateGRPCMetrics(grpcGenerator, grpcGenerator.TargetRPS())
+					}
+				}
+			}()
+		}
+
+		if cfg.ChurnEnabled {
+			go func() {
+				ticker := time.NewTicker(2 * time.Second)
+				defer ticker.Stop()
+				
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						updateChurnMetrics(churnGenerator, cfg.ChurnTargetCPS)
+					}
+				}
+			}()
+		}
+
+		if scenarioGenerator != nil {
+			go func() {
+				ticker := time.NewTicker(2 * time.Second)
+				defer ticker.Stop()
+				
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						updateScenarioMetrics(scenarioGenerator)
 					}
 				}
 			}()
@@ -198,12 +446,43 @@ func main() {
 		websocketGenerator.Start(ctx)
 	}
 
+	if cfg.SSEEnabled {
+		sseGenerator.Start(ctx)
+	}
+
 	if cfg.GRPCEnabled {
 		if err := grpcGenerator.Start(ctx); err != nil {
 			logger.Error("Failed to start gRPC generator: %v", err)
 		}
 	}
 
+	if cfg.ChurnEnabled {
+		churnGenerator.Start(ctx)
+	}
+
+	if scenarioGenerator != nil {
+		if err := scenarioGenerator.Start(ctx); err != nil {
+			logger.Error("Failed to start scenario generator: %v", err)
+		}
+	}
+
+	var controlServer *control.Server
+	if cfg.ControlSocket != "" {
+		controlServer = control.NewServer(cfg.ControlSocket, &control.Generators{
+			CPU:       generator,
+			Memory:    memoryGenerator,
+			HTTP:      httpGenerator,
+			WebSocket: websocketGenerator,
+			SSE:       sseGenerator,
+			GRPC:      grpcGenerator,
+			Scenario:  scenarioGenerator,
+		})
+		if err := controlServer.Start(); err != nil {
+			logger.Error("Failed to start control socket: %v", err)
+			controlServer = nil
+		}
+	}
+
 	logger.Info("Starting StressPulse - Advanced Load Generator")
 	logger.Info("Target CPU: %.1f%%", cfg.TargetCPUPercent)
 	logger.Info("Drift Amplitude: %.1f%%", cfg.DriftAmplitude)
@@ -226,13 +505,44 @@ func main() {
 	}
 	if cfg.HTTPEnabled {
 		logger.Info("HTTP load test enabled: url=%s, target=%d RPS, pattern=%s, method=%s", cfg.HTTPTargetURL, cfg.HTTPTargetRPS, cfg.HTTPPattern, cfg.HTTPMethod)
+		logger.Info("HTTP connections: %s", cfg.HTTPConnectionOptions().Describe())
+		if cfg.HTTPProxy != "" {
+			logger.Info("HTTP proxy: %s", cfg.HTTPProxyOptions().Describe())
+		}
 	}
 	if cfg.WebSocketEnabled {
 		logger.Info("WebSocket load test enabled: url=%s, target=%d CPS, pattern=%s, message_size=%d", cfg.WebSocketTargetURL, cfg.WebSocketTargetCPS, cfg.WebSocketPattern, cfg.WebSocketMessageSize)
+		if cfg.WebSocketProxy != "" {
+			logger.Info("WebSocket proxy: %s", cfg.WebSocketProxyOptions().Describe())
+		}
+	}
+	if cfg.SSEEnabled {
+		logger.Info("SSE load test enabled: url=%s, target=%d CPS, pattern=%s, streams=%d, hold=%s", cfg.SSETargetURL, cfg.SSETargetCPS, cfg.SSEPattern, cfg.SSEMaxStreams, cfg.SSEHoldDuration)
 	}
 	if cfg.GRPCEnabled {
 		logger.Info("gRPC load test enabled: addr=%s, target=%d RPS, pattern=%s, method=%s, secure=%t", cfg.GRPCTargetAddr, cfg.GRPCTargetRPS, cfg.GRPCPattern, cfg.GRPCMethodType, cfg.GRPCUseSecure)
 	}
+	if cfg.ChurnEnabled {
+		logger.Info("Connection churn enabled: addr=%s, target=%d CPS, pattern=%s, tls=%t, hold=%s", cfg.ChurnTargetAddr, cfg.ChurnTargetCPS, cfg.ChurnPattern, cfg.ChurnUseTLS, cfg.ChurnHoldDuration)
+	}
+	if httpGenerator != nil || websocketGenerator != nil || sseGenerator != nil || grpcGenerator != nil || churnGenerator != nil || scenarioGenerator != nil {
+		logger.Info("TLS options: %s", tlsOptions.Describe())
+	}
+	if resolver != nil && (httpGenerator != nil || websocketGenerator != nil || sseGenerator != nil || grpcGenerator != nil) {
+		logger.Info("DNS resolution: %s", resolver.Describe())
+	}
+	if source != nil && (httpGenerator != nil || websocketGenerator != nil || sseGenerator != nil || grpcGenerator != nil || churnGenerator != nil) {
+		logger.Info("Source addresses: %s", source.Describe())
+	}
+	if captures != nil && httpGenerator != nil {
+		logger.Info("Request capture: %s", captures.Describe())
+	}
+	if authProvider != nil {
+		logger.Info("Request authentication enabled: type=%s", cfg.AuthType)
+	}
+	if scenarioGenerator != nil {
+		logger.Info("Scenario enabled: file=%s, users=%d, pattern=%s", cfg.ScenarioFile, cfg.ScenarioUsers, cfg.ScenarioPattern)
+	}
 	if cfg.WebEnabled {
 		logger.Info("Web interface enabled on port %d at http://localhost:%d", cfg.WebPort, cfg.WebPort)
 	}
@@ -252,6 +562,12 @@ func main() {
 		logger.Info("Received signal: %v", sig)
 	}
 
+	if controlServer != nil {
+		if err := controlServer.Stop(); err != nil {
+			logger.Error("Failed to stop control socket: %v", err)
+		}
+	}
+
 	if cfg.FakeLogsEnabled && fakeLogGenerator != nil {
 		fakeLogGenerator.Stop()
 	}
@@ -268,10 +584,22 @@ func main() {
 		websocketGenerator.Stop()
 	}
 
+	if cfg.SSEEnabled && sseGenerator != nil {
+		sseGenerator.Stop()
+	}
+
 	if cfg.GRPCEnabled && grpcGenerator != nil {
 		grpcGenerator.Stop()
 	}
 
+	if cfg.ChurnEnabled && churnGenerator != nil {
+		churnGenerator.Stop()
+	}
+
+	if scenarioGenerator != nil {
+		scenarioGenerator.Stop()
+	}
+
 	if generator != nil {
 		generator.Stop()
 	}
@@ -308,6 +636,49 @@ func main() {
 			httpStats.FailedRequests,
 			avgResponseTime,
 			successRate)
+		logPercentiles("HTTP", httpStats.Latency, httpStats.ServiceTime)
+		logger.Info("HTTP - Dropped: %d, Late: %d, Workers peak: %d of %d", httpStats.DroppedRequests, httpStats.LateRequests, httpStats.Workers.PeakWorkers, httpStats.Workers.MaxWorkers)
+		logger.Info("HTTP - Status codes: %v, Errors: %v", httpStats.StatusCodes, httpStats.ErrorClasses)
+		logger.Info("HTTP - Protocols: %v", httpStats.Protocols)
+		if resolver != nil || len(httpStats.Addresses) > 1 {
+			logger.Info("HTTP - Requests by address: %v", httpStats.Addresses)
+		}
+		logger.Info("HTTP - Connections new: %d, reused: %d (%.1f%%), Bytes sent: %d, received: %d",
+			httpStats.NewConnections,
+			httpStats.ReusedConnections,
+			httpStats.ConnectionReuse*100,
+			httpStats.BytesSent,
+			httpStats.BytesReceived)
+		for _, phase := range []string{network.PhaseDNS, network.PhaseConnect, network.PhaseProxy, network.PhaseTLS, network.PhaseTTFB, network.PhaseTransfer} {
+			if percentiles, ok := httpStats.Phases[phase]; ok && percentiles.Max > 0 {
+				logger.Info("HTTP phase [%s] - p50: %s, p95: %s, p99: %s, max: %s",
+					phase, percentiles.P50, percentiles.P95, percentiles.P99, percentiles.Max)
+			}
+		}
+		for _, check := range httpStats.Checks {
+			logger.Info("HTTP check [%s] - Passed: %d, Failed: %d", check.Name, check.Passed, check.Failed)
+		}
+		if len(httpStats.CheckFailures) > 0 {
+			failure := httpStats.CheckFailures[len(httpStats.CheckFailures)-1]
+			logger.Info("HTTP - Last failed check [%s] on %s: %s (status %d)", failure.Check, failure.Endpoint, failure.Message, failure.StatusCode)
+		}
+		if len(httpStats.Endpoints) > 1 || cfg.HTTPGraphQLFile != "" {
+			for _, endpoint := range httpStats.Endpoints {
+				logger.Info("HTTP [%s] - Total: %d, Success: %d, Failed: %d, Avg Response: %.1fms, Success Rate: %.1f%%",
+					endpoint.Name,
+					endpoint.TotalRequests,
+					endpoint.SuccessRequests,
+					endpoint.FailedRequests,
+					endpoint.AvgResponseTime,
+					endpoint.SuccessRate)
+				logger.Info("HTTP [%s] - Response time p50: %s, p95: %s, p99: %s, max: %s",
+					endpoint.Name, endpoint.Latency.P50, endpoint.Latency.P95, endpoint.Latency.P99, endpoint.Latency.Max)
+				if endpoint.GraphQL != nil {
+					logger.Info("HTTP [%s] - GraphQL errors: %d, codes: %v, APQ hits: %d, misses: %d",
+						endpoint.Name, endpoint.GraphQL.ErrorResponses, endpoint.GraphQL.ErrorCodes, endpoint.GraphQL.APQHits, endpoint.GraphQL.APQMisses)
+				}
+			}
+		}
 	}
 
 	if cfg.WebSocketEnabled {
@@ -323,6 +694,37 @@ func main() {
 			wsStats.MessagesReceived,
 			avgConnectionTime,
 			successRate)
+		logPercentiles("WebSocket", wsStats.Latency, wsStats.ServiceTime)
+		logger.Info("WebSocket - Dropped: %d, Late: %d, Workers peak: %d of %d", wsStats.DroppedConnections, wsStats.LateConnections, wsStats.Workers.PeakWorkers, wsStats.Workers.MaxWorkers)
+		if resolver != nil || len(wsStats.Addresses) > 1 {
+			logger.Info("WebSocket - Connections by address: %v", wsStats.Addresses)
+		}
+		if wsStats.ProxyConnect.Max > 0 {
+			logger.Info("WebSocket - Proxy connect p50: %s, p95: %s, p99: %s, max: %s",
+				wsStats.ProxyConnect.P50, wsStats.ProxyConnect.P95, wsStats.ProxyConnect.P99, wsStats.ProxyConnect.Max)
+		}
+		for _, step := range wsStats.Steps {
+			logger.Info("WebSocket [%s] - Total: %d, Success: %d, Timeouts: %d, Closed: %d, Failed: %d, Round trip p50: %s, p95: %s, p99: %s, max: %s",
+				step.Name, step.Total, step.Success, step.Timeouts, step.Closed, step.Failed,
+				step.RoundTrip.P50, step.RoundTrip.P95, step.RoundTrip.P99, step.RoundTrip.Max)
+		}
+	}
+
+	if cfg.SSEEnabled {
+		sseStats := sseGenerator.GetStats()
+		
+		logger.Info("SSE - Connections: %d, Failed: %d, Active streams: %d, Events: %d, Reconnects: %d, Bytes: %d, Success Rate: %.1f%%",
+			sseStats.TotalConnections,
+			sseStats.FailedConnections,
+			sseStats.ActiveStreams,
+			sseStats.EventsReceived,
+			sseStats.Reconnects,
+			sseStats.BytesReceived,
+			sseGenerator.GetSuccessRate())
+		logPercentiles("SSE", sseStats.Latency, sseStats.ServiceTime)
+		logger.Info("SSE - Event inter-arrival p50: %s, p90: %s, p95: %s, p99: %s, max: %s",
+			sseStats.InterArrival.P50, sseStats.InterArrival.P90, sseStats.InterArrival.P95, sseStats.InterArrival.P99, sseStats.InterArrival.Max)
+		logger.Info("SSE - Dropped: %d, Late: %d, Workers peak: %d of %d, Events by type: %v", sseStats.DroppedConnections, sseStats.LateConnections, sseStats.Workers.PeakWorkers, sseStats.Workers.MaxWorkers, sseStats.EventTypes)
 	}
 
 	if cfg.GRPCEnabled {
@@ -336,7 +738,192 @@ func main() {
 			grpcStats.FailedRequests,
 			avgResponseTime,
 			successRate)
+		logPercentiles("gRPC", grpcStats.Latency, grpcStats.ServiceTime)
+		logger.Info("gRPC - Dropped: %d, Late: %d, Workers peak: %d of %d", grpcStats.DroppedRequests, grpcStats.LateRequests, grpcStats.Workers.PeakWorkers, grpcStats.Workers.MaxWorkers)
+		if resolver != nil || len(grpcStats.Addresses) > 1 {
+			logger.Info("gRPC - Requests by address: %v", grpcStats.Addresses)
+		}
+	}
+
+	if cfg.ChurnEnabled {
+		churnStats := churnGenerator.GetStats()
+		avgHandshakeTime := churnGenerator.GetAverageHandshakeTime()
+		successRate := churnGenerator.GetSuccessRate()
+		
+		logger.Info("Churn - Total: %d, Success: %d, Failed: %d, Avg Handshake: %s, Success Rate: %.1f%%, TIME_WAIT: %d, Errors: %v",
+			churnStats.TotalConnections,
+			churnStats.SuccessConnections,
+			churnStats.FailedConnections,
+			avgHandshakeTime,
+			successRate,
+			churnStats.TimeWaitSockets,
+			churnStats.Errors)
+	}
+
+	if source != nil {
+		sourceStats := source.GetStats()
+		addresses := make([]string, 0, len(sourceStats))
+		for address := range sourceStats {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+		for _, address := range addresses {
+			stats := sourceStats[address]
+			logger.Info("Source [%s] - Connections: %d, Failed: %d, Port exhaustion: %d", address, stats.Connections, stats.Failed, stats.PortExhausted)
+		}
+	}
+
+	if scenarioGenerator != nil {
+		scenarioStats := scenarioGenerator.GetStats()
+		
+		logger.Info("Scenario - Iterations: %d, Success: %d, Failed: %d, Requests: %d, Success Rate: %.1f%%, Errors: %v",
+			scenarioStats.TotalIterations,
+			scenarioStats.SuccessIterations,
+			scenarioStats.FailedIterations,
+			scenarioStats.TotalRequests,
+			scenarioGenerator.GetSuccessRate(),
+			scenarioStats.ErrorClasses)
+		logger.Info("Scenario - Achieved throughput: %.1f iterations/s, %.1f requests/s",
+			scenarioStats.AvgIPS, scenarioStats.AvgRPS)
+		logger.Info("Scenario - Iteration time p50: %s, p95: %s, p99: %s, max: %s",
+			scenarioStats.Iteration.P50, scenarioStats.Iteration.P95, scenarioStats.Iteration.P99, scenarioStats.Iteration.Max)
+		for _, step := range scenarioStats.Steps {
+			logger.Info("Scenario [%s] - Total: %d, Success: %d, Failed: %d, Avg Response: %.1fms, p95: %s, Errors: %v",
+				step.Name,
+				step.TotalRequests,
+				step.SuccessRequests,
+				step.FailedRequests,
+				step.AvgResponseTime,
+				step.Latency.P95,
+				step.ErrorClasses)
+		}
+		for _, check := range scenarioStats.Checks {
+			logger.Info("Scenario check [%s] - Passed: %d, Failed: %d", check.Name, check.Passed, check.Failed)
+		}
+	}
+
+	if captures != nil && httpGenerator != nil {
+		snapshot := captures.Snapshot("")
+		logger.Info("Capture - Failed: %d, Slow: %d, Kept: %d", snapshot.Failed, snapshot.Slow, len(snapshot.Captures))
+		if captures.File() != "" {
+			if err := captures.WriteFile(captures.File()); err != nil {
+				logger.Error("Failed to write captures to %s: %v", captures.File(), err)
+			} else {
+				logger.Info("Captures written to %s", captures.File())
+			}
+		}
+	}
+
+	if authProvider != nil {
+		authStats := authProvider.Stats()
+		
+		if authStats.Type == network.AuthTypeOAuth2 {
+			logger.Info("Auth [%s] - Token fetches: %d, Failures: %d, Fetch time p50: %s, p95: %s, max: %s",
+				authStats.Type,
+				authStats.TokenFetches,
+				authStats.TokenFailures,
+				authStats.FetchLatency.P50,
+				authStats.FetchLatency.P95,
+				authStats.FetchLatency.Max)
+			if authStats.LastError != "" {
+				logger.Info("Auth [%s] - Last error: %s", authStats.Type, authStats.LastError)
+			}
+		}
+	}
+}
+
+// runImportHAR - подкоманда import-har: превращает HAR в сценарий или набор шаблонов для -http-requests.
+func runImportHAR(args []string) int {
+	flags := flag.NewFlagSet("import-har", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: stresspulse import-har [flags] recording.har\n")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "scenario", "Формат результата: scenario (виртуальные пользователи с записанными паузами) или requests (шаблоны для -http-requests)")
+	output := flags.String("o", "", "Файл для результата (по умолчанию stdout)")
+	name := flags.String("name", "", "Имя сценария (по умолчанию имя HAR файла)")
+	includeHosts := flags.String("include-hosts", "", "Импортировать только эти хосты и их поддомены, через запятую")
+	excludeHosts := flags.String("exclude-hosts", "", "Пропустить эти хосты и их поддомены, через запятую")
+	excludeTypes := flags.String("exclude-types", "", "Пропустить ответы с этими Content-Type (префиксы через запятую, например image/,text/css)")
+	skipStatic := flags.Bool("skip-static", false, "Пропустить статику: картинки, шрифты, CSS, JS, медиа")
+	keepCookies := flags.Bool("keep-cookies", false, "Сохранить записанные Cookie заголовки (по умолчанию cookie ведёт клиент)")
+	rateScale := flags.Float64("rate-scale", 1, "Множитель записанной частоты запросов для подсказки -http-rps")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if *format != "scenario" && *format != "requests" {
+		fmt.Fprintf(os.Stderr, "import-har: unknown format %q, expected scenario or requests\n", *format)
+		return 2
+	}
+	if *rateScale <= 0 {
+		fmt.Fprintf(os.Stderr, "import-har: rate scale must be positive\n")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-har: failed to read HAR file: %v\n", err)
+		return 1
+	}
+
+	recording, err := network.ParseHAR(data, &network.HARImportOptions{
+		IncludeHosts:        network.SplitList(*includeHosts),
+		ExcludeHosts:        network.SplitList(*excludeHosts),
+		ExcludeContentTypes: network.SplitList(*excludeTypes),
+		SkipStatic:          *skipStatic,
+		KeepCookies:         *keepCookies,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-har: %v\n", err)
+		return 1
+	}
+
+	var result interface{}
+	if *format == "scenario" {
+		scenarioName := *name
+		if scenarioName == "" {
+			scenarioName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		result = recording.Scenario(scenarioName)
+	} else {
+		result = recording.RequestMix()
+	}
+
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import-har: %v\n", err)
+		return 1
+	}
+	encoded = append(encoded, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(encoded)
+	} else if err := os.WriteFile(*output, encoded, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "import-har: failed to write %s: %v\n", *output, err)
+		return 1
+	}
+
+	recordedRPS := recording.RecordedRPS()
+	fmt.Fprintf(os.Stderr, "Imported %d requests (%d skipped) recorded over %s, %.2f req/s\n",
+		len(recording.Requests), recording.Skipped, recording.Duration.Round(time.Millisecond), recordedRPS)
+	if *format == "requests" {
+		fmt.Fprintf(os.Stderr, "Replay: -http -http-requests <file> -http-rps %d\n", int(math.Max(1, math.Round(recordedRPS**rateScale))))
+	} else {
+		fmt.Fprintf(os.Stderr, "Replay: -scenario <file> -scenario-users <N>\n")
 	}
+	return 0
+}
+
+func logPercentiles(name string, responseTime, serviceTime network.LatencyPercentiles) {
+	logger.Info("%s - Response time p50: %s, p90: %s, p95: %s, p99: %s, p99.9: %s, max: %s",
+		name, responseTime.P50, responseTime.P90, responseTime.P95, responseTime.P99, responseTime.P999, responseTime.Max)
+	logger.Info("%s - Service time p50: %s, p90: %s, p95: %s, p99: %s, p99.9: %s, max: %s",
+		name, serviceTime.P50, serviceTime.P90, serviceTime.P95, serviceTime.P99, serviceTime.P999, serviceTime.Max)
 }
 
 func updateMemoryMetrics(memGen *memory.MemoryGenerator, targetMB int) {
@@ -379,7 +966,8 @@ func updateHTTPMetrics(httpGen *network.HTTPGenerator, targetRPS int) {
 	}
 	metrics.HTTPMaxResponseTimeGauge.Set(stats.MaxResponseTime.Seconds())
 	
-	metrics.HTTPResponseTimeHistogram.Observe(avgResponseTime.Seconds())
+	setPercentileGauges(metrics.HTTPResponseTimePercentileGauge, stats.Latency)
+	metrics.HTTPConnectionReuseRatioGauge.Set(stats.ConnectionReuse)
 	
 	metrics.HTTPSuccessRateGauge.Set(successRate)
 }
@@ -403,7 +991,7 @@ func updateWebSocketMetrics(wsGen *network.WebSocketGenerator, targetCPS int) {
 	metrics.WebSocketMessagesReceivedCounter.Add(float64(stats.MessagesReceived))
 	
 	metrics.WebSocketAvgConnectionTimeGauge.Set(avgConnectionTime.Seconds())
-	metrics.WebSocketConnectionTimeHistogram.Observe(avgConnectionTime.Seconds())
+	setPercentileGauges(metrics.WebSocketConnectionTimePercentileGauge, stats.Latency)
 	
 	metrics.WebSocketSuccessRateGauge.Set(successRate)
 }
@@ -429,7 +1017,7 @@ func updateGRPCMetrics(grpcGen *network.GRPCGenerator, targetRPS int) {
 	}
 	metrics.GRPCMaxResponseTimeGauge.Set(stats.MaxResponseTime.Seconds())
 	
-	metrics.GRPCResponseTimeHistogram.Observe(avgResponseTime.Seconds())
+	setPercentileGauges(metrics.GRPCResponseTimePercentileGauge, stats.Latency)
 	
 	metrics.GRPCSuccessRateGauge.Set(successRate)
 	
@@ -438,6 +1026,69 @@ func updateGRPCMetrics(grpcGen *network.GRPCGenerator, targetRPS int) {
 	}
 }
 
+func setPercentileGauges(gauge *prometheus.GaugeVec, latency network.LatencyPercentiles) {
+	gauge.WithLabelValues("0.5").Set(latency.P50.Seconds())
+	gauge.WithLabelValues("0.9").Set(latency.P90.Seconds())
+	gauge.WithLabelValues("0.95").Set(latency.P95.Seconds())
+	gauge.WithLabelValues("0.99").Set(latency.P99.Seconds())
+	gauge.WithLabelValues("0.999").Set(latency.P999.Seconds())
+	gauge.WithLabelValues("1").Set(latency.Max.Seconds())
+}
+
+func updateChurnMetrics(churnGen *network.ConnChurnGenerator, targetCPS int) {
+	if churnGen == nil {
+		return
+	}
+
+	stats := churnGen.GetStats()
+
+	metrics.ChurnActiveConnectionsGauge.Set(float64(stats.ActiveConnections))
+	metrics.ChurnCurrentCPSGauge.Set(float64(stats.CurrentCPS))
+	metrics.ChurnTargetCPSGauge.Set(float64(targetCPS))
+	if stats.TimeWaitSockets >= 0 {
+		metrics.ChurnTimeWaitSocketsGauge.Set(float64(stats.TimeWaitSockets))
+	}
+}
+
+func buildHTTPChecks(cfg *config.Config) []*network.ResponseCheck {
+	var checks []*network.ResponseCheck
+	
+	if cfg.HTTPCheckStatus != "" {
+		codes, _ := network.ParseCheckStatus(cfg.HTTPCheckStatus)
+		checks = append(checks, &network.ResponseCheck{Status: codes})
+	}
+	if cfg.HTTPCheckBody != "" {
+		checks = append(checks, &network.ResponseCheck{BodyContains: cfg.HTTPCheckBody})
+	}
+	if cfg.HTTPCheckRegex != "" {
+		checks = append(checks, &network.ResponseCheck{BodyRegex: cfg.HTTPCheckRegex})
+	}
+	if cfg.HTTPCheckJSON != "" {
+		checks = append(checks, network.ParseJSONCheck(cfg.HTTPCheckJSON))
+	}
+	if cfg.HTTPCheckHeader != "" {
+		checks = append(checks, &network.ResponseCheck{Header: cfg.HTTPCheckHeader})
+	}
+	if cfg.HTTPMaxLatency > 0 {
+		checks = append(checks, &network.ResponseCheck{MaxLatency: network.Duration(cfg.HTTPMaxLatency)})
+	}
+	
+	return checks
+}
+
+func updateScenarioMetrics(scenarioGen *network.ScenarioGenerator) {
+	if scenarioGen == nil {
+		return
+	}
+
+	stats := scenarioGen.GetStats()
+
+	metrics.ScenarioActiveUsersGauge.Set(float64(stats.ActiveUsers))
+	metrics.ScenarioTargetUsersGauge.Set(float64(stats.TargetUsers))
+	metrics.ScenarioCurrentIPSGauge.Set(float64(stats.CurrentIPS))
+	metrics.ScenarioCurrentRPSGauge.Set(float64(stats.CurrentRPS))
+}
+
 func parseHTTPHeaders(headersStr string) map[string]string {
 	headers := make(map[string]string)
 	
@@ -20,6 +20,7 @@ type MemoryGenerator struct {
 	ctx              context.Context
 	cancel           context.CancelFunc
 	stats            *MemoryStats
+	patternStart     time.Time
 }
 
 type MemoryStats struct {
@@ -31,18 +32,41 @@ type MemoryStats struct {
 }
 
 func NewMemoryGenerator(targetMemoryMB int, pattern string, interval time.Duration) *MemoryGenerator {
+	now := time.Now()
 	return &MemoryGenerator{
 		targetMemoryMB:  targetMemoryMB,
 		pattern:         pattern,
 		interval:        interval,
 		enabled:         false,
 		allocatedBlocks: make([][]byte, 0),
+		patternStart:    now,
 		stats: &MemoryStats{
-			StartTime: time.Now(),
+			StartTime: now,
 		},
 	}
 }
 
+// SetTargetMB меняет целевой объём памяти на ходу; следующий тик паттерна выделит или освободит разницу.
+func (mg *MemoryGenerator) SetTargetMB(targetMB int) {
+	mg.mutex.Lock()
+	mg.targetMemoryMB = targetMB
+	mg.mutex.Unlock()
+}
+
+// SetPattern меняет паттерн на ходу; cycle отсчитывается заново с момента смены.
+func (mg *MemoryGenerator) SetPattern(pattern string) {
+	mg.mutex.Lock()
+	mg.pattern = pattern
+	mg.patternStart = time.Now()
+	mg.mutex.Unlock()
+}
+
+func (mg *MemoryGenerator) TargetMB() int {
+	mg.mutex.Lock()
+	defer mg.mutex.Unlock()
+	return mg.targetMemoryMB
+}
+
 func (mg *MemoryGenerator) Start(ctx context.Context) {
 	if mg.enabled {
 		return
@@ -86,7 +110,11 @@ func (mg *MemoryGenerator) generateMemoryLoad() {
 }
 
 func (mg *MemoryGenerator) executePattern() {
-	switch mg.pattern {
+	mg.mutex.Lock()
+	pattern := mg.pattern
+	mg.mutex.Unlock()
+
+	switch pattern {
 	case "constant":
 		mg.constantAllocation()
 	case "leak":
@@ -154,7 +182,7 @@ func (mg *MemoryGenerator) memoryCycle() {
 
 	currentMB := mg.getCurrentAllocatedMB()
 	
-	elapsedSeconds := int(time.Since(mg.stats.StartTime).Seconds())
+	elapsedSeconds := int(time.Since(mg.patternStart).Seconds())
 	cyclePosition := (elapsedSeconds / 30) % 4 // 30-секундные фазы
 	
 	var targetForPhase int
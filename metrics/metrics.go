@@ -93,10 +93,103 @@ var (
 
 	HTTPResponseTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "http_response_time_seconds",
-		Help:    "HTTP response time distribution",
+		Help:    "HTTP response time distribution measured from the scheduled send time",
 		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
 	})
 
+	HTTPServiceTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "http_service_time_seconds",
+		Help:    "HTTP service time distribution measured from the actual send time",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	})
+
+	HTTPDroppedRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_requests_dropped_total",
+		Help: "Total number of scheduled HTTP requests dropped because the queue was full",
+	})
+
+	HTTPLateRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_requests_late_total",
+		Help: "Total number of HTTP requests sent later than scheduled",
+	})
+
+	HTTPStatusCodesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_status_codes_total",
+		Help: "Total number of HTTP responses by status code",
+	}, []string{"code"})
+
+	HTTPErrorClassesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_errors_total",
+		Help: "Total number of failed HTTP requests by error class",
+	}, []string{"class"})
+
+	HTTPChecksCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_checks_total",
+		Help: "Total number of HTTP response checks by check name and result",
+	}, []string{"check", "result"})
+
+	HTTPGraphQLErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_graphql_errors_total",
+		Help: "Total number of GraphQL errors in responses by operation and error code",
+	}, []string{"operation", "code"})
+
+	HTTPGraphQLPersistedQueriesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_graphql_apq_total",
+		Help: "Total number of GraphQL persisted query lookups by operation and result (hit, miss)",
+	}, []string{"operation", "result"})
+
+	HTTPEndpointRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_endpoint_requests_total",
+		Help: "Total number of HTTP requests by request template and result",
+	}, []string{"endpoint", "result"})
+
+	HTTPEndpointResponseTimeHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_endpoint_response_time_seconds",
+		Help:    "HTTP response time by request template, measured from the scheduled send time",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	}, []string{"endpoint"})
+
+	HTTPPhaseDurationHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_phase_duration_seconds",
+		Help:    "HTTP request phase duration: dns, connect, proxy, tls, ttfb, transfer",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 18), // 100us to ~13s
+	}, []string{"phase"})
+
+	HTTPConnectionsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_connections_total",
+		Help: "Connections obtained for HTTP requests by type (new or reused)",
+	}, []string{"type"})
+
+	HTTPProtocolCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_responses_by_protocol_total",
+		Help: "HTTP responses by negotiated protocol (HTTP/1.1, HTTP/2.0)",
+	}, []string{"protocol"})
+
+	HTTPRequestsByAddressCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_by_address_total",
+		Help: "HTTP requests by IP address of the connection they were sent over",
+	}, []string{"address"})
+
+	HTTPConnectionReuseRatioGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_connection_reuse_ratio",
+		Help: "Share of HTTP requests sent over a reused connection (0-1)",
+	})
+
+	HTTPBytesSentCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_bytes_sent_total",
+		Help: "Bytes written to HTTP connections, including headers and TLS",
+	})
+
+	HTTPBytesReceivedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_bytes_received_total",
+		Help: "Bytes read from HTTP connections, including headers and TLS",
+	})
+
+	HTTPResponseTimePercentileGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_response_time_percentile_seconds",
+		Help: "HTTP response time percentiles since start",
+	}, []string{"quantile"})
+
 	HTTPResponseTimeGauge = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "http_avg_response_time_seconds",
 		Help: "Average HTTP response time",
@@ -154,10 +247,53 @@ var (
 
 	WebSocketConnectionTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "websocket_connection_time_seconds",
-		Help:    "WebSocket connection time distribution",
+		Help:    "WebSocket connection time distribution measured from the scheduled dial time",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	})
+
+	WebSocketConnectionServiceTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "websocket_connection_service_time_seconds",
+		Help:    "WebSocket connection time distribution measured from the actual dial time",
 		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
 	})
 
+	WebSocketConnectionsByAddressCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_connections_by_address_total",
+		Help: "Established WebSocket connections by server IP address",
+	}, []string{"address"})
+
+	WebSocketProxyConnectHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "websocket_proxy_connect_seconds",
+		Help:    "Time to open a WebSocket tunnel through the upstream proxy",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 18), // 100us to ~13s
+	})
+
+	WebSocketDroppedConnectionsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "websocket_connections_dropped_total",
+		Help: "Total number of scheduled WebSocket connections dropped because the queue was full",
+	})
+
+	WebSocketLateConnectionsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "websocket_connections_late_total",
+		Help: "Total number of WebSocket connections dialed later than scheduled",
+	})
+
+	WebSocketScriptStepsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_script_steps_total",
+		Help: "Total number of WebSocket script steps by step and result",
+	}, []string{"step", "result"})
+
+	WebSocketScriptRoundTripHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "websocket_script_round_trip_seconds",
+		Help:    "Time from the start of a WebSocket script step to the matching message",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	}, []string{"step"})
+
+	WebSocketConnectionTimePercentileGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "websocket_connection_time_percentile_seconds",
+		Help: "WebSocket connection time percentiles since start",
+	}, []string{"quantile"})
+
 	WebSocketAvgConnectionTimeGauge = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "websocket_avg_connection_time_seconds",
 		Help: "Average WebSocket connection time",
@@ -168,6 +304,48 @@ var (
 		Help: "WebSocket connection success rate in percentage",
 	})
 
+	SSEConnectionsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sse_connections_total",
+		Help: "Total number of SSE stream connections by result",
+	}, []string{"result"})
+
+	SSEActiveStreamsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sse_active_streams",
+		Help: "Current number of open SSE streams",
+	})
+
+	SSEEventsReceivedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sse_events_received_total",
+		Help: "Total number of SSE events received",
+	})
+
+	SSEReconnectsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sse_reconnects_total",
+		Help: "Total number of SSE reconnects with Last-Event-ID after the server closed the stream",
+	})
+
+	SSEConnectTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "sse_connect_time_seconds",
+		Help:    "Time from the scheduled connection time to SSE response headers",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	})
+
+	SSEEventInterArrivalHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "sse_event_interarrival_seconds",
+		Help:    "Time between consecutive SSE events on the same stream",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 18), // 1ms to ~2m
+	})
+
+	SSEDroppedConnectionsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sse_connections_dropped_total",
+		Help: "Total number of scheduled SSE connections dropped because the queue was full",
+	})
+
+	SSELateConnectionsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sse_connections_late_total",
+		Help: "Total number of SSE connections opened later than scheduled",
+	})
+
 	GRPCRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "grpc_requests_total",
 		Help: "Total number of gRPC requests sent",
@@ -195,10 +373,36 @@ var (
 
 	GRPCResponseTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "grpc_response_time_seconds",
-		Help:    "gRPC response time distribution",
+		Help:    "gRPC response time distribution measured from the scheduled send time",
 		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
 	})
 
+	GRPCServiceTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "grpc_service_time_seconds",
+		Help:    "gRPC service time distribution measured from the actual send time",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	})
+
+	GRPCRequestsByAddressCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_requests_by_address_total",
+		Help: "gRPC calls by server IP address",
+	}, []string{"address"})
+
+	GRPCDroppedRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "grpc_requests_dropped_total",
+		Help: "Total number of scheduled gRPC requests dropped because the queue was full",
+	})
+
+	GRPCLateRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "grpc_requests_late_total",
+		Help: "Total number of gRPC requests sent later than scheduled",
+	})
+
+	GRPCResponseTimePercentileGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpc_response_time_percentile_seconds",
+		Help: "gRPC response time percentiles since start",
+	}, []string{"quantile"})
+
 	GRPCResponseTimeGauge = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "grpc_avg_response_time_seconds",
 		Help: "Average gRPC response time",
@@ -223,4 +427,125 @@ var (
 		Name: "grpc_status_codes_total",
 		Help: "Total number of gRPC requests by status code",
 	}, []string{"code"})
-) 
\ No newline at end of file
+
+	ChurnConnectionsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "churn_connections_total",
+		Help: "Total number of TCP connections attempted by the churn generator",
+	})
+
+	ChurnErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "churn_connection_errors_total",
+		Help: "Total number of failed churn connections by errno",
+	}, []string{"errno"})
+
+	ChurnActiveConnectionsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "churn_active_connections",
+		Help: "Current number of connections held open by the churn generator",
+	})
+
+	ChurnCurrentCPSGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "churn_connections_per_second",
+		Help: "Current churn connections per second",
+	})
+
+	ChurnTargetCPSGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "churn_target_cps",
+		Help: "Target churn connections per second",
+	})
+
+	ChurnHandshakeTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "churn_handshake_time_seconds",
+		Help:    "TCP handshake time distribution",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17), // 100us to ~6.5s
+	})
+
+	ChurnTLSHandshakeTimeHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "churn_tls_handshake_time_seconds",
+		Help:    "TLS handshake time distribution",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17), // 100us to ~6.5s
+	})
+
+	ChurnTimeWaitSocketsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "churn_time_wait_sockets",
+		Help: "Number of TCP sockets in TIME_WAIT state on this host",
+	})
+
+	SourceConnectionsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "source_connections_total",
+		Help: "Connections opened from a configured local source address by result",
+	}, []string{"address", "result"})
+
+	SourcePortExhaustionCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "source_port_exhaustion_total",
+		Help: "Connection attempts that failed because the local source address ran out of ephemeral ports",
+	}, []string{"address"})
+
+	// Метрики сценариев виртуальных пользователей
+	AuthTokenFetchesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_fetches_total",
+		Help: "Total number of auth token requests by result",
+	}, []string{"result"})
+
+	AuthTokenFetchDurationHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "auth_token_fetch_duration_seconds",
+		Help:    "Auth token request duration distribution",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	})
+
+	ScenarioIterationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scenario_iterations_total",
+		Help: "Total number of scenario iterations by result",
+	}, []string{"result"})
+
+	ScenarioIterationDurationHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "scenario_iteration_duration_seconds",
+		Help:    "Full scenario iteration duration including think time",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 15), // 10ms to ~5min
+	})
+
+	ScenarioStepRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scenario_step_requests_total",
+		Help: "Total number of scenario step requests by step and result",
+	}, []string{"step", "result"})
+
+	ScenarioStepResponseTimeHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scenario_step_response_time_seconds",
+		Help:    "Scenario step response time distribution",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+	}, []string{"step"})
+
+	ScenarioActiveUsersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scenario_active_users",
+		Help: "Current number of running virtual users",
+	})
+
+	ScenarioTargetUsersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scenario_target_users",
+		Help: "Target number of virtual users from pattern or stages",
+	})
+
+	ScenarioCurrentIPSGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scenario_iterations_per_second",
+		Help: "Current completed scenario iterations per second",
+	})
+
+	ScenarioCurrentRPSGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scenario_requests_per_second",
+		Help: "Current scenario requests per second",
+	})
+
+	WorkerPoolWorkersGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "worker_pool_workers",
+		Help: "Current number of workers in the generator worker pool",
+	}, []string{"generator"})
+
+	WorkerPoolBusyGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "worker_pool_busy_workers",
+		Help: "Current number of workers processing a request",
+	}, []string{"generator"})
+
+	WorkerPoolSaturatedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "worker_pool_saturated",
+		Help: "1 if the worker pool hit its concurrency cap in the last 10 seconds",
+	}, []string{"generator"})
+)
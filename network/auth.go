@@ -0,0 +1,494 @@
+package network
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+const (
+	AuthTypeStatic = "static"
+	AuthTypeBasic  = "basic"
+	AuthTypeOAuth2 = "oauth2"
+	AuthTypeHMAC   = "hmac"
+)
+
+const (
+	// Токен OAuth2 обновляется заранее, когда до истечения остаётся эта доля срока жизни.
+	oauth2RefreshFraction = 0.2
+	// После неудачного получения токена повторная попытка не раньше чем через oauth2RetryDelay.
+	oauth2RetryDelay    = 1 * time.Second
+	oauth2FetchTimeout  = 10 * time.Second
+	oauth2DefaultExpiry = 5 * time.Minute
+)
+
+// AuthOptions - настройки аутентификации запросов. Используются только поля выбранного типа.
+type AuthOptions struct {
+	Type string `json:"type"`
+
+	// static: заголовок Header со значением "Scheme Token" (схема опускается, если пустая).
+	Token  string `json:"token,omitempty"`
+	Header string `json:"header,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+
+	// basic
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// oauth2 client credentials
+	TokenURL         string            `json:"tokenUrl,omitempty"`
+	ClientID         string            `json:"clientId,omitempty"`
+	ClientSecret     string            `json:"clientSecret,omitempty"`
+	Scopes           []string          `json:"scopes,omitempty"`
+	Params           map[string]string `json:"params,omitempty"`
+	ClientAuthInBody bool              `json:"clientAuthInBody,omitempty"`
+
+	// hmac: подпись в формате HTTP Signatures (keyId, algorithm, headers, signature).
+	KeyID           string   `json:"keyId,omitempty"`
+	Secret          string   `json:"secret,omitempty"`
+	Algorithm       string   `json:"algorithm,omitempty"`
+	SignedHeaders   []string `json:"signedHeaders,omitempty"`
+	SignatureHeader string   `json:"signatureHeader,omitempty"`
+}
+
+// AuthRequest - запрос, который нужно аутентифицировать. Заголовки меняются на месте.
+// Host - значение, которое уйдёт в заголовке Host, если оно отличается от URL.Host.
+type AuthRequest struct {
+	Method string
+	URL    *url.URL
+	Host   string
+	Header http.Header
+	Body   []byte
+}
+
+// AuthProvider добавляет к запросу данные аутентификации.
+type AuthProvider interface {
+	Authorize(req *AuthRequest) error
+	Stats() AuthStats
+}
+
+// AuthStats - статистика получения токенов; считается отдельно от запросов генераторов.
+type AuthStats struct {
+	Type          string             `json:"type"`
+	TokenFetches  int64              `json:"tokenFetches"`
+	TokenFailures int64              `json:"tokenFailures"`
+	LastError     string             `json:"lastError,omitempty"`
+	ExpiresAt     *time.Time         `json:"expiresAt,omitempty"`
+	FetchLatency  LatencyPercentiles `json:"fetchLatency"`
+}
+
+// Validate проверяет, что для выбранного типа заданы обязательные поля.
+func (o *AuthOptions) Validate() error {
+	switch o.Type {
+	case "":
+		return nil
+	case AuthTypeStatic:
+		if o.Token == "" {
+			return fmt.Errorf("static auth requires a token")
+		}
+	case AuthTypeBasic:
+		if o.Username == "" {
+			return fmt.Errorf("basic auth requires a username")
+		}
+	case AuthTypeOAuth2:
+		if o.TokenURL == "" || o.ClientID == "" {
+			return fmt.Errorf("oauth2 auth requires token URL and client ID")
+		}
+		if _, err := url.ParseRequestURI(o.TokenURL); err != nil {
+			return fmt.Errorf("invalid oauth2 token URL: %v", err)
+		}
+	case AuthTypeHMAC:
+		if o.KeyID == "" || o.Secret == "" {
+			return fmt.Errorf("hmac auth requires key ID and secret")
+		}
+		if _, err := hmacHash(o.Algorithm); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown auth type: %s", o.Type)
+	}
+	return nil
+}
+
+// NewAuthProvider создаёт провайдер по настройкам; для пустого типа возвращает nil.
+// tlsConfig используется для запросов к token endpoint.
+func NewAuthProvider(options *AuthOptions, tlsConfig *tls.Config) (AuthProvider, error) {
+	if options == nil || options.Type == "" {
+		return nil, nil
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch options.Type {
+	case AuthTypeStatic:
+		header := options.Header
+		if header == "" {
+			header = "Authorization"
+		}
+		value := options.Token
+		if options.Scheme != "" {
+			value = options.Scheme + " " + options.Token
+		}
+		return &staticAuth{kind: AuthTypeStatic, header: header, value: value}, nil
+	case AuthTypeBasic:
+		credentials := base64.StdEncoding.EncodeToString([]byte(options.Username + ":" + options.Password))
+		return &staticAuth{kind: AuthTypeBasic, header: "Authorization", value: "Basic " + credentials}, nil
+	case AuthTypeOAuth2:
+		return newOAuth2Auth(options, tlsConfig), nil
+	default:
+		return newHMACAuth(options), nil
+	}
+}
+
+// staticAuth ставит один и тот же заголовок в каждый запрос (static и basic).
+type staticAuth struct {
+	kind   string
+	header string
+	value  string
+}
+
+func (a *staticAuth) Authorize(req *AuthRequest) error {
+	req.Header.Set(a.header, a.value)
+	return nil
+}
+
+func (a *staticAuth) Stats() AuthStats {
+	return AuthStats{Type: a.kind}
+}
+
+// oauth2Auth получает токен по client credentials и обновляет его в фоне до истечения.
+type oauth2Auth struct {
+	options   *AuthOptions
+	client    *http.Client
+	token     string
+	tokenType string
+	expiresAt time.Time
+	refreshAt time.Time
+	retryAt   time.Time
+	// pending закрывается, когда текущее получение токена завершится; nil - токен никто не получает.
+	pending  chan struct{}
+	lastErr  error
+	fetches  int64
+	failures int64
+	latency  *LatencyHistogram
+	mutex    sync.Mutex
+}
+
+func newOAuth2Auth(options *AuthOptions, tlsConfig *tls.Config) *oauth2Auth {
+	return &oauth2Auth{
+		options: options,
+		client: &http.Client{
+			Timeout:   oauth2FetchTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		latency: NewLatencyHistogram(),
+	}
+}
+
+func (a *oauth2Auth) Authorize(req *AuthRequest) error {
+	token, tokenType, err := a.currentToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", tokenType+" "+token)
+	return nil
+}
+
+// currentToken возвращает действующий токен. Если токена нет или он истёк, запрос ждёт получения нового;
+// если токен скоро истечёт, он обновляется в фоне, а запросы продолжают использовать текущий.
+// Токен получает одна горутина за раз и без мьютекса, так что запросы с действующим токеном не ждут token endpoint.
+func (a *oauth2Auth) currentToken() (string, string, error) {
+	a.mutex.Lock()
+
+	now := time.Now()
+	if a.token != "" && now.Before(a.expiresAt) {
+		if now.After(a.refreshAt) && now.After(a.retryAt) && a.pending == nil {
+			a.startFetch()
+		}
+		token, tokenType := a.token, a.tokenType
+		a.mutex.Unlock()
+		return token, tokenType, nil
+	}
+
+	if a.pending == nil {
+		if now.Before(a.retryAt) {
+			err := a.lastErr
+			a.mutex.Unlock()
+			return "", "", fmt.Errorf("oauth2 token unavailable: %v", err)
+		}
+		a.startFetch()
+	}
+	pending := a.pending
+	a.mutex.Unlock()
+
+	<-pending
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" && time.Now().Before(a.expiresAt) {
+		return a.token, a.tokenType, nil
+	}
+	return "", "", fmt.Errorf("oauth2 token request failed: %v", a.lastErr)
+}
+
+// startFetch запускает получение токена в фоне; вызывается под мьютексом.
+func (a *oauth2Auth) startFetch() {
+	done := make(chan struct{})
+	a.pending = done
+
+	go func() {
+		defer close(done)
+		result := a.fetch()
+
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		a.pending = nil
+		if err := a.store(result); err != nil && a.token != "" && time.Now().Before(a.expiresAt) {
+			logger.Warning("OAuth2 token refresh failed, using current token until it expires: %v", err)
+		}
+	}()
+}
+
+type oauth2Token struct {
+	token     string
+	tokenType string
+	expiresIn time.Duration
+	err       error
+}
+
+func (a *oauth2Auth) fetch() oauth2Token {
+	startTime := time.Now()
+	var result oauth2Token
+	result.token, result.tokenType, result.expiresIn, result.err = a.requestToken()
+	duration := time.Since(startTime)
+
+	a.latency.Record(duration)
+	metrics.AuthTokenFetchDurationHistogram.Observe(duration.Seconds())
+	atomic.AddInt64(&a.fetches, 1)
+
+	if result.err != nil {
+		atomic.AddInt64(&a.failures, 1)
+		metrics.AuthTokenFetchesCounter.WithLabelValues("failed").Inc()
+	} else {
+		metrics.AuthTokenFetchesCounter.WithLabelValues("success").Inc()
+		logger.Debug("OAuth2 token obtained in %s, expires in %s", duration, result.expiresIn)
+	}
+	return result
+}
+
+// store сохраняет полученный токен; вызывается под мьютексом.
+func (a *oauth2Auth) store(result oauth2Token) error {
+	now := time.Now()
+	if result.err != nil {
+		a.lastErr = result.err
+		a.retryAt = now.Add(oauth2RetryDelay)
+		return fmt.Errorf("oauth2 token request failed: %v", result.err)
+	}
+
+	a.token = result.token
+	a.tokenType = result.tokenType
+	a.expiresAt = now.Add(result.expiresIn)
+	a.refreshAt = now.Add(time.Duration(float64(result.expiresIn) * (1 - oauth2RefreshFraction)))
+	a.lastErr = nil
+	return nil
+}
+
+func (a *oauth2Auth) requestToken() (string, string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(a.options.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.options.Scopes, " "))
+	}
+	for key, value := range a.options.Params {
+		form.Set(key, value)
+	}
+	if a.options.ClientAuthInBody {
+		form.Set("client_id", a.options.ClientID)
+		form.Set("client_secret", a.options.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.options.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !a.options.ClientAuthInBody {
+		req.SetBasicAuth(url.QueryEscape(a.options.ClientID), url.QueryEscape(a.options.ClientSecret))
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBodySize))
+	if err != nil {
+		return "", "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", 0, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var response struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", "", 0, fmt.Errorf("invalid token response: %v", err)
+	}
+	if response.AccessToken == "" {
+		return "", "", 0, fmt.Errorf("token response has no access_token")
+	}
+
+	tokenType := response.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	expiresIn := oauth2DefaultExpiry
+	if seconds, err := strconv.ParseFloat(response.ExpiresIn.String(), 64); err == nil && seconds > 0 {
+		expiresIn = time.Duration(seconds * float64(time.Second))
+	}
+
+	return response.AccessToken, tokenType, expiresIn, nil
+}
+
+func (a *oauth2Auth) Stats() AuthStats {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	stats := AuthStats{
+		Type:          AuthTypeOAuth2,
+		TokenFetches:  atomic.LoadInt64(&a.fetches),
+		TokenFailures: atomic.LoadInt64(&a.failures),
+		FetchLatency:  a.latency.Percentiles(),
+	}
+	if !a.expiresAt.IsZero() {
+		expiresAt := a.expiresAt
+		stats.ExpiresAt = &expiresAt
+	}
+	if a.lastErr != nil {
+		stats.LastError = a.lastErr.Error()
+	}
+	return stats
+}
+
+// hmacAuth подписывает запрос по выбранным заголовкам. Псевдозаголовок "(request-target)" - это
+// "метод путь?запрос"; заголовки Date и Digest (SHA-256 тела) добавляются автоматически, если подписываются.
+type hmacAuth struct {
+	keyID           string
+	secret          []byte
+	algorithm       string
+	newHash         func() hash.Hash
+	signedHeaders   []string
+	signatureHeader string
+}
+
+func newHMACAuth(options *AuthOptions) *hmacAuth {
+	algorithm := strings.ToLower(options.Algorithm)
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	newHash, _ := hmacHash(algorithm)
+
+	signedHeaders := make([]string, 0, len(options.SignedHeaders))
+	for _, header := range options.SignedHeaders {
+		signedHeaders = append(signedHeaders, strings.ToLower(strings.TrimSpace(header)))
+	}
+	if len(signedHeaders) == 0 {
+		signedHeaders = []string{"(request-target)", "date"}
+	}
+
+	signatureHeader := options.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = "Signature"
+	}
+
+	return &hmacAuth{
+		keyID:           options.KeyID,
+		secret:          []byte(options.Secret),
+		algorithm:       algorithm,
+		newHash:         newHash,
+		signedHeaders:   signedHeaders,
+		signatureHeader: signatureHeader,
+	}
+}
+
+func hmacHash(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported hmac algorithm: %s", algorithm)
+	}
+}
+
+func (a *hmacAuth) Authorize(req *AuthRequest) error {
+	lines := make([]string, 0, len(a.signedHeaders))
+	for _, name := range a.signedHeaders {
+		var value string
+		switch name {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "date":
+			if req.Header.Get("Date") == "" {
+				req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			}
+			value = req.Header.Get("Date")
+		case "digest":
+			sum := sha256.Sum256(req.Body)
+			req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+			value = req.Header.Get("Digest")
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			value = strings.Join(req.Header.Values(name), ", ")
+		}
+		lines = append(lines, name+": "+value)
+	}
+
+	mac := hmac.New(a.newHash, a.secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	req.Header.Set(a.signatureHeader, fmt.Sprintf(`keyId="%s",algorithm="hmac-%s",headers="%s",signature="%s"`,
+		a.keyID, a.algorithm, strings.Join(a.signedHeaders, " "), signature))
+	return nil
+}
+
+func (a *hmacAuth) Stats() AuthStats {
+	return AuthStats{Type: AuthTypeHMAC}
+}
+
+func truncate(value string, size int) string {
+	if len(value) > size {
+		return value[:size]
+	}
+	return value
+}
@@ -0,0 +1,304 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Причины, по которым запрос попадает в хранилище.
+const (
+	CaptureFailed = "failed"
+	CaptureSlow   = "slow"
+)
+
+const (
+	DefaultCaptureFirst    = 20
+	DefaultCaptureSample   = 100
+	DefaultCaptureBodySize = 4096
+)
+
+// Заголовки с учётными данными сохраняются без значения.
+var captureRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// CaptureOptions - сохранение проваленных и медленных запросов. По каждой причине хранятся
+// первые First запросов и случайная выборка (reservoir) из Sample остальных. Slow - порог
+// времени обслуживания для медленных успешных запросов, 0 - не сохранять. File - куда
+// записать собранное по окончании теста.
+type CaptureOptions struct {
+	Enabled  bool     `json:"enabled"`
+	First    int      `json:"first,omitempty"`
+	Sample   int      `json:"sample,omitempty"`
+	Slow     Duration `json:"slow,omitempty"`
+	BodySize int      `json:"bodySize,omitempty"`
+	File     string   `json:"file,omitempty"`
+}
+
+func (o *CaptureOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.First < 0 || o.Sample < 0 || o.BodySize < 0 || o.Slow < 0 {
+		return fmt.Errorf("capture limits must be non-negative")
+	}
+	return nil
+}
+
+// CapturedRequest - запрос и ответ (или ошибка) одного сохранённого запроса. Тела обрезаются
+// до BodySize байт, Truncated отмечает обрезанные.
+type CapturedRequest struct {
+	Time            time.Time         `json:"time"`
+	Reason          string            `json:"reason"`
+	Kept            string            `json:"kept"`
+	Endpoint        string            `json:"endpoint"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
+	RequestBody     string            `json:"requestBody,omitempty"`
+	StatusCode      int               `json:"statusCode,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"`
+	ErrorClass      string            `json:"errorClass,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	ServiceTime     float64           `json:"serviceTimeMs"`
+	ResponseTime    float64           `json:"responseTimeMs"`
+}
+
+// CaptureSnapshot - содержимое хранилища: счётчики всех провалов и медленных запросов
+// и сохранённые из них, по времени.
+type CaptureSnapshot struct {
+	Options  CaptureOptions    `json:"options"`
+	Failed   int64             `json:"failed"`
+	Slow     int64             `json:"slow"`
+	Captures []CapturedRequest `json:"captures"`
+}
+
+type captureBucket struct {
+	seen   int64
+	first  []CapturedRequest
+	sample []CapturedRequest
+}
+
+// CaptureStore - ограниченное хранилище сохранённых запросов, общее для генераторов запуска.
+type CaptureStore struct {
+	options CaptureOptions
+	buckets map[string]*captureBucket
+	mutex   sync.Mutex
+}
+
+// NewCaptureStore возвращает nil, если сохранение выключено.
+func NewCaptureStore(options *CaptureOptions) (*CaptureStore, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if !options.Enabled {
+		return nil, nil
+	}
+
+	s := &CaptureStore{
+		options: *options,
+		buckets: map[string]*captureBucket{
+			CaptureFailed: {},
+			CaptureSlow:   {},
+		},
+	}
+	if s.options.First == 0 && s.options.Sample == 0 {
+		s.options.First = DefaultCaptureFirst
+		s.options.Sample = DefaultCaptureSample
+	}
+	if s.options.BodySize == 0 {
+		s.options.BodySize = DefaultCaptureBodySize
+	}
+	return s, nil
+}
+
+func (s *CaptureStore) Describe() string {
+	description := fmt.Sprintf("first=%d, sample=%d, body=%dB", s.options.First, s.options.Sample, s.options.BodySize)
+	if s.options.Slow > 0 {
+		description += ", slow>" + time.Duration(s.options.Slow).String()
+	}
+	if s.options.File != "" {
+		description += ", file=" + s.options.File
+	}
+	return description
+}
+
+// File - файл для записи по окончании теста, пусто - не записывать.
+func (s *CaptureStore) File() string {
+	return s.options.File
+}
+
+// slow сообщает, что успешный запрос с таким временем обслуживания надо сохранить.
+func (s *CaptureStore) slow(serviceTime time.Duration) bool {
+	return s != nil && s.options.Slow > 0 && serviceTime >= time.Duration(s.options.Slow)
+}
+
+// add сохраняет запрос: первые First по причине - всегда, дальше по алгоритму R,
+// чтобы выборка была равномерной по всему тесту.
+func (s *CaptureStore) add(capture CapturedRequest) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bucket := s.buckets[capture.Reason]
+	if len(bucket.first) < s.options.First {
+		capture.Kept = "first"
+		bucket.first = append(bucket.first, capture)
+		return
+	}
+
+	bucket.seen++
+	if s.options.Sample == 0 {
+		return
+	}
+	capture.Kept = "sample"
+	if len(bucket.sample) < s.options.Sample {
+		bucket.sample = append(bucket.sample, capture)
+		return
+	}
+	if i := rand.Int63n(bucket.seen); i < int64(s.options.Sample) {
+		bucket.sample[i] = capture
+	}
+}
+
+// Snapshot возвращает сохранённые запросы; reason фильтрует по причине, пусто - все.
+func (s *CaptureStore) Snapshot(reason string) CaptureSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := CaptureSnapshot{Options: s.options, Captures: []CapturedRequest{}}
+	for name, bucket := range s.buckets {
+		total := int64(len(bucket.first)) + bucket.seen
+		if name == CaptureFailed {
+			snapshot.Failed = total
+		} else {
+			snapshot.Slow = total
+		}
+		if reason != "" && reason != name {
+			continue
+		}
+		snapshot.Captures = append(snapshot.Captures, bucket.first...)
+		snapshot.Captures = append(snapshot.Captures, bucket.sample...)
+	}
+	sort.Slice(snapshot.Captures, func(i, j int) bool {
+		return snapshot.Captures[i].Time.Before(snapshot.Captures[j].Time)
+	})
+	return snapshot
+}
+
+// WriteFile записывает все сохранённые запросы в JSON файл.
+func (s *CaptureStore) WriteFile(path string) error {
+	data, err := json.MarshalIndent(s.Snapshot(""), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// truncate обрезает тело до BodySize байт.
+func (s *CaptureStore) truncate(body []byte) (string, bool) {
+	if len(body) > s.options.BodySize {
+		return string(body[:s.options.BodySize]), true
+	}
+	return string(body), false
+}
+
+// captureHeaders сворачивает заголовки в карту, значения с учётными данными скрываются.
+func captureHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	for _, name := range captureRedactedHeaders {
+		if _, ok := headers[name]; ok {
+			headers[name] = "[redacted]"
+		}
+	}
+	return headers
+}
+
+// requestCapture накапливает данные одного запроса по мере выполнения. Методы можно
+// вызывать на nil - тогда сохранение выключено и ничего не происходит.
+type requestCapture struct {
+	store        *CaptureStore
+	endpoint     string
+	method       string
+	url          string
+	request      *http.Request
+	requestBody  string
+	response     *http.Response
+	responseBody []byte
+}
+
+func (s *CaptureStore) begin(endpoint, method, url string) *requestCapture {
+	if s == nil {
+		return nil
+	}
+	return &requestCapture{store: s, endpoint: endpoint, method: method, url: url}
+}
+
+// setRequest запоминает запрос; заголовки копируются при сохранении, чтобы попали и добавленные позже.
+func (c *requestCapture) setRequest(req *http.Request, body string) {
+	if c == nil {
+		return
+	}
+	c.method = req.Method
+	c.url = req.URL.String()
+	c.request = req
+	c.requestBody = body
+}
+
+func (c *requestCapture) setResponse(resp *http.Response, body []byte) {
+	if c == nil {
+		return
+	}
+	c.response = resp
+	c.responseBody = body
+}
+
+// finish сохраняет запрос с причиной reason; err - ошибка транспорта или проверки, если была.
+func (c *requestCapture) finish(reason, errorClass string, err error, serviceTime, responseTime time.Duration) {
+	if c == nil {
+		return
+	}
+
+	capture := CapturedRequest{
+		Time:         time.Now(),
+		Reason:       reason,
+		Endpoint:     c.endpoint,
+		Method:       c.method,
+		URL:          c.url,
+		ErrorClass:   errorClass,
+		ServiceTime:  float64(serviceTime) / float64(time.Millisecond),
+		ResponseTime: float64(responseTime) / float64(time.Millisecond),
+	}
+	if c.request != nil {
+		header := c.request.Header.Clone()
+		if c.request.Host != "" && c.request.Host != c.request.URL.Host {
+			header.Set("Host", c.request.Host)
+		}
+		capture.RequestHeaders = captureHeaders(header)
+	}
+	requestBody, requestTruncated := c.store.truncate([]byte(c.requestBody))
+	capture.RequestBody = requestBody
+	if c.response != nil {
+		capture.StatusCode = c.response.StatusCode
+		capture.ResponseHeaders = captureHeaders(c.response.Header)
+	}
+	responseBody, responseTruncated := c.store.truncate(c.responseBody)
+	capture.ResponseBody = responseBody
+	capture.Truncated = requestTruncated || responseTruncated
+	if err != nil {
+		capture.Error = err.Error()
+	}
+	c.store.add(capture)
+}
@@ -0,0 +1,424 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+const (
+	// Тело ответа читается для проверок не больше чем на maxCheckBodySize байт.
+	maxCheckBodySize = 1 << 20
+	// Сколько последних провалившихся ответов хранится для отладки.
+	checkFailureSamples  = 20
+	checkFailureBodySize = 512
+)
+
+// ResponseCheck - одна проверка ответа. В проверке задаётся ровно одно условие:
+// статус, подстрока или regex в теле, значение по JSON пути, наличие заголовка, бюджет времени ответа
+// или соответствие тела JSON схеме для кода ответа (ключи - "200", "2XX", "default").
+type ResponseCheck struct {
+	Name         string             `json:"name,omitempty"`
+	Status       []int              `json:"status,omitempty"`
+	BodyContains string             `json:"bodyContains,omitempty"`
+	BodyRegex    string             `json:"bodyRegex,omitempty"`
+	JSONPath     string             `json:"jsonPath,omitempty"`
+	Equals       interface{}        `json:"equals,omitempty"`
+	Header       string             `json:"header,omitempty"`
+	MaxLatency   Duration           `json:"maxLatency,omitempty"`
+	Schemas      map[string]*Schema `json:"schemas,omitempty"`
+	regex        *regexp.Regexp
+	path         []string
+	counters     *checkCounters
+}
+
+type CheckStats struct {
+	Name   string `json:"name"`
+	Passed int64  `json:"passed"`
+	Failed int64  `json:"failed"`
+}
+
+type CheckFailure struct {
+	Time       time.Time `json:"time"`
+	Endpoint   string    `json:"endpoint"`
+	Check      string    `json:"check"`
+	StatusCode int       `json:"statusCode"`
+	Message    string    `json:"message"`
+	Body       string    `json:"body"`
+}
+
+type checkCounters struct {
+	name   string
+	passed int64
+	failed int64
+}
+
+// checkRegistry хранит счётчики проверок по имени и последние провалы.
+type checkRegistry struct {
+	counters map[string]*checkCounters
+	order    []string
+	failures []CheckFailure
+	next     int
+	mutex    sync.Mutex
+}
+
+func newCheckRegistry() *checkRegistry {
+	return &checkRegistry{
+		counters: make(map[string]*checkCounters),
+	}
+}
+
+// compile проверяет, что условие задано ровно одно, и готовит regex и JSON путь.
+func (c *ResponseCheck) compile() error {
+	conditions := 0
+	if len(c.Status) > 0 {
+		conditions++
+	}
+	if c.BodyContains != "" {
+		conditions++
+	}
+	if c.BodyRegex != "" {
+		conditions++
+		regex, err := regexp.Compile(c.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid body regex %q: %v", c.BodyRegex, err)
+		}
+		c.regex = regex
+	}
+	if c.JSONPath != "" {
+		conditions++
+		c.path = parseJSONPath(c.JSONPath)
+	}
+	if c.Header != "" {
+		conditions++
+	}
+	if c.MaxLatency > 0 {
+		conditions++
+	}
+	if len(c.Schemas) > 0 {
+		conditions++
+		schemas := make(map[string]*Schema, len(c.Schemas))
+		for code, schema := range c.Schemas {
+			if err := schema.compile(); err != nil {
+				return fmt.Errorf("schema for %s: %v", code, err)
+			}
+			schemas[strings.ToUpper(code)] = schema
+		}
+		c.Schemas = schemas
+	}
+
+	if conditions != 1 {
+		return fmt.Errorf("check must define exactly one condition, got %d", conditions)
+	}
+
+	if c.Name == "" {
+		c.Name = c.describe()
+	}
+
+	return nil
+}
+
+func (c *ResponseCheck) describe() string {
+	switch {
+	case len(c.Status) > 0:
+		codes := make([]string, len(c.Status))
+		for i, code := range c.Status {
+			codes[i] = strconv.Itoa(code)
+		}
+		return "status in " + strings.Join(codes, ",")
+	case c.BodyContains != "":
+		return "body contains " + c.BodyContains
+	case c.BodyRegex != "":
+		return "body matches " + c.BodyRegex
+	case c.JSONPath != "" && c.Equals != nil:
+		return fmt.Sprintf("%s == %v", c.JSONPath, c.Equals)
+	case c.JSONPath != "":
+		return c.JSONPath + " exists"
+	case c.Header != "":
+		return "header " + c.Header
+	case len(c.Schemas) > 0:
+		return "response schema"
+	default:
+		return "latency <= " + time.Duration(c.MaxLatency).String()
+	}
+}
+
+func (c *ResponseCheck) needsBody() bool {
+	return c.BodyContains != "" || c.BodyRegex != "" || c.JSONPath != "" || len(c.Schemas) > 0
+}
+
+// evaluate возвращает пустую строку, если проверка прошла, иначе причину провала.
+func (c *ResponseCheck) evaluate(resp *http.Response, body []byte, parsed *jsonBody, latency time.Duration) string {
+	switch {
+	case len(c.Status) > 0:
+		for _, code := range c.Status {
+			if resp.StatusCode == code {
+				return ""
+			}
+		}
+		return fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	case c.BodyContains != "":
+		if bytes.Contains(body, []byte(c.BodyContains)) {
+			return ""
+		}
+		return "body does not contain " + strconv.Quote(c.BodyContains)
+	case c.regex != nil:
+		if c.regex.Match(body) {
+			return ""
+		}
+		return "body does not match " + c.BodyRegex
+	case c.JSONPath != "":
+		doc, err := parsed.get(body)
+		if err != nil {
+			return "body is not valid JSON"
+		}
+		value, ok := lookupJSONPath(doc, c.path)
+		if !ok {
+			return c.JSONPath + " not found"
+		}
+		if c.Equals != nil && !jsonValuesEqual(value, c.Equals) {
+			return fmt.Sprintf("%s is %v, expected %v", c.JSONPath, value, c.Equals)
+		}
+		return ""
+	case c.Header != "":
+		if resp.Header.Get(c.Header) != "" {
+			return ""
+		}
+		return "header " + c.Header + " is missing"
+	case len(c.Schemas) > 0:
+		schema, ok := schemaForStatus(c.Schemas, resp.StatusCode)
+		if !ok || schema == nil {
+			return ""
+		}
+		doc, err := parsed.get(body)
+		if err != nil {
+			return "body is not valid JSON"
+		}
+		return schema.validate(doc, "$")
+	default:
+		if latency <= time.Duration(c.MaxLatency) {
+			return ""
+		}
+		return fmt.Sprintf("latency %s exceeds %s", latency, time.Duration(c.MaxLatency))
+	}
+}
+
+// ParseCheckStatus разбирает список статусов вида "200,201,204".
+func ParseCheckStatus(value string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code: %s", part)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// ParseJSONCheck разбирает условие вида "data.status=ok"; значение после "=" читается как JSON,
+// а если это не JSON - как строка. Без "=" проверяется только наличие значения.
+func ParseJSONCheck(value string) *ResponseCheck {
+	path, expected, found := strings.Cut(value, "=")
+	check := &ResponseCheck{JSONPath: strings.TrimSpace(path)}
+	if found {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(expected), &decoded); err == nil {
+			check.Equals = decoded
+		} else {
+			check.Equals = expected
+		}
+	}
+	return check
+}
+
+// compile копирует и компилирует проверки; безымянные проверки шаблона получают префикс с его именем.
+func (r *checkRegistry) compile(checks []*ResponseCheck, prefix string) ([]*ResponseCheck, error) {
+	compiled := make([]*ResponseCheck, 0, len(checks))
+	for i, check := range checks {
+		if check == nil {
+			return nil, fmt.Errorf("check %d is empty", i)
+		}
+
+		c := *check
+		named := c.Name != ""
+		if err := c.compile(); err != nil {
+			return nil, fmt.Errorf("check %d: %v", i, err)
+		}
+		if !named && prefix != "" {
+			c.Name = prefix + ": " + c.Name
+		}
+
+		r.register(&c)
+		compiled = append(compiled, &c)
+	}
+	return compiled, nil
+}
+
+// run выполняет все проверки ответа и сохраняет образец ответа, если хотя бы одна провалилась.
+// Возвращает первую проваленную проверку, nil - все прошли.
+func (r *checkRegistry) run(name string, checks []*ResponseCheck, resp *http.Response, body []byte, latency time.Duration) error {
+	var parsed jsonBody
+	var failedCheck, failedMessage string
+
+	for _, check := range checks {
+		message := check.evaluate(resp, body, &parsed, latency)
+		r.record(check, message == "")
+		if message != "" && failedCheck == "" {
+			failedCheck = check.Name
+			failedMessage = message
+		}
+	}
+
+	if failedCheck == "" {
+		return nil
+	}
+
+	r.recordFailure(CheckFailure{
+		Time:       time.Now(),
+		Endpoint:   name,
+		Check:      failedCheck,
+		StatusCode: resp.StatusCode,
+		Message:    failedMessage,
+		Body:       string(body),
+	})
+	logger.Debug("Request %s failed check %s: %s", name, failedCheck, failedMessage)
+	return fmt.Errorf("check %s: %s", failedCheck, failedMessage)
+}
+
+func (r *checkRegistry) register(check *ResponseCheck) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	counters, ok := r.counters[check.Name]
+	if !ok {
+		counters = &checkCounters{name: check.Name}
+		r.counters[check.Name] = counters
+		r.order = append(r.order, check.Name)
+	}
+	check.counters = counters
+}
+
+func (r *checkRegistry) record(check *ResponseCheck, passed bool) {
+	result := "passed"
+	if passed {
+		atomic.AddInt64(&check.counters.passed, 1)
+	} else {
+		atomic.AddInt64(&check.counters.failed, 1)
+		result = "failed"
+	}
+	metrics.HTTPChecksCounter.WithLabelValues(check.Name, result).Inc()
+}
+
+func (r *checkRegistry) recordFailure(failure CheckFailure) {
+	if len(failure.Body) > checkFailureBodySize {
+		failure.Body = failure.Body[:checkFailureBodySize]
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.failures) < checkFailureSamples {
+		r.failures = append(r.failures, failure)
+		return
+	}
+	r.failures[r.next] = failure
+	r.next = (r.next + 1) % checkFailureSamples
+}
+
+func (r *checkRegistry) stats() ([]CheckStats, []CheckFailure) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	checks := make([]CheckStats, 0, len(r.order))
+	for _, name := range r.order {
+		counters := r.counters[name]
+		checks = append(checks, CheckStats{
+			Name:   name,
+			Passed: atomic.LoadInt64(&counters.passed),
+			Failed: atomic.LoadInt64(&counters.failed),
+		})
+	}
+
+	failures := make([]CheckFailure, 0, len(r.failures))
+	failures = append(failures, r.failures[r.next:]...)
+	failures = append(failures, r.failures[:r.next]...)
+
+	return checks, failures
+}
+
+// jsonBody разбирает тело ответа один раз на все JSON проверки запроса.
+type jsonBody struct {
+	parsed bool
+	doc    interface{}
+	err    error
+}
+
+func (j *jsonBody) get(body []byte) (interface{}, error) {
+	if !j.parsed {
+		j.parsed = true
+		j.err = json.Unmarshal(body, &j.doc)
+	}
+	return j.doc, j.err
+}
+
+// parseJSONPath разбирает путь вида "$.data.items[0].id" на ключи и индексы.
+func parseJSONPath(path string) []string {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+
+	var parts []string
+	for _, part := range strings.Split(path, ".") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func lookupJSONPath(doc interface{}, path []string) (interface{}, bool) {
+	current := doc
+	for _, part := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func jsonValuesEqual(actual, expected interface{}) bool {
+	if reflect.DeepEqual(actual, expected) {
+		return true
+	}
+	// Числа и строки сравниваются по строковому виду, чтобы "42" совпадало с 42.
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
@@ -0,0 +1,386 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+type ConnChurnGenerator struct {
+	targetAddress  string
+	targetCPS      int
+	pattern        string
+	useTLS         bool
+	holdDuration   time.Duration
+	timeout        time.Duration
+	enabled        bool
+	ctx            context.Context
+	cancel         context.CancelFunc
+	stats          *ConnChurnStats
+	connectionChan chan struct{}
+	workerCount    int
+	tlsConfig      *tls.Config
+	source         *SourceBinder
+}
+
+type ConnChurnStats struct {
+	TotalConnections      int64
+	SuccessConnections    int64
+	FailedConnections     int64
+	ActiveConnections     int64
+	TotalHandshakeTime    time.Duration
+	MinHandshakeTime      time.Duration
+	MaxHandshakeTime      time.Duration
+	TotalTLSHandshakeTime time.Duration
+	CurrentCPS            int64
+	TimeWaitSockets       int64
+	Errors                map[string]int64
+	StartTime             time.Time
+	mutex                 sync.RWMutex
+}
+
+func NewConnChurnGenerator(targetAddress string, targetCPS int, pattern string, useTLS bool, holdDuration, timeout time.Duration) *ConnChurnGenerator {
+	workerCount := 10
+	if targetCPS > 500 {
+		workerCount = targetCPS / 50
+		if workerCount > 200 {
+			workerCount = 200
+		}
+	}
+
+	host, _, err := net.SplitHostPort(targetAddress)
+	if err != nil {
+		host = targetAddress
+	}
+
+	return &ConnChurnGenerator{
+		targetAddress:  targetAddress,
+		targetCPS:      targetCPS,
+		pattern:        pattern,
+		useTLS:         useTLS,
+		holdDuration:   holdDuration,
+		timeout:        timeout,
+		enabled:        false,
+		connectionChan: make(chan struct{}, targetCPS*4),
+		workerCount:    workerCount,
+		tlsConfig: &tls.Config{
+			ServerName: host,
+		},
+		stats: &ConnChurnStats{
+			StartTime:        time.Now(),
+			MinHandshakeTime: time.Hour,
+			Errors:           make(map[string]int64),
+		},
+	}
+}
+
+// SetTLSConfig задаёт TLS настройки для handshake; без явного SNI используется хост из адреса.
+func (cg *ConnChurnGenerator) SetTLSConfig(config *tls.Config) {
+	host, _, err := net.SplitHostPort(cg.targetAddress)
+	if err != nil {
+		host = cg.targetAddress
+	}
+	cg.tlsConfig = tlsConfigForHost(config, host)
+}
+
+// SetSource задаёт локальные адреса соединений; nil - адрес выбирает ядро.
+func (cg *ConnChurnGenerator) SetSource(source *SourceBinder) {
+	cg.source = source
+}
+
+func (cg *ConnChurnGenerator) Start(ctx context.Context) {
+	if cg.enabled {
+		return
+	}
+
+	cg.enabled = true
+	cg.ctx = ctx
+
+	logger.Info("Starting connection churn generator: %s, target CPS: %d, pattern: %s, tls: %t, hold: %s",
+		cg.targetAddress, cg.targetCPS, cg.pattern, cg.useTLS, cg.holdDuration)
+
+	for i := 0; i < cg.workerCount; i++ {
+		go cg.churnWorker(i)
+	}
+
+	go cg.generateConnectionLoad()
+	go cg.statsCollector()
+}
+
+func (cg *ConnChurnGenerator) Stop() {
+	if !cg.enabled {
+		return
+	}
+
+	cg.enabled = false
+
+	logger.Info("Connection churn generator stopped")
+}
+
+func (cg *ConnChurnGenerator) generateConnectionLoad() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	lastSecond := time.Now().Unix()
+	connectionsThisSecond := 0
+
+	for {
+		select {
+		case <-cg.ctx.Done():
+			return
+		case <-ticker.C:
+			currentSecond := time.Now().Unix()
+
+			if currentSecond != lastSecond {
+				lastSecond = currentSecond
+				connectionsThisSecond = 0
+			}
+
+			currentCPS := cg.calculateCurrentCPS()
+			connectionsToCreate := cg.calculateConnectionsToCreate(currentCPS, connectionsThisSecond)
+
+			for i := 0; i < connectionsToCreate; i++ {
+				select {
+				case cg.connectionChan <- struct{}{}:
+					connectionsThisSecond++
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (cg *ConnChurnGenerator) calculateCurrentCPS() int {
+	switch cg.pattern {
+	case "constant":
+		return cg.targetCPS
+	case "spike":
+		if rand.Intn(10) == 0 {
+			return cg.targetCPS * 3
+		}
+		return cg.targetCPS
+	case "cycle":
+		elapsedSeconds := int(time.Since(cg.stats.StartTime).Seconds())
+		cyclePosition := (elapsedSeconds / 30) % 4
+
+		switch cyclePosition {
+		case 0:
+			return cg.targetCPS / 4
+		case 1:
+			return cg.targetCPS
+		case 2:
+			return cg.targetCPS / 2
+		case 3:
+			return cg.targetCPS / 8
+		}
+	case "ramp":
+		elapsedMinutes := int(time.Since(cg.stats.StartTime).Minutes())
+		rampMultiplier := float64(elapsedMinutes+1) * 0.2
+		if rampMultiplier > 1.0 {
+			rampMultiplier = 1.0
+		}
+		return int(float64(cg.targetCPS) * rampMultiplier)
+	case "random":
+		variation := rand.Intn(140) + 10
+		return (cg.targetCPS * variation) / 100
+	default:
+		return cg.targetCPS
+	}
+	return cg.targetCPS
+}
+
+func (cg *ConnChurnGenerator) calculateConnectionsToCreate(currentCPS, connectionsThisSecond int) int {
+	connectionsPer100ms := currentCPS / 10
+	if connectionsPer100ms == 0 && currentCPS > 0 && connectionsThisSecond < currentCPS {
+		connectionsPer100ms = 1
+	}
+
+	remainingCPS := currentCPS - connectionsThisSecond
+	if remainingCPS < 0 {
+		remainingCPS = 0
+	}
+
+	if connectionsPer100ms > remainingCPS {
+		connectionsPer100ms = remainingCPS
+	}
+
+	return connectionsPer100ms
+}
+
+func (cg *ConnChurnGenerator) churnWorker(workerID int) {
+	logger.Debug("Churn worker %d started", workerID)
+	defer logger.Debug("Churn worker %d stopped", workerID)
+
+	for {
+		select {
+		case <-cg.ctx.Done():
+			return
+		case <-cg.connectionChan:
+			cg.openConnection()
+		}
+	}
+}
+
+func (cg *ConnChurnGenerator) openConnection() {
+	dial := cg.source.dialer(&net.Dialer{Timeout: cg.timeout})
+
+	startTime := time.Now()
+	conn, err := dial(cg.ctx, "tcp", cg.targetAddress)
+	handshakeTime := time.Since(startTime)
+
+	if err != nil {
+		cg.recordFailure(err)
+		logger.Debug("Churn connection failed: %v", err)
+		return
+	}
+
+	var tlsHandshakeTime time.Duration
+	if cg.useTLS {
+		tlsConn := tls.Client(conn, cg.tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(cg.timeout))
+
+		tlsStart := time.Now()
+		err = tlsConn.HandshakeContext(cg.ctx)
+		tlsHandshakeTime = time.Since(tlsStart)
+
+		if err != nil {
+			conn.Close()
+			cg.recordFailure(err)
+			logger.Debug("Churn TLS handshake failed: %v", err)
+			return
+		}
+
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	cg.recordSuccess(handshakeTime, tlsHandshakeTime)
+
+	if cg.holdDuration <= 0 {
+		conn.Close()
+		return
+	}
+
+	atomic.AddInt64(&cg.stats.ActiveConnections, 1)
+	go cg.holdConnection(conn)
+}
+
+func (cg *ConnChurnGenerator) holdConnection(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		atomic.AddInt64(&cg.stats.ActiveConnections, -1)
+	}()
+
+	timer := time.NewTimer(cg.holdDuration)
+	defer timer.Stop()
+
+	select {
+	case <-cg.ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func (cg *ConnChurnGenerator) recordSuccess(handshakeTime, tlsHandshakeTime time.Duration) {
+	atomic.AddInt64(&cg.stats.TotalConnections, 1)
+	atomic.AddInt64(&cg.stats.SuccessConnections, 1)
+
+	cg.stats.mutex.Lock()
+	cg.stats.TotalHandshakeTime += handshakeTime
+	cg.stats.TotalTLSHandshakeTime += tlsHandshakeTime
+	if handshakeTime < cg.stats.MinHandshakeTime {
+		cg.stats.MinHandshakeTime = handshakeTime
+	}
+	if handshakeTime > cg.stats.MaxHandshakeTime {
+		cg.stats.MaxHandshakeTime = handshakeTime
+	}
+	cg.stats.mutex.Unlock()
+
+	metrics.ChurnConnectionsCounter.Inc()
+	metrics.ChurnHandshakeTimeHistogram.Observe(handshakeTime.Seconds())
+	if cg.useTLS {
+		metrics.ChurnTLSHandshakeTimeHistogram.Observe(tlsHandshakeTime.Seconds())
+	}
+}
+
+func (cg *ConnChurnGenerator) recordFailure(err error) {
+	atomic.AddInt64(&cg.stats.TotalConnections, 1)
+	atomic.AddInt64(&cg.stats.FailedConnections, 1)
+
+	errorClass := classifyDialError(err)
+
+	cg.stats.mutex.Lock()
+	cg.stats.Errors[errorClass]++
+	cg.stats.mutex.Unlock()
+
+	metrics.ChurnConnectionsCounter.Inc()
+	metrics.ChurnErrorsCounter.WithLabelValues(errorClass).Inc()
+}
+
+func (cg *ConnChurnGenerator) statsCollector() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	lastConnections := int64(0)
+
+	for {
+		select {
+		case <-cg.ctx.Done():
+			return
+		case <-ticker.C:
+			currentConnections := atomic.LoadInt64(&cg.stats.TotalConnections)
+			currentCPS := currentConnections - lastConnections
+			atomic.StoreInt64(&cg.stats.CurrentCPS, currentCPS)
+			lastConnections = currentConnections
+
+			atomic.StoreInt64(&cg.stats.TimeWaitSockets, countTimeWaitSockets())
+		}
+	}
+}
+
+func (cg *ConnChurnGenerator) GetStats() *ConnChurnStats {
+	cg.stats.mutex.RLock()
+	defer cg.stats.mutex.RUnlock()
+
+	errorCounts := make(map[string]int64)
+	for class, count := range cg.stats.Errors {
+		errorCounts[class] = count
+	}
+
+	return &ConnChurnStats{
+		TotalConnections:      atomic.LoadInt64(&cg.stats.TotalConnections),
+		SuccessConnections:    atomic.LoadInt64(&cg.stats.SuccessConnections),
+		FailedConnections:     atomic.LoadInt64(&cg.stats.FailedConnections),
+		ActiveConnections:     atomic.LoadInt64(&cg.stats.ActiveConnections),
+		TotalHandshakeTime:    cg.stats.TotalHandshakeTime,
+		MinHandshakeTime:      cg.stats.MinHandshakeTime,
+		MaxHandshakeTime:      cg.stats.MaxHandshakeTime,
+		TotalTLSHandshakeTime: cg.stats.TotalTLSHandshakeTime,
+		CurrentCPS:            atomic.LoadInt64(&cg.stats.CurrentCPS),
+		TimeWaitSockets:       atomic.LoadInt64(&cg.stats.TimeWaitSockets),
+		Errors:                errorCounts,
+		StartTime:             cg.stats.StartTime,
+	}
+}
+
+func (cg *ConnChurnGenerator) GetAverageHandshakeTime() time.Duration {
+	stats := cg.GetStats()
+	if stats.SuccessConnections == 0 {
+		return 0
+	}
+	return stats.TotalHandshakeTime / time.Duration(stats.SuccessConnections)
+}
+
+func (cg *ConnChurnGenerator) GetSuccessRate() float64 {
+	stats := cg.GetStats()
+	if stats.TotalConnections == 0 {
+		return 0
+	}
+	return float64(stats.SuccessConnections) / float64(stats.TotalConnections) * 100.0
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"stresspulse/logger"
+)
+
+const (
+	DataModeSequential = "sequential"
+	DataModeRandom     = "random"
+	DataModeUnique     = "unique"
+)
+
+// ErrDataExhausted возвращается в режиме unique, когда все строки файла уже выданы.
+var ErrDataExhausted = errors.New("data feeder exhausted")
+
+// DataFeeder выдаёт строки из CSV или JSON файла для подстановки в шаблоны запросов.
+type DataFeeder struct {
+	rows      []map[string]string
+	mode      string
+	next      int
+	exhausted bool
+	mutex     sync.Mutex
+}
+
+// LoadDataFeeder читает CSV (первая строка - имена колонок) или JSON массив объектов.
+func LoadDataFeeder(path, mode string) (*DataFeeder, error) {
+	switch mode {
+	case "":
+		mode = DataModeSequential
+	case DataModeSequential, DataModeRandom, DataModeUnique:
+	default:
+		return nil, fmt.Errorf("invalid data mode: %s", mode)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %v", err)
+	}
+
+	var rows []map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		rows, err = parseJSONRows(data)
+	} else {
+		rows, err = parseCSVRows(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data file %s: %v", path, err)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("data file %s contains no rows", path)
+	}
+
+	return NewDataFeeder(rows, mode), nil
+}
+
+func NewDataFeeder(rows []map[string]string, mode string) *DataFeeder {
+	return &DataFeeder{
+		rows: rows,
+		mode: mode,
+	}
+}
+
+func parseCSVRows(data string) ([]map[string]string, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseJSONRows(data []byte) ([]map[string]string, error) {
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]string, len(item))
+		for key, value := range item {
+			switch v := value.(type) {
+			case string:
+				row[key] = v
+			case nil:
+				row[key] = ""
+			default:
+				encoded, _ := json.Marshal(v)
+				row[key] = string(encoded)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Next возвращает следующую строку: по кругу, случайную или каждую ровно один раз.
+func (df *DataFeeder) Next() (map[string]string, error) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	switch df.mode {
+	case DataModeRandom:
+		return df.rows[rand.Intn(len(df.rows))], nil
+	case DataModeUnique:
+		if df.next >= len(df.rows) {
+			if !df.exhausted {
+				df.exhausted = true
+				logger.Warning("All %d data rows have been used, further templated requests will fail", len(df.rows))
+			}
+			return nil, ErrDataExhausted
+		}
+	default:
+		if df.next >= len(df.rows) {
+			df.next = 0
+		}
+	}
+
+	row := df.rows[df.next]
+	df.next++
+	return row, nil
+}
+
+func (df *DataFeeder) Len() int {
+	return len(df.rows)
+}
@@ -0,0 +1,152 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+const (
+	ErrorClassDNS            = "dns"
+	ErrorClassConnect        = "connect"
+	ErrorClassPortExhaustion = "port_exhaustion"
+	ErrorClassTLS            = "tls"
+	ErrorClassTimeout        = "timeout"
+	ErrorClassReset          = "reset"
+	ErrorClassRead           = "read"
+	ErrorClassCanceled       = "canceled"
+	ErrorClassRequest        = "request"
+	ErrorClassData           = "data"
+	ErrorClassCheck          = "check"
+	ErrorClassExtract        = "extract"
+	ErrorClassAuth           = "auth"
+	ErrorClassGraphQL        = "graphql"
+	ErrorClassOther          = "other"
+)
+
+// classifyDialError сводит ошибку установки соединения к короткому имени errno,
+// чтобы по нему можно было строить счётчики и метки метрик.
+func classifyDialError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var exhaustion *PortExhaustionError
+	if errors.As(err, &exhaustion) {
+		return ErrorClassPortExhaustion
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "econnrefused"
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return "eaddrnotavail"
+	case errors.Is(err, syscall.EADDRINUSE):
+		return "eaddrinuse"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "econnreset"
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return "ehostunreach"
+	case errors.Is(err, syscall.ENETUNREACH):
+		return "enetunreach"
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE):
+		return "emfile"
+	case errors.Is(err, syscall.ETIMEDOUT), isTimeoutError(err):
+		return ErrorClassTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorClassDNS
+	}
+
+	if isTLSError(err) {
+		return ErrorClassTLS
+	}
+
+	return ErrorClassOther
+}
+
+// classifyHTTPError определяет класс ошибки HTTP клиента: dns, connect, tls, timeout, reset и т.д.
+func classifyHTTPError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorClassDNS
+	}
+
+	if isTLSError(err) {
+		return ErrorClassTLS
+	}
+
+	if isTimeoutError(err) {
+		return ErrorClassTimeout
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorClassReset
+	}
+
+	if isPortExhaustion(err) {
+		return ErrorClassPortExhaustion
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrorClassConnect
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return ErrorClassConnect
+	}
+
+	return ErrorClassOther
+}
+
+// classifyHTTPStatus возвращает класс ошибки для неуспешного HTTP статуса.
+func classifyHTTPStatus(statusCode int) string {
+	return fmt.Sprintf("http_%dxx", statusCode/100)
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var opErr *net.OpError
+
+	// Алерт от сервера (например, отказ в mTLS без клиентского сертификата) приходит как net.OpError "remote error".
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		return true
+	}
+
+	return errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &certErr) ||
+		errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
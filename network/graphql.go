@@ -0,0 +1,274 @@
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"stresspulse/metrics"
+)
+
+// Ответ сервера, которому неизвестен хеш persisted query (Apollo APQ).
+const (
+	graphQLPersistedQueryNotFound     = "PersistedQueryNotFound"
+	graphQLPersistedQueryNotFoundCode = "PERSISTED_QUERY_NOT_FOUND"
+	graphQLUnknownErrorCode           = "UNKNOWN"
+)
+
+// GraphQLMix - файл операций для -graphql: взвешенный набор именованных запросов и мутаций.
+// APQ включает automatic persisted queries: сначала отправляется только sha256 хеш запроса,
+// полный текст - только когда сервер ответил PersistedQueryNotFound.
+type GraphQLMix struct {
+	APQ        bool                `json:"apq,omitempty"`
+	Operations []*GraphQLOperation `json:"operations"`
+}
+
+// GraphQLOperation - одна операция. Variables - JSON объект, строки которого могут быть шаблонами
+// ({"id": "{{.Data.user_id}}"}), или строка-шаблон всего объекта, если нужны не строковые
+// значения ("{\"limit\": {{randInt 1 50}}}"). Name - имя операции в статистике и operationName.
+type GraphQLOperation struct {
+	Name      string            `json:"name"`
+	Query     string            `json:"query"`
+	Variables json.RawMessage   `json:"variables,omitempty"`
+	Weight    int               `json:"weight,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timeout   Duration          `json:"timeout,omitempty"`
+	Checks    []*ResponseCheck  `json:"checks,omitempty"`
+}
+
+// GraphQLStats - ошибки GraphQL уровня и работа APQ по одной операции.
+type GraphQLStats struct {
+	ErrorResponses int64            `json:"errorResponses"`
+	ErrorCodes     map[string]int64 `json:"errorCodes"`
+	APQHits        int64            `json:"apqHits"`
+	APQMisses      int64            `json:"apqMisses"`
+}
+
+// graphQLOperation - состояние операции у endpoint HTTP генератора. Тело endpoint при APQ
+// содержит только хеш, fullBody - тело с текстом запроса для регистрации.
+type graphQLOperation struct {
+	name       string
+	apq        bool
+	fullBody   *TextTemplate
+	errors     int64
+	apqHits    int64
+	apqMisses  int64
+	errorCodes map[string]int64
+	mutex      sync.Mutex
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func LoadGraphQLOperations(path string) (*GraphQLMix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GraphQL operations file: %v", err)
+	}
+
+	var mix GraphQLMix
+	if err := json.Unmarshal(data, &mix); err != nil {
+		return nil, fmt.Errorf("failed to parse GraphQL operations file: %v", err)
+	}
+
+	if len(mix.Operations) == 0 {
+		return nil, fmt.Errorf("GraphQL operations file %s contains no operations", path)
+	}
+
+	return &mix, nil
+}
+
+// SetGraphQLOperations задаёт набор GraphQL операций вместо одного URL: каждая операция
+// отправляется POST запросом с JSON телом на URL генератора (или свой URL операции).
+func (hg *HTTPGenerator) SetGraphQLOperations(mix *GraphQLMix) error {
+	if mix == nil || len(mix.Operations) == 0 {
+		return fmt.Errorf("GraphQL operations list is empty")
+	}
+
+	templates := make([]*RequestTemplate, 0, len(mix.Operations))
+	operations := make([]*graphQLOperation, 0, len(mix.Operations))
+	for i, op := range mix.Operations {
+		if op == nil || strings.TrimSpace(op.Query) == "" {
+			return fmt.Errorf("GraphQL operation %d has no query", i)
+		}
+		if op.Name == "" {
+			return fmt.Errorf("GraphQL operation %d has no name", i)
+		}
+
+		variables, err := graphQLVariablesTemplate(op.Variables)
+		if err != nil {
+			return fmt.Errorf("GraphQL operation %s: %v", op.Name, err)
+		}
+
+		fullBody := graphQLBody(op, variables, true, mix.APQ)
+		compiled, err := hg.templates.Compile("body", fullBody)
+		if err != nil {
+			return fmt.Errorf("GraphQL operation %s: %v", op.Name, err)
+		}
+
+		headers := map[string]string{"Content-Type": "application/json"}
+		for key, value := range op.Headers {
+			headers[key] = value
+		}
+
+		templates = append(templates, &RequestTemplate{
+			Name:    op.Name,
+			Method:  "POST",
+			URL:     op.URL,
+			Headers: headers,
+			Body:    graphQLBody(op, variables, !mix.APQ, mix.APQ),
+			Weight:  op.Weight,
+			Timeout: op.Timeout,
+			Checks:  op.Checks,
+		})
+		operations = append(operations, &graphQLOperation{
+			name:       op.Name,
+			apq:        mix.APQ,
+			fullBody:   compiled,
+			errorCodes: make(map[string]int64),
+		})
+	}
+
+	if err := hg.SetRequestTemplates(templates); err != nil {
+		return err
+	}
+	for i, endpoint := range hg.endpoints {
+		endpoint.graphql = operations[i]
+	}
+	return nil
+}
+
+// graphQLVariablesTemplate возвращает текст шаблона переменных: объект как есть, строку - её содержимое.
+func graphQLVariablesTemplate(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "{}", nil
+	}
+	switch raw[0] {
+	case '{':
+		return string(raw), nil
+	case '"':
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return "", err
+		}
+		return text, nil
+	}
+	return "", fmt.Errorf("variables must be a JSON object or a template string")
+}
+
+// graphQLBody собирает шаблон JSON тела. Текст запроса экранируется, чтобы {{ в нём не
+// считалось шаблоном; шаблонами остаются только переменные.
+func graphQLBody(op *GraphQLOperation, variables string, withQuery, apq bool) string {
+	name, _ := json.Marshal(op.Name)
+	body := `{"operationName":` + templateLiteral(string(name)) + `,"variables":` + variables
+	if withQuery {
+		query, _ := json.Marshal(op.Query)
+		body += `,"query":` + templateLiteral(string(query))
+	}
+	if apq {
+		body += `,"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + graphQLQueryHash(op.Query) + `"}}`
+	}
+	return body + "}"
+}
+
+func graphQLQueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// templateLiteral экранирует {{ так, чтобы text/template вывел текст без изменений.
+func templateLiteral(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// needsRegistration сообщает, что сервер не знает хеш и запрос надо повторить с полным текстом.
+// Учитывает попадание или промах APQ для ответа на запрос с одним хешем.
+func (g *graphQLOperation) needsRegistration(body []byte) bool {
+	var response graphQLResponse
+	notFound := false
+	if json.Unmarshal(body, &response) == nil {
+		for _, graphQLError := range response.Errors {
+			if graphQLError.Message == graphQLPersistedQueryNotFound || graphQLError.Extensions.Code == graphQLPersistedQueryNotFoundCode {
+				notFound = true
+				break
+			}
+		}
+	}
+
+	result := "hit"
+	if notFound {
+		result = "miss"
+		atomic.AddInt64(&g.apqMisses, 1)
+	} else {
+		atomic.AddInt64(&g.apqHits, 1)
+	}
+	metrics.HTTPGraphQLPersistedQueriesCounter.WithLabelValues(g.name, result).Inc()
+	return notFound
+}
+
+// check ищет ошибки GraphQL уровня: сервер отвечает 200, а ошибки кладёт в errors[].
+// Обрезанное тело, которое не разбирается как JSON, не проверяется.
+func (g *graphQLOperation) check(body []byte) error {
+	var response graphQLResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		if len(body) >= maxCheckBodySize {
+			return nil
+		}
+		return fmt.Errorf("invalid GraphQL response: %v", err)
+	}
+	if len(response.Errors) == 0 {
+		if len(response.Data) == 0 || string(response.Data) == "null" {
+			return fmt.Errorf("GraphQL response has neither data nor errors")
+		}
+		return nil
+	}
+
+	atomic.AddInt64(&g.errors, 1)
+	g.mutex.Lock()
+	for _, graphQLError := range response.Errors {
+		code := graphQLError.Extensions.Code
+		if code == "" {
+			code = graphQLUnknownErrorCode
+		}
+		g.errorCodes[code]++
+		metrics.HTTPGraphQLErrorsCounter.WithLabelValues(g.name, code).Inc()
+	}
+	g.mutex.Unlock()
+
+	message := response.Errors[0].Message
+	if len(response.Errors) > 1 {
+		message = fmt.Sprintf("%s (and %d more)", message, len(response.Errors)-1)
+	}
+	return fmt.Errorf("GraphQL error: %s", message)
+}
+
+func (g *graphQLOperation) snapshot() *GraphQLStats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	stats := &GraphQLStats{
+		ErrorResponses: atomic.LoadInt64(&g.errors),
+		ErrorCodes:     make(map[string]int64, len(g.errorCodes)),
+		APQHits:        atomic.LoadInt64(&g.apqHits),
+		APQMisses:      atomic.LoadInt64(&g.apqMisses),
+	}
+	for code, count := range g.errorCodes {
+		stats.ErrorCodes[code] = count
+	}
+	return stats
+}
@@ -3,7 +3,12 @@ package network
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,8 +19,10 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"stresspulse/logger"
+	"stresspulse/metrics"
 )
 
 type GRPCGenerator struct {
@@ -25,15 +32,22 @@ type GRPCGenerator struct {
 	serviceName     string
 	methodType      string
 	useSecure       bool
+	tlsConfig       *tls.Config
+	resolver        *Resolver
+	source          *SourceBinder
+	auth            AuthProvider
 	enabled         bool
 	ctx             context.Context
 	cancel          context.CancelFunc
 	stats           *GRPCStats
-	requestChan     chan struct{}
-	workerCount     int
+	workers         *workerPool
 	connPool        []*grpc.ClientConn
 	poolSize        int
 	metadata        map[string]string
+	templates       *TemplateEngine
+	metadataTemplates map[string]*TextTemplate
+	settingsMutex   sync.RWMutex
+	patternStart    time.Time
 }
 
 type GRPCStats struct {
@@ -44,8 +58,16 @@ type GRPCStats struct {
 	MinResponseTime   time.Duration
 	MaxResponseTime   time.Duration
 	CurrentRPS        int64
+	DroppedRequests   int64
+	LateRequests      int64
+	Workers           WorkerPoolStats
 	StartTime         time.Time
 	StatusCodes       map[codes.Code]int64
+	Addresses         map[string]int64
+	Latency           LatencyPercentiles
+	ServiceTime       LatencyPercentiles
+	latency           *LatencyHistogram
+	serviceTime       *LatencyHistogram
 	mutex             sync.RWMutex
 }
 
@@ -68,7 +90,7 @@ func NewGRPCGenerator(targetAddress string, targetRPS int, pattern, serviceName,
 		}
 	}
 
-	return &GRPCGenerator{
+	gg := &GRPCGenerator{
 		targetAddress: targetAddress,
 		targetRPS:     targetRPS,
 		pattern:       pattern,
@@ -76,28 +98,139 @@ func NewGRPCGenerator(targetAddress string, targetRPS int, pattern, serviceName,
 		methodType:    methodType,
 		useSecure:     useSecure,
 		enabled:       false,
-		requestChan:   make(chan struct{}, targetRPS*4),
-		workerCount:   workerCount,
 		poolSize:      poolSize,
 		connPool:      make([]*grpc.ClientConn, 0),
 		metadata:      make(map[string]string),
+		templates:     NewTemplateEngine(nil),
 		stats: &GRPCStats{
 			StartTime:       time.Now(),
 			MinResponseTime: time.Hour,
 			StatusCodes:     make(map[codes.Code]int64),
+			Addresses:       make(map[string]int64),
+			latency:         NewLatencyHistogram(),
+			serviceTime:     NewLatencyHistogram(),
 		},
 	}
+	gg.patternStart = gg.stats.StartTime
+	gg.workers = newWorkerPool("gRPC", "-grpc-max-concurrency", targetRPS*4, workerCount, DefaultGRPCMaxConcurrency, gg.makeRequest)
+	return gg
+}
+
+// SetMaxConcurrency задаёт предел одновременных вызовов; пул воркеров растёт до него по мере нужды.
+func (gg *GRPCGenerator) SetMaxConcurrency(maxConcurrency int) {
+	gg.workers.setMaxWorkers(maxConcurrency)
+}
+
+// SetTargetRPS меняет целевой RPS на ходу; статистика и соединения сохраняются.
+func (gg *GRPCGenerator) SetTargetRPS(rps int) {
+	gg.settingsMutex.Lock()
+	gg.targetRPS = rps
+	gg.settingsMutex.Unlock()
+}
+
+// SetPattern меняет паттерн нагрузки на ходу; cycle и ramp отсчитываются заново с момента смены.
+func (gg *GRPCGenerator) SetPattern(pattern string) {
+	gg.settingsMutex.Lock()
+	gg.pattern = pattern
+	gg.patternStart = time.Now()
+	gg.settingsMutex.Unlock()
+}
+
+func (gg *GRPCGenerator) TargetRPS() int {
+	targetRPS, _, _ := gg.target()
+	return targetRPS
+}
+
+func (gg *GRPCGenerator) target() (int, string, time.Time) {
+	gg.settingsMutex.RLock()
+	defer gg.settingsMutex.RUnlock()
+	return gg.targetRPS, gg.pattern, gg.patternStart
 }
 
 func (gg *GRPCGenerator) SetMetadata(metadata map[string]string) {
 	gg.metadata = metadata
 }
 
+func (gg *GRPCGenerator) SetDataFeeder(feeder *DataFeeder) {
+	gg.templates.feeder = feeder
+}
+
+// SetTLSConfig задаёт TLS настройки для защищённого соединения (-grpc-secure).
+func (gg *GRPCGenerator) SetTLSConfig(config *tls.Config) {
+	gg.tlsConfig = config
+}
+
+// SetResolver задаёт разрешение имён и раскладку соединений пула по IP; nil - как решит gRPC.
+func (gg *GRPCGenerator) SetResolver(resolver *Resolver) {
+	gg.resolver = resolver
+}
+
+// SetSource задаёт локальные адреса соединений пула; nil - адрес выбирает ядро.
+func (gg *GRPCGenerator) SetSource(source *SourceBinder) {
+	gg.source = source
+}
+
+// SetAuth задаёт провайдер аутентификации; его заголовки передаются как метаданные запроса.
+func (gg *GRPCGenerator) SetAuth(auth AuthProvider) {
+	gg.auth = auth
+}
+
+// authorizeMetadata добавляет к метаданным заголовки аутентификации. Путь для подписи - полное имя метода.
+func (gg *GRPCGenerator) authorizeMetadata(md metadata.MD) error {
+	path := "/grpc.health.v1.Health/Check"
+	if gg.methodType == "server_stream" || gg.methodType == "bidi_stream" {
+		path = "/grpc.health.v1.Health/Watch"
+	}
+
+	header := http.Header{}
+	if err := gg.auth.Authorize(&AuthRequest{Method: http.MethodPost, URL: &url.URL{Path: path}, Header: header}); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	for key, values := range header {
+		md.Set(strings.ToLower(key), values...)
+	}
+	return nil
+}
+
+// renderMetadata подставляет данные запроса в значения метаданных.
+func (gg *GRPCGenerator) renderMetadata() (metadata.MD, error) {
+	var data *TemplateData
+	for _, value := range gg.metadataTemplates {
+		if !value.IsStatic() {
+			var err error
+			data, err = gg.templates.NewData(nil)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	
+	md := metadata.MD{}
+	for key, value := range gg.metadataTemplates {
+		rendered, err := value.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		md.Set(key, rendered)
+	}
+	return md, nil
+}
+
 func (gg *GRPCGenerator) Start(ctx context.Context) error {
 	if gg.enabled {
 		return nil
 	}
 
+	gg.metadataTemplates = make(map[string]*TextTemplate)
+	for key, value := range gg.metadata {
+		compiled, err := gg.templates.Compile(key, value)
+		if err != nil {
+			return err
+		}
+		gg.metadataTemplates[key] = compiled
+	}
+	
 	gg.enabled = true
 	gg.ctx = ctx
 	
@@ -108,9 +241,7 @@ func (gg *GRPCGenerator) Start(ctx context.Context) error {
 	logger.Info("Starting gRPC load generator: %s, target RPS: %d, pattern: %s", 
 		gg.targetAddress, gg.targetRPS, gg.pattern)
 	
-	for i := 0; i < gg.workerCount; i++ {
-		go gg.grpcWorker(i)
-	}
+	gg.workers.start(ctx)
 	
 	go gg.generateRPSLoad()
 	go gg.statsCollector()
@@ -125,10 +256,10 @@ func (gg *GRPCGenerator) Stop() {
 
 	gg.enabled = false
 	
+	// Пул не обнуляется: воркеры, которые ещё не вышли, получат Canceled от закрытого соединения.
 	for _, conn := range gg.connPool {
 		conn.Close()
 	}
-	gg.connPool = nil
 	
 	logger.Info("gRPC load generator stopped")
 }
@@ -136,33 +267,60 @@ func (gg *GRPCGenerator) Stop() {
 func (gg *GRPCGenerator) createConnectionPool() error {
 	var creds credentials.TransportCredentials
 	if gg.useSecure {
-		creds = credentials.NewTLS(&tls.Config{
-			InsecureSkipVerify: true,
-		})
+		tlsConfig := gg.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		creds = credentials.NewTLS(tlsConfig.Clone())
 	} else {
 		creds = insecure.NewCredentials()
 	}
 	
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.WithTimeout(30*time.Second),
+		// Без этого по таймауту остаётся только "context deadline exceeded" без причины (например, непроверенного сертификата).
+		grpc.WithReturnConnectionError(),
+		grpc.WithUnaryInterceptor(gg.unaryAddressInterceptor),
+		grpc.WithStreamInterceptor(gg.streamAddressInterceptor),
+	}
+	// unix:///path gRPC набирает сам; резолвер и локальные адреса для сокета не нужны.
+	if (gg.resolver != nil || gg.source != nil) && !IsUnixURL(gg.targetAddress) {
+		// Каждое соединение пула разрешает имя и берёт локальный адрес заново, так что пул раскладывается по IP.
+		dial := gg.source.dialer(&net.Dialer{Timeout: 30 * time.Second})
+		if gg.resolver != nil {
+			dial = gg.resolver.dialer(dial)
+		}
+		options = append(options, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, "tcp", addr)
+		}))
+	}
+	
+	var pool []*grpc.ClientConn
 	for i := 0; i < gg.poolSize; i++ {
-		conn, err := grpc.DialContext(gg.ctx, gg.targetAddress, 
-			grpc.WithTransportCredentials(creds),
-			grpc.WithBlock(),
-			grpc.WithTimeout(30*time.Second),
-		)
+		conn, err := grpc.DialContext(gg.ctx, gg.targetAddress, options...)
 		if err != nil {
-			for _, existingConn := range gg.connPool {
+			for _, existingConn := range pool {
 				existingConn.Close()
 			}
+			// gRPC оборачивает ошибку через %v, так что тип x509 уже не достать - смотрим на текст.
+			if gg.useSecure && strings.Contains(err.Error(), "x509:") {
+				return fmt.Errorf("%v (server certificate is verified; use -tls-ca or -tls-insecure for self-signed servers)", err)
+			}
 			return err
 		}
-		gg.connPool = append(gg.connPool, conn)
+		pool = append(pool, conn)
 	}
+	gg.connPool = pool
 	
 	return nil
 }
 
 func (gg *GRPCGenerator) generateRPSLoad() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+	targetRPS, _, _ := gg.target()
+	tickInterval := 100 * time.Millisecond
+	ticker := time.NewTicker(tickInterval)
 	defer ticker.Stop()
 
 	lastSecond := time.Now().Unix()
@@ -172,7 +330,7 @@ func (gg *GRPCGenerator) generateRPSLoad() {
 		select {
 		case <-gg.ctx.Done():
 			return
-		case <-ticker.C:
+		case tickTime := <-ticker.C:
 			currentSecond := time.Now().Unix()
 			
 			if currentSecond != lastSecond {
@@ -180,14 +338,21 @@ func (gg *GRPCGenerator) generateRPSLoad() {
 				requestsThisSecond = 0
 			}
 			
+			// Цель могла измениться через SetTargetRPS: очередь растёт под новый темп.
+			if newTargetRPS, _, _ := gg.target(); newTargetRPS != targetRPS {
+				targetRPS = newTargetRPS
+				gg.workers.resize(targetRPS * 4)
+			}
+			
 			currentRPS := gg.calculateCurrentRPS()
 			requestsToSend := gg.calculateRequestsToSend(currentRPS, requestsThisSecond)
 			
 			for i := 0; i < requestsToSend; i++ {
-				select {
-				case gg.requestChan <- struct{}{}:
+				if gg.workers.submit(scheduledTime(tickTime, tickInterval, i, requestsToSend)) {
 					requestsThisSecond++
-				default:
+				} else {
+					atomic.AddInt64(&gg.stats.DroppedRequests, 1)
+					metrics.GRPCDroppedRequestsCounter.Inc()
 				}
 			}
 		}
@@ -195,42 +360,43 @@ func (gg *GRPCGenerator) generateRPSLoad() {
 }
 
 func (gg *GRPCGenerator) calculateCurrentRPS() int {
-	switch gg.pattern {
+	targetRPS, pattern, patternStart := gg.target()
+	switch pattern {
 	case "constant":
-		return gg.targetRPS
+		return targetRPS
 	case "spike":
 		if rand.Intn(10) == 0 {
-			return gg.targetRPS * 3
+			return targetRPS * 3
 		}
-		return gg.targetRPS
+		return targetRPS
 	case "cycle":
-		elapsedSeconds := int(time.Since(gg.stats.StartTime).Seconds())
+		elapsedSeconds := int(time.Since(patternStart).Seconds())
 		cyclePosition := (elapsedSeconds / 30) % 4
 		
 		switch cyclePosition {
 		case 0:
-			return gg.targetRPS / 4
+			return targetRPS / 4
 		case 1:
-			return gg.targetRPS
+			return targetRPS
 		case 2:
-			return gg.targetRPS / 2
+			return targetRPS / 2
 		case 3:
-			return gg.targetRPS / 8
+			return targetRPS / 8
 		}
 	case "ramp":
-		elapsedMinutes := int(time.Since(gg.stats.StartTime).Minutes())
+		elapsedMinutes := int(time.Since(patternStart).Minutes())
 		rampMultiplier := float64(elapsedMinutes+1) * 0.2
 		if rampMultiplier > 1.0 {
 			rampMultiplier = 1.0
 		}
-		return int(float64(gg.targetRPS) * rampMultiplier)
+		return int(float64(targetRPS) * rampMultiplier)
 	case "random":
 		variation := rand.Intn(140) + 10
-		return (gg.targetRPS * variation) / 100
+		return (targetRPS * variation) / 100
 	default:
-		return gg.targetRPS
+		return targetRPS
 	}
-	return gg.targetRPS
+	return targetRPS
 }
 
 func (gg *GRPCGenerator) calculateRequestsToSend(currentRPS, requestsThisSecond int) int {
@@ -248,28 +414,31 @@ func (gg *GRPCGenerator) calculateRequestsToSend(currentRPS, requestsThisSecond
 	return requestsPer100ms
 }
 
-func (gg *GRPCGenerator) grpcWorker(workerID int) {
-	logger.Debug("gRPC worker %d started", workerID)
-	defer logger.Debug("gRPC worker %d stopped", workerID)
-
-	for {
-		select {
-		case <-gg.ctx.Done():
-			return
-		case <-gg.requestChan:
-			gg.makeRequest(workerID)
-		}
+func (gg *GRPCGenerator) makeRequest(workerID int, intendedTime time.Time) {
+	if lag := waitForSchedule(gg.ctx, intendedTime); lag >= lateRequestThreshold {
+		atomic.AddInt64(&gg.stats.LateRequests, 1)
+		metrics.GRPCLateRequestsCounter.Inc()
 	}
-}
-
-func (gg *GRPCGenerator) makeRequest(workerID int) {
+	
 	startTime := time.Now()
 	
 	conn := gg.connPool[workerID%len(gg.connPool)]
 	
 	ctx := gg.ctx
-	if len(gg.metadata) > 0 {
-		md := metadata.New(gg.metadata)
+	if len(gg.metadataTemplates) > 0 || gg.auth != nil {
+		md, err := gg.renderMetadata()
+		if err != nil {
+			gg.recordFailure(time.Since(startTime), time.Since(intendedTime), err)
+			logger.Debug("Failed to render gRPC metadata: %v", err)
+			return
+		}
+		if gg.auth != nil {
+			if err := gg.authorizeMetadata(md); err != nil {
+				gg.recordFailure(time.Since(startTime), time.Since(intendedTime), err)
+				logger.Debug("Failed to authorize gRPC request: %v", err)
+				return
+			}
+		}
 		ctx = metadata.NewOutgoingContext(ctx, md)
 	}
 	
@@ -293,13 +462,14 @@ func (gg *GRPCGenerator) makeRequest(workerID int) {
 		err = gg.makeHealthCheckRequest(ctx, conn)
 	}
 	
-	responseTime := time.Since(startTime)
+	serviceTime := time.Since(startTime)
+	responseTime := time.Since(intendedTime)
 	
 	if err != nil {
-		gg.recordFailure(responseTime, err)
+		gg.recordFailure(serviceTime, responseTime, err)
 		logger.Debug("gRPC request failed: %v", err)
 	} else {
-		gg.recordSuccess(responseTime)
+		gg.recordSuccess(serviceTime, responseTime)
 	}
 }
 
@@ -367,28 +537,59 @@ func (gg *GRPCGenerator) makeBidiStreamRequest(ctx context.Context, conn *grpc.C
 	return err
 }
 
-func (gg *GRPCGenerator) recordSuccess(responseTime time.Duration) {
+// unaryAddressInterceptor и streamAddressInterceptor считают вызовы по IP сервера.
+func (gg *GRPCGenerator) unaryAddressInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var p peer.Peer
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+	gg.recordAddress(p.Addr)
+	return err
+}
+
+func (gg *GRPCGenerator) streamAddressInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err == nil {
+		if p, ok := peer.FromContext(stream.Context()); ok {
+			gg.recordAddress(p.Addr)
+		}
+	}
+	return stream, err
+}
+
+func (gg *GRPCGenerator) recordAddress(addr net.Addr) {
+	address := remoteIP(addr)
+	if address == "" {
+		return
+	}
+	gg.stats.mutex.Lock()
+	gg.stats.Addresses[address]++
+	gg.stats.mutex.Unlock()
+	metrics.GRPCRequestsByAddressCounter.WithLabelValues(address).Inc()
+}
+
+func (gg *GRPCGenerator) recordSuccess(serviceTime, responseTime time.Duration) {
 	atomic.AddInt64(&gg.stats.TotalRequests, 1)
 	atomic.AddInt64(&gg.stats.SuccessRequests, 1)
 	
 	gg.stats.mutex.Lock()
-	gg.stats.TotalResponseTime += responseTime
-	if responseTime < gg.stats.MinResponseTime {
-		gg.stats.MinResponseTime = responseTime
+	gg.stats.TotalResponseTime += serviceTime
+	if serviceTime < gg.stats.MinResponseTime {
+		gg.stats.MinResponseTime = serviceTime
 	}
-	if responseTime > gg.stats.MaxResponseTime {
-		gg.stats.MaxResponseTime = responseTime
+	if serviceTime > gg.stats.MaxResponseTime {
+		gg.stats.MaxResponseTime = serviceTime
 	}
 	gg.stats.StatusCodes[codes.OK]++
 	gg.stats.mutex.Unlock()
+	
+	gg.recordLatency(serviceTime, responseTime)
 }
 
-func (gg *GRPCGenerator) recordFailure(responseTime time.Duration, err error) {
+func (gg *GRPCGenerator) recordFailure(serviceTime, responseTime time.Duration, err error) {
 	atomic.AddInt64(&gg.stats.TotalRequests, 1)
 	atomic.AddInt64(&gg.stats.FailedRequests, 1)
 	
 	gg.stats.mutex.Lock()
-	gg.stats.TotalResponseTime += responseTime
+	gg.stats.TotalResponseTime += serviceTime
 	
 	if st, ok := status.FromError(err); ok {
 		gg.stats.StatusCodes[st.Code()]++
@@ -396,6 +597,15 @@ func (gg *GRPCGenerator) recordFailure(responseTime time.Duration, err error) {
 		gg.stats.StatusCodes[codes.Unknown]++
 	}
 	gg.stats.mutex.Unlock()
+	
+	gg.recordLatency(serviceTime, responseTime)
+}
+
+func (gg *GRPCGenerator) recordLatency(serviceTime, responseTime time.Duration) {
+	gg.stats.serviceTime.Record(serviceTime)
+	gg.stats.latency.Record(responseTime)
+	metrics.GRPCServiceTimeHistogram.Observe(serviceTime.Seconds())
+	metrics.GRPCResponseTimeHistogram.Observe(responseTime.Seconds())
 }
 
 func (gg *GRPCGenerator) statsCollector() {
@@ -413,6 +623,7 @@ func (gg *GRPCGenerator) statsCollector() {
 			currentRPS := currentRequests - lastRequests
 			atomic.StoreInt64(&gg.stats.CurrentRPS, currentRPS)
 			lastRequests = currentRequests
+			gg.workers.checkBottleneck(atomic.LoadInt64(&gg.stats.DroppedRequests), atomic.LoadInt64(&gg.stats.LateRequests))
 		}
 	}
 }
@@ -431,19 +642,34 @@ func (gg *GRPCGenerator) GetStats() *GRPCStats {
 		statusCodes[code] = count
 	}
 	
+	addresses := make(map[string]int64)
+	for address, count := range gg.stats.Addresses {
+		addresses[address] = count
+	}
+	
 	return &GRPCStats{
 		TotalRequests:     total,
 		SuccessRequests:   success,
 		FailedRequests:    failed,
 		CurrentRPS:        currentRPS,
+		DroppedRequests:   atomic.LoadInt64(&gg.stats.DroppedRequests),
+		LateRequests:      atomic.LoadInt64(&gg.stats.LateRequests),
+		Workers:           gg.workers.stats(),
 		TotalResponseTime: gg.stats.TotalResponseTime,
 		MinResponseTime:   gg.stats.MinResponseTime,
 		MaxResponseTime:   gg.stats.MaxResponseTime,
 		StartTime:         gg.stats.StartTime,
 		StatusCodes:       statusCodes,
+		Addresses:         addresses,
+		Latency:           gg.stats.latency.Percentiles(),
+		ServiceTime:       gg.stats.serviceTime.Percentiles(),
 	}
 }
 
+func (gg *GRPCGenerator) GetLatencyHistogram() *LatencyHistogram {
+	return gg.stats.latency.Snapshot()
+}
+
 func (gg *GRPCGenerator) GetAverageResponseTime() time.Duration {
 	stats := gg.GetStats()
 	if stats.TotalRequests == 0 {
@@ -0,0 +1,334 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Типы ответов и расширения, которые считаются статикой при SkipStatic.
+var (
+	harStaticContentTypes = []string{
+		"image/", "font/", "audio/", "video/", "text/css",
+		"text/javascript", "application/javascript", "application/x-javascript",
+		"application/font-", "application/x-font-", "application/vnd.ms-fontobject",
+	}
+	harStaticExtensions = map[string]bool{
+		".js": true, ".mjs": true, ".css": true, ".map": true,
+		".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true, ".avif": true,
+		".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+		".mp4": true, ".webm": true, ".mp3": true,
+	}
+)
+
+// Заголовки, которые не переносятся из записи: их выставляет транспорт,
+// а Accept-Encoding отключил бы автоматическую распаковку ответа в Go.
+var harSkippedHeaders = map[string]bool{
+	"host": true, "content-length": true, "connection": true, "keep-alive": true,
+	"proxy-connection": true, "transfer-encoding": true, "upgrade": true, "te": true,
+	"accept-encoding": true,
+}
+
+// HARImportOptions - фильтры импорта HAR. Хосты совпадают точно или как поддомен,
+// типы - по префиксу Content-Type ответа ("image/", "text/css").
+type HARImportOptions struct {
+	IncludeHosts        []string `json:"includeHosts,omitempty"`
+	ExcludeHosts        []string `json:"excludeHosts,omitempty"`
+	ExcludeContentTypes []string `json:"excludeContentTypes,omitempty"`
+	SkipStatic          bool     `json:"skipStatic,omitempty"`
+	KeepCookies         bool     `json:"keepCookies,omitempty"`
+}
+
+// HARRecording - запросы из HAR в порядке отправки.
+type HARRecording struct {
+	Requests []*HARRequest
+	Skipped  int
+	Duration time.Duration
+}
+
+// HARRequest - записанный запрос, его смещение от начала записи и пауза до следующего запроса.
+type HARRequest struct {
+	Template RequestTemplate
+	Offset   time.Duration
+	Gap      time.Duration
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string  `json:"startedDateTime"`
+	Time            float64 `json:"time"`
+	Request         struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []harHeader `json:"headers"`
+		PostData *struct {
+			MimeType string      `json:"mimeType"`
+			Text     string      `json:"text"`
+			Params   []harHeader `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int         `json:"status"`
+		Headers []harHeader `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimedEntry struct {
+	entry   *harEntry
+	started time.Time
+}
+
+// ParseHAR разбирает HAR и отбирает запросы по фильтрам.
+// Запросы не из http(s) и CONNECT пропускаются всегда.
+func ParseHAR(data []byte, options *HARImportOptions) (*HARRecording, error) {
+	if options == nil {
+		options = &HARImportOptions{}
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR: %v", err)
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, fmt.Errorf("HAR contains no entries")
+	}
+
+	recording := &HARRecording{}
+	entries := make([]harTimedEntry, 0, len(har.Log.Entries))
+	for i := range har.Log.Entries {
+		entry := &har.Log.Entries[i]
+		started, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid startedDateTime %q", i, entry.StartedDateTime)
+		}
+		if !options.accepts(entry) {
+			recording.Skipped++
+			continue
+		}
+		entries = append(entries, harTimedEntry{entry: entry, started: started})
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("all %d HAR entries were filtered out", len(har.Log.Entries))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].started.Before(entries[j].started)
+	})
+
+	first := entries[0].started
+	var end time.Time
+	for i, timed := range entries {
+		finished := timed.started.Add(harDuration(timed.entry.Time))
+		if finished.After(end) {
+			end = finished
+		}
+
+		request := &HARRequest{
+			Template: harTemplate(timed.entry, options.KeepCookies),
+			Offset:   timed.started.Sub(first),
+		}
+		// Пауза - от конца этого запроса до начала следующего; параллельные запросы дают 0.
+		if i+1 < len(entries) {
+			if gap := entries[i+1].started.Sub(finished); gap > 0 {
+				request.Gap = gap.Round(time.Millisecond)
+			}
+		}
+		recording.Requests = append(recording.Requests, request)
+	}
+	recording.Duration = end.Sub(first)
+
+	return recording, nil
+}
+
+func harDuration(ms float64) time.Duration {
+	if ms < 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (o *HARImportOptions) accepts(entry *harEntry) bool {
+	method := strings.ToUpper(entry.Request.Method)
+	if method == "" || method == "CONNECT" {
+		return false
+	}
+	parsed, err := url.Parse(entry.Request.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if len(o.IncludeHosts) > 0 && !matchHost(host, o.IncludeHosts) {
+		return false
+	}
+	if matchHost(host, o.ExcludeHosts) {
+		return false
+	}
+
+	contentType := strings.ToLower(entry.Response.Content.MimeType)
+	for _, excluded := range o.ExcludeContentTypes {
+		if excluded = strings.ToLower(strings.TrimSpace(excluded)); excluded != "" && strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+
+	if o.SkipStatic {
+		for _, static := range harStaticContentTypes {
+			if strings.HasPrefix(contentType, static) {
+				return false
+			}
+		}
+		if harStaticExtensions[strings.ToLower(path.Ext(parsed.Path))] {
+			return false
+		}
+	}
+	return true
+}
+
+func matchHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pattern), "*."))
+		if pattern != "" && (host == pattern || strings.HasSuffix(host, "."+pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+func harTemplate(entry *harEntry, keepCookies bool) RequestTemplate {
+	tmpl := RequestTemplate{
+		Method:  strings.ToUpper(entry.Request.Method),
+		URL:     escapeTemplateText(entry.Request.URL),
+		Headers: make(map[string]string),
+	}
+
+	for _, header := range entry.Request.Headers {
+		name := strings.ToLower(header.Name)
+		// Псевдозаголовки HTTP/2 (:authority, :path) и cookie - cookie ведёт сам клиент.
+		if strings.HasPrefix(name, ":") || harSkippedHeaders[name] || (name == "cookie" && !keepCookies) {
+			continue
+		}
+		key := canonicalHeaderName(header.Name)
+		if existing, ok := tmpl.Headers[key]; ok {
+			separator := ", "
+			if name == "cookie" {
+				separator = "; "
+			}
+			tmpl.Headers[key] = existing + separator + escapeTemplateText(header.Value)
+			continue
+		}
+		tmpl.Headers[key] = escapeTemplateText(header.Value)
+	}
+
+	if postData := entry.Request.PostData; postData != nil {
+		body := postData.Text
+		if body == "" && len(postData.Params) > 0 {
+			form := url.Values{}
+			for _, param := range postData.Params {
+				form.Add(param.Name, param.Value)
+			}
+			body = form.Encode()
+		}
+		tmpl.Body = escapeTemplateText(body)
+		if _, ok := tmpl.Headers["Content-Type"]; !ok && postData.MimeType != "" {
+			tmpl.Headers["Content-Type"] = postData.MimeType
+		}
+	}
+
+	if len(tmpl.Headers) == 0 {
+		tmpl.Headers = nil
+	}
+	return tmpl
+}
+
+func canonicalHeaderName(name string) string {
+	parts := strings.Split(strings.ToLower(name), "-")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// escapeTemplateText экранирует "{{" в записанных значениях, чтобы они не разбирались как шаблон.
+func escapeTemplateText(text string) string {
+	return strings.ReplaceAll(text, "{{", "{{`{{`}}")
+}
+
+// RecordedRPS - средняя частота запросов в записи.
+func (r *HARRecording) RecordedRPS() float64 {
+	if r.Duration <= 0 {
+		return float64(len(r.Requests))
+	}
+	return float64(len(r.Requests)) / r.Duration.Seconds()
+}
+
+// Scenario превращает запись в сценарий: шаги идут в записанном порядке,
+// после каждого шага - записанная пауза до следующего запроса.
+func (r *HARRecording) Scenario(name string) *Scenario {
+	scenario := &Scenario{Name: name}
+	for i, request := range r.Requests {
+		step := &ScenarioStep{RequestTemplate: request.Template}
+		step.Name = fmt.Sprintf("%02d %s %s", i+1, step.Method, harPath(request.Template.URL))
+		if request.Gap > 0 {
+			step.ThinkTime = &ThinkTime{Distribution: ThinkTimeConstant, Mean: Duration(request.Gap)}
+		}
+		scenario.Steps = append(scenario.Steps, step)
+	}
+	return scenario
+}
+
+// RequestMix превращает запись в набор шаблонов для HTTP генератора.
+// Одинаковые запросы объединяются, их количество становится весом, так что пропорции записи сохраняются.
+func (r *HARRecording) RequestMix() *RequestMix {
+	mix := &RequestMix{}
+	byKey := make(map[string]*RequestTemplate)
+	names := make(map[string]int)
+
+	for _, request := range r.Requests {
+		key := request.Template.Method + " " + request.Template.URL + "\n" + request.Template.Body
+		if existing, ok := byKey[key]; ok {
+			existing.Weight++
+			continue
+		}
+
+		tmpl := request.Template
+		tmpl.Weight = 1
+		baseName := tmpl.Method + " " + harPath(tmpl.URL)
+		names[baseName]++
+		tmpl.Name = baseName
+		if names[baseName] > 1 {
+			tmpl.Name = fmt.Sprintf("%s #%d", baseName, names[baseName])
+		}
+
+		byKey[key] = &tmpl
+		mix.Requests = append(mix.Requests, &tmpl)
+	}
+	return mix
+}
+
+func harPath(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Path == "" {
+		return rawURL
+	}
+	return parsed.Path
+}
@@ -0,0 +1,269 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Гистограмма в стиле HdrHistogram: log-linear бакеты с точностью
+// в 2 значащие цифры в диапазоне от 1us до 1h. Значения хранятся в микросекундах.
+const (
+	histSubBucketBits  = 7
+	histSubBucketHalf  = 1 << histSubBucketBits
+	histSubBucketCount = histSubBucketHalf << 1
+	histSubBucketMask  = histSubBucketCount - 1
+	histMaxValue       = int64(time.Hour / time.Microsecond)
+)
+
+var histCountsLen = countsIndex(histMaxValue) + 1
+
+type LatencyHistogram struct {
+	counts []int64
+	total  int64
+	min    int64
+	max    int64
+	mutex  sync.Mutex
+}
+
+// HistogramData - содержимое гистограммы для передачи по сети: ненулевые бакеты по индексу и
+// min/max в микросекундах. В отличие от перцентилей такие данные можно сложить, поэтому агенты
+// отдают их мастеру для общих перцентилей.
+type HistogramData struct {
+	Counts map[int]int64 `json:"counts"`
+	Total  int64         `json:"total"`
+	MinUs  int64         `json:"min_us"`
+	MaxUs  int64         `json:"max_us"`
+}
+
+type LatencyPercentiles struct {
+	P50  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	P999 time.Duration
+	Max  time.Duration
+}
+
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{
+		counts: make([]int64, histCountsLen),
+		min:    math.MaxInt64,
+	}
+}
+
+func countsIndex(value int64) int {
+	bucket := bits.Len64(uint64(value)|histSubBucketMask) - (histSubBucketBits + 1)
+	subBucket := int(value >> uint(bucket))
+	return (bucket+1)<<histSubBucketBits + subBucket - histSubBucketHalf
+}
+
+func highestEquivalentValue(index int) int64 {
+	bucket := index>>histSubBucketBits - 1
+	subBucket := index&(histSubBucketHalf-1) + histSubBucketHalf
+	if bucket < 0 {
+		bucket = 0
+		subBucket = index
+	}
+	return int64(subBucket)<<uint(bucket) + (int64(1) << uint(bucket)) - 1
+}
+
+func (h *LatencyHistogram) Record(d time.Duration) {
+	value := int64(d / time.Microsecond)
+	if value < 0 {
+		value = 0
+	}
+	if value > histMaxValue {
+		value = histMaxValue
+	}
+
+	h.mutex.Lock()
+	h.counts[countsIndex(value)]++
+	h.total++
+	if value < h.min {
+		h.min = value
+	}
+	if value > h.max {
+		h.max = value
+	}
+	h.mutex.Unlock()
+}
+
+// Merge добавляет значения другой гистограммы, например при сведении статистики агентов.
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other == nil || other == h {
+		return
+	}
+
+	snapshot := other.Snapshot()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, count := range snapshot.counts {
+		h.counts[i] += count
+	}
+	h.total += snapshot.total
+	if snapshot.min < h.min {
+		h.min = snapshot.min
+	}
+	if snapshot.max > h.max {
+		h.max = snapshot.max
+	}
+}
+
+// Data выгружает ненулевые бакеты гистограммы.
+func (h *LatencyHistogram) Data() *HistogramData {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	data := &HistogramData{Counts: make(map[int]int64), Total: h.total, MaxUs: h.max}
+	for i, count := range h.counts {
+		if count > 0 {
+			data.Counts[i] = count
+		}
+	}
+	if h.total > 0 {
+		data.MinUs = h.min
+	}
+	return data
+}
+
+// Histogram восстанавливает гистограмму из выгруженных данных, проверяя индексы и сумму бакетов.
+func (d *HistogramData) Histogram() (*LatencyHistogram, error) {
+	h := NewLatencyHistogram()
+	if d == nil {
+		return h, nil
+	}
+
+	var total int64
+	for i, count := range d.Counts {
+		if i < 0 || i >= histCountsLen || count < 0 {
+			return nil, fmt.Errorf("invalid histogram bucket %d: %d", i, count)
+		}
+		h.counts[i] = count
+		total += count
+	}
+	if total != d.Total {
+		return nil, fmt.Errorf("histogram total %d does not match bucket sum %d", d.Total, total)
+	}
+	h.total = total
+	if total > 0 {
+		h.min = d.MinUs
+		h.max = d.MaxUs
+	}
+	return h, nil
+}
+
+func (h *LatencyHistogram) Snapshot() *LatencyHistogram {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	counts := make([]int64, len(h.counts))
+	copy(counts, h.counts)
+
+	return &LatencyHistogram{
+		counts: counts,
+		total:  h.total,
+		min:    h.min,
+		max:    h.max,
+	}
+}
+
+func (h *LatencyHistogram) Reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.total = 0
+	h.min = math.MaxInt64
+	h.max = 0
+}
+
+func (h *LatencyHistogram) Count() int64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.total
+}
+
+func (h *LatencyHistogram) Max() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return time.Duration(h.max) * time.Microsecond
+}
+
+func (h *LatencyHistogram) Percentile(percentile float64) time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.percentileLocked(percentile)
+}
+
+func (h *LatencyHistogram) percentileLocked(percentile float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	if percentile > 100 {
+		percentile = 100
+	}
+	target := int64(math.Ceil(percentile / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var cumulative int64
+	for i, count := range h.counts {
+		cumulative += count
+		if cumulative >= target {
+			value := highestEquivalentValue(i)
+			if value > h.max {
+				value = h.max
+			}
+			if value < h.min {
+				value = h.min
+			}
+			return time.Duration(value) * time.Microsecond
+		}
+	}
+
+	return time.Duration(h.max) * time.Microsecond
+}
+
+func (h *LatencyHistogram) Percentiles() LatencyPercentiles {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.total == 0 {
+		return LatencyPercentiles{}
+	}
+
+	return LatencyPercentiles{
+		P50:  h.percentileLocked(50),
+		P90:  h.percentileLocked(90),
+		P95:  h.percentileLocked(95),
+		P99:  h.percentileLocked(99),
+		P999: h.percentileLocked(99.9),
+		Max:  time.Duration(h.max) * time.Microsecond,
+	}
+}
+
+// MarshalJSON отдаёт перцентили в миллисекундах, как их удобнее читать в веб-интерфейсе.
+func (lp LatencyPercentiles) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]float64{
+		"p50_ms":  durationToMillis(lp.P50),
+		"p90_ms":  durationToMillis(lp.P90),
+		"p95_ms":  durationToMillis(lp.P95),
+		"p99_ms":  durationToMillis(lp.P99),
+		"p999_ms": durationToMillis(lp.P999),
+		"max_ms":  durationToMillis(lp.Max),
+	})
+}
+
+func durationToMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,405 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestMix - файл со списком шаблонов запросов для -http-requests.
+type RequestMix struct {
+	Requests []*RequestTemplate `json:"requests"`
+}
+
+// EndpointStats - статистика по одному шаблону запроса.
+type EndpointStats struct {
+	Name            string             `json:"name"`
+	Method          string             `json:"method"`
+	URL             string             `json:"url"`
+	Weight          int                `json:"weight"`
+	TotalRequests   int64              `json:"totalRequests"`
+	SuccessRequests int64              `json:"successRequests"`
+	FailedRequests  int64              `json:"failedRequests"`
+	SuccessRate     float64            `json:"successRate"`
+	AvgResponseTime float64            `json:"avgResponseTimeMs"`
+	StatusCodes     map[int]int64      `json:"statusCodes"`
+	ErrorClasses    map[string]int64   `json:"errorClasses"`
+	Latency         LatencyPercentiles `json:"latency"`
+	GraphQL         *GraphQLStats      `json:"graphql,omitempty"`
+}
+
+type httpEndpoint struct {
+	template          *RequestTemplate
+	sourceURL         string
+	followsTarget     bool
+	url               atomic.Pointer[TextTemplate]
+	headers           map[string]*TextTemplate
+	body              *TextTemplate
+	ownChecks         []*ResponseCheck
+	checks            []*ResponseCheck
+	needsBody         bool
+	hasStatusCheck    bool
+	graphql           *graphQLOperation
+	totalRequests     int64
+	successRequests   int64
+	failedRequests    int64
+	totalResponseTime time.Duration
+	statusCodes       map[int]int64
+	errorClasses      map[string]int64
+	latency           *LatencyHistogram
+	mutex             sync.Mutex
+}
+
+func LoadRequestTemplates(path string) ([]*RequestTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read requests file: %v", err)
+	}
+
+	var mix RequestMix
+	if err := json.Unmarshal(data, &mix); err != nil {
+		return nil, fmt.Errorf("failed to parse requests file: %v", err)
+	}
+
+	if len(mix.Requests) == 0 {
+		return nil, fmt.Errorf("requests file %s contains no requests", path)
+	}
+
+	return mix.Requests, nil
+}
+
+// SetRequestTemplates задаёт взвешенный набор запросов вместо одного URL.
+// Пустой URL шаблона заменяется на URL генератора, а путь вида "/cart" дописывается к его хосту.
+func (hg *HTTPGenerator) SetRequestTemplates(templates []*RequestTemplate) error {
+	if len(templates) == 0 {
+		return fmt.Errorf("request templates list is empty")
+	}
+
+	endpoints := make([]*httpEndpoint, 0, len(templates))
+	names := make(map[string]bool)
+	totalWeight := 0
+
+	for i, tmpl := range templates {
+		if tmpl == nil {
+			return fmt.Errorf("request template %d is empty", i)
+		}
+
+		normalized := *tmpl
+		if normalized.Method == "" {
+			normalized.Method = hg.method
+		}
+		normalized.Method = strings.ToUpper(normalized.Method)
+
+		sourceURL := normalized.URL
+		resolvedURL, err := resolveTemplateURL(hg.targetURL, normalized.URL)
+		if err != nil {
+			return fmt.Errorf("request template %d: %v", i, err)
+		}
+		normalized.URL = resolvedURL
+
+		if normalized.Weight < 0 {
+			return fmt.Errorf("request template %d: weight must be non-negative", i)
+		}
+		if normalized.Weight == 0 {
+			normalized.Weight = 1
+		}
+		if normalized.Timeout < 0 {
+			return fmt.Errorf("request template %d: timeout must be non-negative", i)
+		}
+
+		if normalized.Name == "" {
+			normalized.Name = normalized.Method + " " + normalized.URL
+		}
+		if names[normalized.Name] {
+			return fmt.Errorf("duplicate request template name: %s", normalized.Name)
+		}
+		names[normalized.Name] = true
+
+		endpoint := newHTTPEndpoint(&normalized)
+		endpoint.sourceURL = sourceURL
+		endpoint.followsTarget = sourceURL == "" || strings.HasPrefix(sourceURL, "/")
+		if err := hg.compileEndpoint(endpoint); err != nil {
+			return fmt.Errorf("request template %s: %v", normalized.Name, err)
+		}
+		ownChecks, err := hg.checks.compile(normalized.Checks, normalized.Name)
+		if err != nil {
+			return fmt.Errorf("request template %s: %v", normalized.Name, err)
+		}
+		endpoint.ownChecks = ownChecks
+		endpoints = append(endpoints, endpoint)
+		totalWeight += normalized.Weight
+	}
+
+	hg.endpoints = endpoints
+	hg.totalWeight = totalWeight
+
+	return nil
+}
+
+func newHTTPEndpoint(tmpl *RequestTemplate) *httpEndpoint {
+	return &httpEndpoint{
+		template:     tmpl,
+		statusCodes:  make(map[int]int64),
+		errorClasses: make(map[string]int64),
+		latency:      NewLatencyHistogram(),
+	}
+}
+
+func (e *httpEndpoint) setChecks(globalChecks []*ResponseCheck) {
+	e.checks = append(append([]*ResponseCheck{}, globalChecks...), e.ownChecks...)
+	e.needsBody = e.graphql != nil
+	e.hasStatusCheck = false
+	for _, check := range e.checks {
+		if check.needsBody() {
+			e.needsBody = true
+		}
+		if len(check.Status) > 0 {
+			e.hasStatusCheck = true
+		}
+	}
+}
+
+// compileEndpoint компилирует URL, заголовки (общие и шаблона) и тело запроса.
+func (hg *HTTPGenerator) compileEndpoint(endpoint *httpEndpoint) error {
+	tmpl := endpoint.template
+
+	// URL мог уже смениться через SetTargetURL, его не перезаписываем.
+	urlTemplate := endpoint.url.Load()
+	if urlTemplate == nil {
+		var err error
+		if urlTemplate, err = hg.templates.Compile("url", tmpl.URL); err != nil {
+			return err
+		}
+	}
+
+	bodyTemplate, err := hg.templates.Compile("body", tmpl.Body)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]*TextTemplate)
+	for _, source := range []map[string]string{hg.headers, tmpl.Headers} {
+		for key, value := range source {
+			headerTemplate, err := hg.templates.Compile(key, value)
+			if err != nil {
+				return err
+			}
+			headers[key] = headerTemplate
+		}
+	}
+
+	endpoint.url.Store(urlTemplate)
+	endpoint.body = bodyTemplate
+	endpoint.headers = headers
+	return nil
+}
+
+// useLiteral отправляет URL, заголовки и тело как есть, если шаблоны не компилируются.
+func (e *httpEndpoint) useLiteral(globalHeaders map[string]string) {
+	if e.url.Load() == nil {
+		e.url.Store(&TextTemplate{raw: e.template.URL})
+	}
+	e.body = &TextTemplate{raw: e.template.Body}
+	e.headers = make(map[string]*TextTemplate)
+	for _, source := range []map[string]string{globalHeaders, e.template.Headers} {
+		for key, value := range source {
+			e.headers[key] = &TextTemplate{raw: value}
+		}
+	}
+}
+
+// isStatic сообщает, что запрос не содержит шаблонов и его можно отправлять без подготовки данных.
+func (e *httpEndpoint) isStatic() bool {
+	if !e.url.Load().IsStatic() || !e.body.IsStatic() {
+		return false
+	}
+	for _, header := range e.headers {
+		if !header.IsStatic() {
+			return false
+		}
+	}
+	return true
+}
+
+// render подставляет данные запроса в URL, заголовки и тело.
+func (e *httpEndpoint) render(data *TemplateData) (string, map[string]string, string, error) {
+	targetURL, err := e.url.Load().Render(data)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	body, err := e.body.Render(data)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	headers := make(map[string]string, len(e.headers))
+	for key, header := range e.headers {
+		value, err := header.Render(data)
+		if err != nil {
+			return "", nil, "", err
+		}
+		headers[key] = value
+	}
+
+	return targetURL, headers, body, nil
+}
+
+func resolveTemplateURL(baseURL, templateURL string) (string, error) {
+	if templateURL == "" {
+		if baseURL == "" {
+			return "", fmt.Errorf("URL is empty")
+		}
+		return baseURL, nil
+	}
+
+	if !strings.HasPrefix(templateURL, "/") {
+		return templateURL, nil
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return "", fmt.Errorf("relative URL %s requires an absolute base URL", templateURL)
+	}
+
+	return base.Scheme + "://" + base.Host + templateURL, nil
+}
+
+// defaultEndpoint собирает единственный шаблон из URL, метода, заголовков и тела генератора.
+func (hg *HTTPGenerator) defaultEndpoint() *httpEndpoint {
+	endpoint := newHTTPEndpoint(&RequestTemplate{
+		Name:   hg.method + " " + hg.targetURL,
+		Method: hg.method,
+		URL:    hg.targetURL,
+		Body:   hg.body,
+		Weight: 1,
+	})
+	endpoint.followsTarget = true
+	return endpoint
+}
+
+// SetTargetURL меняет URL генератора на ходу. Запросы с пустым или относительным URL
+// переключаются на новый адрес, абсолютные URL из набора запросов остаются как были.
+// Если новый URL не подходит хотя бы одному запросу, ничего не меняется.
+func (hg *HTTPGenerator) SetTargetURL(targetURL string) error {
+	hg.settingsMutex.Lock()
+	defer hg.settingsMutex.Unlock()
+
+	// Транспорт уже набирает соединения в конкретный сокет или по TCP, поэтому сменить можно только путь запроса.
+	socketPath, targetURL, err := SplitUnixURL(targetURL)
+	if err != nil {
+		return err
+	}
+	if socketPath != hg.unixSocket {
+		return fmt.Errorf("cannot switch unix socket target on the fly, restart the run to change it")
+	}
+
+	compiled := make(map[*httpEndpoint]*TextTemplate)
+	for _, endpoint := range hg.endpoints {
+		if !endpoint.followsTarget {
+			continue
+		}
+		resolvedURL, err := resolveTemplateURL(targetURL, endpoint.sourceURL)
+		if err != nil {
+			return fmt.Errorf("request template %s: %v", endpoint.template.Name, err)
+		}
+		urlTemplate, err := hg.templates.Compile("url", resolvedURL)
+		if err != nil {
+			return err
+		}
+		compiled[endpoint] = urlTemplate
+	}
+
+	for endpoint, urlTemplate := range compiled {
+		endpoint.url.Store(urlTemplate)
+	}
+	hg.targetURL = targetURL
+	return nil
+}
+
+func (hg *HTTPGenerator) pickEndpoint() *httpEndpoint {
+	if len(hg.endpoints) == 1 {
+		return hg.endpoints[0]
+	}
+
+	point := rand.Intn(hg.totalWeight)
+	for _, endpoint := range hg.endpoints {
+		point -= endpoint.template.Weight
+		if point < 0 {
+			return endpoint
+		}
+	}
+
+	return hg.endpoints[len(hg.endpoints)-1]
+}
+
+func (e *httpEndpoint) recordStatusCode(statusCode int) {
+	e.mutex.Lock()
+	e.statusCodes[statusCode]++
+	e.mutex.Unlock()
+}
+
+func (e *httpEndpoint) recordResult(success bool, serviceTime, responseTime time.Duration, errorClass string) {
+	atomic.AddInt64(&e.totalRequests, 1)
+	if success {
+		atomic.AddInt64(&e.successRequests, 1)
+	} else {
+		atomic.AddInt64(&e.failedRequests, 1)
+	}
+
+	e.mutex.Lock()
+	e.totalResponseTime += serviceTime
+	if errorClass != "" {
+		e.errorClasses[errorClass]++
+	}
+	e.mutex.Unlock()
+
+	e.latency.Record(responseTime)
+}
+
+func (e *httpEndpoint) snapshot() EndpointStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	total := atomic.LoadInt64(&e.totalRequests)
+	success := atomic.LoadInt64(&e.successRequests)
+
+	stats := EndpointStats{
+		Name:            e.template.Name,
+		Method:          e.template.Method,
+		URL:             e.template.URL,
+		Weight:          e.template.Weight,
+		TotalRequests:   total,
+		SuccessRequests: success,
+		FailedRequests:  atomic.LoadInt64(&e.failedRequests),
+		StatusCodes:     make(map[int]int64),
+		ErrorClasses:    make(map[string]int64),
+		Latency:         e.latency.Percentiles(),
+	}
+
+	if current := e.url.Load(); current != nil {
+		stats.URL = current.raw
+	}
+	if total > 0 {
+		stats.SuccessRate = float64(success) / float64(total) * 100.0
+		stats.AvgResponseTime = durationToMillis(e.totalResponseTime / time.Duration(total))
+	}
+
+	for code, count := range e.statusCodes {
+		stats.StatusCodes[code] = count
+	}
+	for class, count := range e.errorClasses {
+		stats.ErrorClasses[class] = count
+	}
+	if e.graphql != nil {
+		stats.GraphQL = e.graphql.snapshot()
+	}
+
+	return stats
+}
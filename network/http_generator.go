@@ -3,16 +3,20 @@ package network
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"stresspulse/logger"
+	"stresspulse/metrics"
 )
 
 type HTTPGenerator struct {
@@ -28,8 +32,23 @@ type HTTPGenerator struct {
 	ctx              context.Context
 	cancel           context.CancelFunc
 	stats            *HTTPStats
-	requestChan      chan struct{}
-	workerCount      int
+	workers          *workerPool
+	endpoints        []*httpEndpoint
+	totalWeight      int
+	templates        *TemplateEngine
+	checks           *checkRegistry
+	globalChecks     []*ResponseCheck
+	auth             AuthProvider
+	settingsMutex    sync.RWMutex
+	patternStart     time.Time
+	tlsConfig        *tls.Config
+	connOptions      HTTPConnectionOptions
+	proxy            ProxyOptions
+	resolver         *Resolver
+	source           *SourceBinder
+	captures         *CaptureStore
+	unixSocket       string
+	maxConcurrency   int
 }
 
 type HTTPStats struct {
@@ -40,14 +59,40 @@ type HTTPStats struct {
 	MinResponseTime   time.Duration
 	MaxResponseTime   time.Duration
 	CurrentRPS        int64
+	DroppedRequests   int64
+	LateRequests      int64
+	Workers           WorkerPoolStats
+	NewConnections    int64
+	ReusedConnections int64
+	ConnectionReuse   float64
+	BytesSent         int64
+	BytesReceived     int64
 	StartTime         time.Time
+	StatusCodes       map[int]int64
+	ErrorClasses      map[string]int64
+	Endpoints         []EndpointStats
+	Latency           LatencyPercentiles
+	ServiceTime       LatencyPercentiles
+	Phases            map[string]LatencyPercentiles
+	Protocols         map[string]int64
+	Addresses         map[string]int64
+	Checks            []CheckStats
+	CheckFailures     []CheckFailure
+	latency           *LatencyHistogram
+	serviceTime       *LatencyHistogram
+	phases            map[string]*LatencyHistogram
 	mutex             sync.RWMutex
 }
 
 type RequestTemplate struct {
+	Name    string            `json:"name,omitempty"`
 	Method  string            `json:"method"`
-	Headers map[string]string `json:"headers"`
-	Body    string            `json:"body"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Weight  int               `json:"weight,omitempty"`
+	Timeout Duration          `json:"timeout,omitempty"`
+	Checks  []*ResponseCheck  `json:"checks,omitempty"`
 }
 
 func NewHTTPGenerator(targetURL string, targetRPS int, pattern, method string, timeout time.Duration) *HTTPGenerator {
@@ -59,7 +104,22 @@ func NewHTTPGenerator(targetURL string, targetRPS int, pattern, method string, t
 		}
 	}
 
-	return &HTTPGenerator{
+	stats := &HTTPStats{
+		StartTime:       time.Now(),
+		MinResponseTime: time.Hour,
+		StatusCodes:     make(map[int]int64),
+		ErrorClasses:    make(map[string]int64),
+		Protocols:       make(map[string]int64),
+		Addresses:       make(map[string]int64),
+		latency:         NewLatencyHistogram(),
+		serviceTime:     NewLatencyHistogram(),
+		phases:          make(map[string]*LatencyHistogram),
+	}
+	for _, phase := range httpPhases {
+		stats.phases[phase] = NewLatencyHistogram()
+	}
+
+	hg := &HTTPGenerator{
 		targetURL:   targetURL,
 		targetRPS:   targetRPS,
 		pattern:     pattern,
@@ -67,22 +127,53 @@ func NewHTTPGenerator(targetURL string, targetRPS int, pattern, method string, t
 		headers:     make(map[string]string),
 		timeout:     timeout,
 		enabled:     false,
-		requestChan: make(chan struct{}, targetRPS*4),
-		workerCount: workerCount,
-		client: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        200,
-				MaxIdleConnsPerHost: 50,
-				IdleConnTimeout:     30 * time.Second,
-				DisableKeepAlives:   false,
-			},
-		},
-		stats: &HTTPStats{
-			StartTime:       time.Now(),
-			MinResponseTime: time.Hour,
-		},
+		templates:   NewTemplateEngine(nil),
+		checks:      newCheckRegistry(),
+		patternStart: stats.StartTime,
+		client:      &http.Client{},
+		stats:       stats,
 	}
+	if socketPath, requestURL, err := SplitUnixURL(targetURL); err == nil && socketPath != "" {
+		hg.targetURL = requestURL
+		hg.unixSocket = socketPath
+	}
+	hg.workers = newWorkerPool("HTTP", "-http-max-concurrency", targetRPS*4, workerCount, DefaultHTTPMaxConcurrency, func(workerID int, intendedTime time.Time) {
+		hg.makeRequest(intendedTime)
+	})
+	hg.SetMaxConcurrency(DefaultHTTPMaxConcurrency)
+	return hg
+}
+
+// SetMaxConcurrency задаёт предел одновременных запросов; пул воркеров растёт до него по мере нужды.
+func (hg *HTTPGenerator) SetMaxConcurrency(maxConcurrency int) {
+	hg.workers.setMaxWorkers(maxConcurrency)
+	hg.maxConcurrency = maxConcurrency
+}
+
+// SetTargetRPS меняет целевой RPS на ходу; статистика и соединения сохраняются.
+func (hg *HTTPGenerator) SetTargetRPS(rps int) {
+	hg.settingsMutex.Lock()
+	hg.targetRPS = rps
+	hg.settingsMutex.Unlock()
+}
+
+// SetPattern меняет паттерн нагрузки на ходу; cycle и ramp отсчитываются заново с момента смены.
+func (hg *HTTPGenerator) SetPattern(pattern string) {
+	hg.settingsMutex.Lock()
+	hg.pattern = pattern
+	hg.patternStart = time.Now()
+	hg.settingsMutex.Unlock()
+}
+
+func (hg *HTTPGenerator) TargetRPS() int {
+	targetRPS, _, _ := hg.target()
+	return targetRPS
+}
+
+func (hg *HTTPGenerator) target() (int, string, time.Time) {
+	hg.settingsMutex.RLock()
+	defer hg.settingsMutex.RUnlock()
+	return hg.targetRPS, hg.pattern, hg.patternStart
 }
 
 func (hg *HTTPGenerator) SetHeaders(headers map[string]string) {
@@ -93,6 +184,65 @@ func (hg *HTTPGenerator) SetBody(body string) {
 	hg.body = body
 }
 
+// SetChecks задаёт проверки ответа для всех запросов генератора; проверки из шаблонов добавляются к ним.
+func (hg *HTTPGenerator) SetChecks(checks []*ResponseCheck) error {
+	compiled, err := hg.checks.compile(checks, "")
+	if err != nil {
+		return err
+	}
+	hg.globalChecks = compiled
+	return nil
+}
+
+// SetTLSConfig задаёт TLS настройки для https:// запросов.
+func (hg *HTTPGenerator) SetTLSConfig(config *tls.Config) {
+	hg.tlsConfig = config
+}
+
+// SetConnectionOptions задаёт протокол (h1, h2, h2c, auto) и политику соединений. Транспорт
+// собирается при старте, поэтому настройки применяются до Start.
+func (hg *HTTPGenerator) SetConnectionOptions(options *HTTPConnectionOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	hg.connOptions = *options
+	return nil
+}
+
+// SetProxy направляет запросы через upstream прокси; применяется до Start.
+func (hg *HTTPGenerator) SetProxy(options *ProxyOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	hg.proxy = *options
+	return nil
+}
+
+// SetResolver задаёт разрешение имён и раскладку соединений по IP; nil - системный резолвер.
+func (hg *HTTPGenerator) SetResolver(resolver *Resolver) {
+	hg.resolver = resolver
+}
+
+// SetSource задаёт локальные адреса новых соединений; nil - адрес выбирает ядро.
+func (hg *HTTPGenerator) SetSource(source *SourceBinder) {
+	hg.source = source
+}
+
+// SetCaptures задаёт хранилище проваленных и медленных запросов; nil - не сохранять.
+func (hg *HTTPGenerator) SetCaptures(captures *CaptureStore) {
+	hg.captures = captures
+}
+
+// SetAuth задаёт провайдер аутентификации, который добавляет заголовки к каждому запросу.
+func (hg *HTTPGenerator) SetAuth(auth AuthProvider) {
+	hg.auth = auth
+}
+
+// SetDataFeeder подключает файл данных для подстановки {{.Data.column}} в URL, заголовки и тело.
+func (hg *HTTPGenerator) SetDataFeeder(feeder *DataFeeder) {
+	hg.templates.feeder = feeder
+}
+
 func (hg *HTTPGenerator) Start(ctx context.Context) {
 	if hg.enabled {
 		return
@@ -100,14 +250,49 @@ func (hg *HTTPGenerator) Start(ctx context.Context) {
 
 	hg.enabled = true
 	hg.ctx = ctx
+	var dial dialFunc
+	if hg.unixSocket != "" {
+		// Все соединения идут в сокет, поэтому резолвер, прокси и локальные адреса не участвуют.
+		dial = unixDialer(hg.unixSocket, countingDialer(nil, &hg.stats.BytesSent, &hg.stats.BytesReceived))
+	} else {
+		dial = countingDialer(hg.source, &hg.stats.BytesSent, &hg.stats.BytesReceived)
+		if hg.resolver != nil {
+			dial = hg.resolver.dialer(dial)
+		}
+		if hg.proxy.enabled() {
+			dial = hg.proxy.dialer(dial, hg.tlsConfig, hg.resolver, hg.recordProxyConnect)
+		}
+	}
+	hg.client.Transport = hg.connOptions.newTransport(hg.tlsConfig, dial, hg.maxConcurrency)
+	if transport, ok := hg.client.Transport.(*http.Transport); ok && hg.proxy.enabled() {
+		transport.Proxy = hg.proxy.forwardProxy()
+	}
+	
+	if len(hg.endpoints) == 0 {
+		hg.endpoints = []*httpEndpoint{hg.defaultEndpoint()}
+		hg.totalWeight = 1
+	}
+	for _, endpoint := range hg.endpoints {
+		endpoint.setChecks(hg.globalChecks)
+		if err := hg.compileEndpoint(endpoint); err != nil {
+			logger.Error("HTTP request %s: %v, templates disabled", endpoint.template.Name, err)
+			endpoint.useLiteral(hg.headers)
+		}
+	}
 	
 	logger.Info("Starting HTTP load generator: %s, target RPS: %d, pattern: %s", 
 		hg.targetURL, hg.targetRPS, hg.pattern)
-	
-	for i := 0; i < hg.workerCount; i++ {
-		go hg.httpWorker(i)
+	if hg.unixSocket != "" {
+		logger.Info("HTTP requests go to unix socket %s", hg.unixSocket)
+	}
+	if len(hg.endpoints) > 1 {
+		for _, endpoint := range hg.endpoints {
+			logger.Info("HTTP endpoint %s: %s %s, weight %d", endpoint.template.Name, endpoint.template.Method, endpoint.template.URL, endpoint.template.Weight)
+		}
 	}
 	
+	hg.workers.start(ctx)
+	
 	go hg.generateRPSLoad()
 	
 	go hg.statsCollector()
@@ -119,18 +304,26 @@ func (hg *HTTPGenerator) Stop() {
 	}
 
 	hg.enabled = false
+	hg.client.CloseIdleConnections()
 	
 	logger.Info("HTTP load generator stopped")
 }
 
-func (hg *HTTPGenerator) generateRPSLoad() {
-	tickInterval := 100 * time.Millisecond
-	if hg.targetRPS > 5000 {
-		tickInterval = 50 * time.Millisecond
-	}
-	if hg.targetRPS > 10000 {
-		tickInterval = 20 * time.Millisecond
+// httpTickInterval - шаг планирования: на высоком RPS чаще, чтобы пачки запросов были мельче.
+func httpTickInterval(targetRPS int) time.Duration {
+	switch {
+	case targetRPS > 10000:
+		return 20 * time.Millisecond
+	case targetRPS > 5000:
+		return 50 * time.Millisecond
+	default:
+		return 100 * time.Millisecond
 	}
+}
+
+func (hg *HTTPGenerator) generateRPSLoad() {
+	targetRPS, _, _ := hg.target()
+	tickInterval := httpTickInterval(targetRPS)
 
 	ticker := time.NewTicker(tickInterval)
 	defer ticker.Stop()
@@ -142,7 +335,7 @@ func (hg *HTTPGenerator) generateRPSLoad() {
 		select {
 		case <-hg.ctx.Done():
 			return
-		case <-ticker.C:
+		case tickTime := <-ticker.C:
 			currentSecond := time.Now().Unix()
 			
 			if currentSecond != lastSecond {
@@ -150,14 +343,25 @@ func (hg *HTTPGenerator) generateRPSLoad() {
 				requestsThisSecond = 0
 			}
 			
+			// Цель могла измениться через SetTargetRPS: очередь и шаг планирования подстраиваются под неё.
+			if newTargetRPS, _, _ := hg.target(); newTargetRPS != targetRPS {
+				targetRPS = newTargetRPS
+				hg.workers.resize(targetRPS * 4)
+				if interval := httpTickInterval(targetRPS); interval != tickInterval {
+					tickInterval = interval
+					ticker.Reset(tickInterval)
+				}
+			}
+			
 			currentRPS := hg.calculateCurrentRPS()
 			requestsToSend := hg.calculateRequestsToSend(currentRPS, requestsThisSecond, tickInterval)
 			
 			for i := 0; i < requestsToSend; i++ {
-				select {
-				case hg.requestChan <- struct{}{}:
+				if hg.workers.submit(scheduledTime(tickTime, tickInterval, i, requestsToSend)) {
 					requestsThisSecond++
-				default:
+				} else {
+					atomic.AddInt64(&hg.stats.DroppedRequests, 1)
+					metrics.HTTPDroppedRequestsCounter.Inc()
 				}
 			}
 		}
@@ -165,42 +369,43 @@ func (hg *HTTPGenerator) generateRPSLoad() {
 }
 
 func (hg *HTTPGenerator) calculateCurrentRPS() int {
-	switch hg.pattern {
+	targetRPS, pattern, patternStart := hg.target()
+	switch pattern {
 	case "constant":
-		return hg.targetRPS
+		return targetRPS
 	case "spike":
 		if rand.Intn(10) == 0 {
-			return hg.targetRPS * 3
+			return targetRPS * 3
 		}
-		return hg.targetRPS
+		return targetRPS
 	case "cycle":
-		elapsedSeconds := int(time.Since(hg.stats.StartTime).Seconds())
+		elapsedSeconds := int(time.Since(patternStart).Seconds())
 		cyclePosition := (elapsedSeconds / 30) % 4
 		
 		switch cyclePosition {
 		case 0:
-			return hg.targetRPS / 4
+			return targetRPS / 4
 		case 1:
-			return hg.targetRPS
+			return targetRPS
 		case 2:
-			return hg.targetRPS / 2
+			return targetRPS / 2
 		case 3:
-			return hg.targetRPS / 8
+			return targetRPS / 8
 		}
 	case "ramp":
-		elapsedMinutes := int(time.Since(hg.stats.StartTime).Minutes())
+		elapsedMinutes := int(time.Since(patternStart).Minutes())
 		rampMultiplier := float64(elapsedMinutes+1) * 0.2
 		if rampMultiplier > 1.0 {
 			rampMultiplier = 1.0
 		}
-		return int(float64(hg.targetRPS) * rampMultiplier)
+		return int(float64(targetRPS) * rampMultiplier)
 	case "random":
 		variation := rand.Intn(140) + 10
-		return (hg.targetRPS * variation) / 100
+		return (targetRPS * variation) / 100
 	default:
-		return hg.targetRPS
+		return targetRPS
 	}
-	return hg.targetRPS
+	return targetRPS
 }
 
 func (hg *HTTPGenerator) calculateRequestsToSend(currentRPS, requestsThisSecond int, tickInterval time.Duration) int {
@@ -220,89 +425,281 @@ func (hg *HTTPGenerator) calculateRequestsToSend(currentRPS, requestsThisSecond
 	return requestsToSend
 }
 
-func (hg *HTTPGenerator) httpWorker(workerID int) {
-	logger.Debug("HTTP worker %d started", workerID)
-	defer logger.Debug("HTTP worker %d stopped", workerID)
-
-	for {
-		select {
-		case <-hg.ctx.Done():
-			return
-		case <-hg.requestChan:
-			hg.makeRequest()
-		}
+func (hg *HTTPGenerator) makeRequest(intendedTime time.Time) {
+	if lag := waitForSchedule(hg.ctx, intendedTime); lag >= lateRequestThreshold {
+		atomic.AddInt64(&hg.stats.LateRequests, 1)
+		metrics.HTTPLateRequestsCounter.Inc()
 	}
-}
-
-func (hg *HTTPGenerator) makeRequest() {
+	
+	endpoint := hg.pickEndpoint()
+	tmpl := endpoint.template
+	capture := hg.captures.begin(tmpl.Name, tmpl.Method, tmpl.URL)
+	
+	timeout := hg.timeout
+	if tmpl.Timeout > 0 {
+		timeout = time.Duration(tmpl.Timeout)
+	}
+	ctx, cancel := context.WithTimeout(hg.ctx, timeout)
+	defer cancel()
+	ctx, trace := newRequestTrace(ctx)
+	
 	startTime := time.Now()
 	
-	var bodyReader io.Reader
-	if hg.body != "" {
-		bodyReader = bytes.NewReader([]byte(hg.body))
+	var data *TemplateData
+	if !endpoint.isStatic() {
+		var err error
+		data, err = hg.templates.NewData(nil)
+		if err != nil {
+			hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassData, err)
+			logger.Debug("Failed to get request data: %v", err)
+			return
+		}
 	}
 	
-	req, err := http.NewRequestWithContext(hg.ctx, hg.method, hg.targetURL, bodyReader)
+	targetURL, headers, body, err := endpoint.render(data)
 	if err != nil {
-		hg.recordFailure(time.Since(startTime))
-		logger.Debug("Failed to create request: %v", err)
+		hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassRequest, err)
+		logger.Debug("Failed to render request %s: %v", tmpl.Name, err)
 		return
 	}
 	
-	for key, value := range hg.headers {
-		req.Header.Set(key, value)
+	var resp *http.Response
+	var responseBody []byte
+	// Второй проход - только для APQ, когда сервер не знает хеш запроса.
+	for registered := false; ; registered = true {
+		var bodyReader io.Reader
+		if body != "" {
+			bodyReader = bytes.NewReader([]byte(body))
+		}
+		
+		req, err := http.NewRequestWithContext(ctx, tmpl.Method, targetURL, bodyReader)
+		if err != nil {
+			hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassRequest, err)
+			logger.Debug("Failed to create request: %v", err)
+			return
+		}
+		
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		// net/http не отправляет Host из заголовков, только req.Host.
+		if host := req.Header.Get("Host"); host != "" {
+			req.Host = host
+		}
+		
+		if req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", "StressPulse/1.0")
+		}
+		
+		if hg.connOptions.DisableKeepAlive {
+			req.Close = true
+		} else if hg.connOptions.recyclesConnections() {
+			// Соединение становится известно только в GotConn; заголовок общий у копий запроса
+			// внутри транспорта, поэтому Connection: close ставится через него, а не через req.Close.
+			header := req.Header
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					if hg.connOptions.shouldRetire(info.Conn) {
+						header.Set("Connection", "close")
+					}
+				},
+			}))
+		}
+		
+		capture.setRequest(req, body)
+		if hg.auth != nil {
+			if err := hg.auth.Authorize(&AuthRequest{Method: req.Method, URL: req.URL, Host: req.Host, Header: req.Header, Body: []byte(body)}); err != nil {
+				hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassAuth, err)
+				logger.Debug("Failed to authorize request %s: %v", tmpl.Name, err)
+				return
+			}
+		}
+		
+		resp, err = hg.client.Do(req)
+		
+		if err != nil {
+			hg.recordTrace(trace)
+			errorClass := classifyHTTPError(err)
+			hg.recordFailure(endpoint, capture, startTime, intendedTime, errorClass, err)
+			logger.Debug("Request %s failed (%s): %v", tmpl.Name, errorClass, err)
+			return
+		}
+		
+		responseBody = nil
+		if endpoint.needsBody || capture != nil {
+			// Для сохранения хватает начала тела; байт сверх BodySize отмечает обрезку.
+			limit := int64(maxCheckBodySize)
+			if !endpoint.needsBody {
+				limit = int64(hg.captures.options.BodySize) + 1
+			}
+			responseBody, err = io.ReadAll(io.LimitReader(resp.Body, limit))
+			if err == nil {
+				_, err = io.Copy(io.Discard, resp.Body)
+			}
+		} else {
+			_, err = io.Copy(io.Discard, resp.Body)
+		}
+		resp.Body.Close()
+		// Ответ PersistedQueryNotFound - служебный: повтор с полным текстом считается тем же запросом.
+		if err == nil && endpoint.graphql != nil && endpoint.graphql.apq && !registered && endpoint.graphql.needsRegistration(responseBody) {
+			body, err = endpoint.graphql.fullBody.Render(data)
+			if err != nil {
+				hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassRequest, err)
+				logger.Debug("Failed to render request %s: %v", tmpl.Name, err)
+				return
+			}
+			continue
+		}
+		
+		hg.recordStatusCode(endpoint, resp.StatusCode)
+		hg.recordProtocol(resp.Proto)
+		if err == nil {
+			trace.finish()
+		}
+		hg.recordTrace(trace)
+		capture.setResponse(resp, responseBody)
+		if err != nil {
+			errorClass := classifyHTTPError(err)
+			if errorClass == ErrorClassOther || errorClass == ErrorClassReset {
+				errorClass = ErrorClassRead
+			}
+			hg.recordFailure(endpoint, capture, startTime, intendedTime, errorClass, err)
+			logger.Debug("Failed to read response body (%s): %v", errorClass, err)
+			return
+		}
+		break
 	}
 	
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", "StressPulse/1.0")
+	if !endpoint.hasStatusCheck && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
+		hg.recordFailure(endpoint, capture, startTime, intendedTime, classifyHTTPStatus(resp.StatusCode), nil)
+		logger.Debug("Request %s failed with status: %d", tmpl.Name, resp.StatusCode)
+		return
 	}
 	
-	resp, err := hg.client.Do(req)
-	responseTime := time.Since(startTime)
+	if endpoint.graphql != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := endpoint.graphql.check(responseBody); err != nil {
+			hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassGraphQL, err)
+			logger.Debug("Request %s failed: %v", tmpl.Name, err)
+			return
+		}
+	}
 	
-	if err != nil {
-		hg.recordFailure(responseTime)
-		logger.Debug("Request failed: %v", err)
-		return
+	if len(endpoint.checks) > 0 {
+		if err := hg.checks.run(endpoint.template.Name, endpoint.checks, resp, responseBody, time.Since(startTime)); err != nil {
+			hg.recordFailure(endpoint, capture, startTime, intendedTime, ErrorClassCheck, err)
+			return
+		}
 	}
 	
-	defer resp.Body.Close()
+	hg.recordSuccess(endpoint, capture, startTime, intendedTime)
+}
+
+
+func (hg *HTTPGenerator) recordStatusCode(endpoint *httpEndpoint, statusCode int) {
+	endpoint.recordStatusCode(statusCode)
 	
-	_, err = io.Copy(io.Discard, resp.Body)
-	if err != nil {
-		logger.Debug("Failed to read response body: %v", err)
-	}
+	hg.stats.mutex.Lock()
+	hg.stats.StatusCodes[statusCode]++
+	hg.stats.mutex.Unlock()
 	
-	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-		hg.recordSuccess(responseTime)
-	} else {
-		hg.recordFailure(responseTime)
-		logger.Debug("Request failed with status: %d", resp.StatusCode)
-	}
+	metrics.HTTPStatusCodesCounter.WithLabelValues(strconv.Itoa(statusCode)).Inc()
 }
 
-func (hg *HTTPGenerator) recordSuccess(responseTime time.Duration) {
+// recordProtocol считает ответы по согласованному протоколу (HTTP/1.1, HTTP/2.0).
+func (hg *HTTPGenerator) recordProtocol(protocol string) {
+	hg.stats.mutex.Lock()
+	hg.stats.Protocols[protocol]++
+	hg.stats.mutex.Unlock()
+	
+	metrics.HTTPProtocolCounter.WithLabelValues(protocol).Inc()
+}
+
+func (hg *HTTPGenerator) recordSuccess(endpoint *httpEndpoint, capture *requestCapture, startTime, intendedTime time.Time) {
+	serviceTime := time.Since(startTime)
+	responseTime := time.Since(intendedTime)
+	if hg.captures.slow(serviceTime) {
+		capture.finish(CaptureSlow, "", nil, serviceTime, responseTime)
+	}
+	
 	atomic.AddInt64(&hg.stats.TotalRequests, 1)
 	atomic.AddInt64(&hg.stats.SuccessRequests, 1)
 	
 	hg.stats.mutex.Lock()
-	hg.stats.TotalResponseTime += responseTime
-	if responseTime < hg.stats.MinResponseTime {
-		hg.stats.MinResponseTime = responseTime
+	hg.stats.TotalResponseTime += serviceTime
+	if serviceTime < hg.stats.MinResponseTime {
+		hg.stats.MinResponseTime = serviceTime
 	}
-	if responseTime > hg.stats.MaxResponseTime {
-		hg.stats.MaxResponseTime = responseTime
+	if serviceTime > hg.stats.MaxResponseTime {
+		hg.stats.MaxResponseTime = serviceTime
 	}
 	hg.stats.mutex.Unlock()
+	
+	endpoint.recordResult(true, serviceTime, responseTime, "")
+	metrics.HTTPEndpointRequestsCounter.WithLabelValues(endpoint.template.Name, "success").Inc()
+	metrics.HTTPEndpointResponseTimeHistogram.WithLabelValues(endpoint.template.Name).Observe(responseTime.Seconds())
+	hg.recordLatency(serviceTime, responseTime)
 }
 
-func (hg *HTTPGenerator) recordFailure(responseTime time.Duration) {
+// recordFailure учитывает провал; capture и err - для хранилища сохранённых запросов.
+func (hg *HTTPGenerator) recordFailure(endpoint *httpEndpoint, capture *requestCapture, startTime, intendedTime time.Time, errorClass string, err error) {
+	serviceTime := time.Since(startTime)
+	responseTime := time.Since(intendedTime)
+	capture.finish(CaptureFailed, errorClass, err, serviceTime, responseTime)
+	
 	atomic.AddInt64(&hg.stats.TotalRequests, 1)
 	atomic.AddInt64(&hg.stats.FailedRequests, 1)
 	
 	hg.stats.mutex.Lock()
-	hg.stats.TotalResponseTime += responseTime
+	hg.stats.TotalResponseTime += serviceTime
+	hg.stats.ErrorClasses[errorClass]++
 	hg.stats.mutex.Unlock()
+	
+	metrics.HTTPErrorClassesCounter.WithLabelValues(errorClass).Inc()
+	
+	endpoint.recordResult(false, serviceTime, responseTime, errorClass)
+	metrics.HTTPEndpointRequestsCounter.WithLabelValues(endpoint.template.Name, "failed").Inc()
+	metrics.HTTPEndpointResponseTimeHistogram.WithLabelValues(endpoint.template.Name).Observe(responseTime.Seconds())
+	hg.recordLatency(serviceTime, responseTime)
+}
+
+// recordLatency пишет время обслуживания (от фактической отправки) и время ответа
+// с точки зрения пользователя (от запланированной отправки, без coordinated omission).
+func (hg *HTTPGenerator) recordLatency(serviceTime, responseTime time.Duration) {
+	hg.stats.serviceTime.Record(serviceTime)
+	hg.stats.latency.Record(responseTime)
+	metrics.HTTPServiceTimeHistogram.Observe(serviceTime.Seconds())
+	metrics.HTTPResponseTimeHistogram.Observe(responseTime.Seconds())
+}
+
+// recordTrace пишет длительности фаз запроса, тип соединения (новое или переиспользованное)
+// и IP, на который ушёл запрос.
+func (hg *HTTPGenerator) recordTrace(trace *requestTrace) {
+	if gotConn, reused, address := trace.connection(); gotConn {
+		if address != "" {
+			hg.stats.mutex.Lock()
+			hg.stats.Addresses[address]++
+			hg.stats.mutex.Unlock()
+			metrics.HTTPRequestsByAddressCounter.WithLabelValues(address).Inc()
+		}
+		if reused {
+			atomic.AddInt64(&hg.stats.ReusedConnections, 1)
+			metrics.HTTPConnectionsCounter.WithLabelValues("reused").Inc()
+		} else {
+			atomic.AddInt64(&hg.stats.NewConnections, 1)
+			metrics.HTTPConnectionsCounter.WithLabelValues("new").Inc()
+		}
+	}
+
+	for phase, duration := range trace.phases() {
+		hg.stats.phases[phase].Record(duration)
+		metrics.HTTPPhaseDurationHistogram.WithLabelValues(phase).Observe(duration.Seconds())
+	}
+}
+
+// recordProxyConnect пишет время установки соединения через прокси как отдельную фазу.
+func (hg *HTTPGenerator) recordProxyConnect(duration time.Duration) {
+	hg.stats.phases[PhaseProxy].Record(duration)
+	metrics.HTTPPhaseDurationHistogram.WithLabelValues(PhaseProxy).Observe(duration.Seconds())
 }
 
 func (hg *HTTPGenerator) statsCollector() {
@@ -320,6 +717,7 @@ func (hg *HTTPGenerator) statsCollector() {
 			currentRPS := currentRequests - lastRequests
 			atomic.StoreInt64(&hg.stats.CurrentRPS, currentRPS)
 			lastRequests = currentRequests
+			hg.workers.checkBottleneck(atomic.LoadInt64(&hg.stats.DroppedRequests), atomic.LoadInt64(&hg.stats.LateRequests))
 		}
 	}
 }
@@ -333,18 +731,79 @@ func (hg *HTTPGenerator) GetStats() *HTTPStats {
 	failed := atomic.LoadInt64(&hg.stats.FailedRequests)
 	currentRPS := atomic.LoadInt64(&hg.stats.CurrentRPS)
 	
+	statusCodes := make(map[int]int64)
+	for code, count := range hg.stats.StatusCodes {
+		statusCodes[code] = count
+	}
+	
+	errorClasses := make(map[string]int64)
+	for class, count := range hg.stats.ErrorClasses {
+		errorClasses[class] = count
+	}
+	
+	protocols := make(map[string]int64)
+	for protocol, count := range hg.stats.Protocols {
+		protocols[protocol] = count
+	}
+	
+	addresses := make(map[string]int64)
+	for address, count := range hg.stats.Addresses {
+		addresses[address] = count
+	}
+	
+	checks, checkFailures := hg.checks.stats()
+	
+	endpoints := make([]EndpointStats, 0, len(hg.endpoints))
+	for _, endpoint := range hg.endpoints {
+		endpoints = append(endpoints, endpoint.snapshot())
+	}
+	
+	phases := make(map[string]LatencyPercentiles, len(hg.stats.phases))
+	for phase, histogram := range hg.stats.phases {
+		phases[phase] = histogram.Percentiles()
+	}
+	
+	newConnections := atomic.LoadInt64(&hg.stats.NewConnections)
+	reusedConnections := atomic.LoadInt64(&hg.stats.ReusedConnections)
+	connectionReuse := 0.0
+	if newConnections+reusedConnections > 0 {
+		connectionReuse = float64(reusedConnections) / float64(newConnections+reusedConnections)
+	}
+	
 	return &HTTPStats{
 		TotalRequests:     total,
 		SuccessRequests:   success,
 		FailedRequests:    failed,
 		CurrentRPS:        currentRPS,
+		DroppedRequests:   atomic.LoadInt64(&hg.stats.DroppedRequests),
+		LateRequests:      atomic.LoadInt64(&hg.stats.LateRequests),
+		Workers:           hg.workers.stats(),
+		NewConnections:    newConnections,
+		ReusedConnections: reusedConnections,
+		ConnectionReuse:   connectionReuse,
+		BytesSent:         atomic.LoadInt64(&hg.stats.BytesSent),
+		BytesReceived:     atomic.LoadInt64(&hg.stats.BytesReceived),
 		TotalResponseTime: hg.stats.TotalResponseTime,
 		MinResponseTime:   hg.stats.MinResponseTime,
 		MaxResponseTime:   hg.stats.MaxResponseTime,
 		StartTime:         hg.stats.StartTime,
+		StatusCodes:       statusCodes,
+		ErrorClasses:      errorClasses,
+		Endpoints:         endpoints,
+		Checks:            checks,
+		CheckFailures:     checkFailures,
+		Latency:           hg.stats.latency.Percentiles(),
+		ServiceTime:       hg.stats.serviceTime.Percentiles(),
+		Phases:            phases,
+		Protocols:         protocols,
+		Addresses:         addresses,
 	}
 }
 
+func (hg *HTTPGenerator) GetLatencyHistogram() *LatencyHistogram {
+	return hg.stats.latency.Snapshot()
+}
+
 func (hg *HTTPGenerator) GetAverageResponseTime() time.Duration {
 	stats := hg.GetStats()
 	if stats.TotalRequests == 0 {
@@ -376,5 +835,6 @@ func CreateRequestTemplate(method string, headers map[string]string, bodyData in
 		Method:  method,
 		Headers: headers,
 		Body:    body,
+		Weight:  1,
 	}, nil
 } 
\ No newline at end of file
@@ -0,0 +1,175 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/metrics"
+)
+
+// Фазы HTTP запроса. Все, кроме proxy, измеряются через httptrace; proxy - установку туннеля
+// через прокси - пишет dialer прокси при открытии соединения.
+const (
+	PhaseDNS      = "dns"
+	PhaseConnect  = "connect"
+	PhaseTLS      = "tls"
+	PhaseProxy    = "proxy"
+	PhaseTTFB     = "ttfb"
+	PhaseTransfer = "transfer"
+)
+
+var httpPhases = []string{PhaseDNS, PhaseConnect, PhaseProxy, PhaseTLS, PhaseTTFB, PhaseTransfer}
+
+// requestTrace собирает моменты начала и конца фаз одного запроса.
+// Колбэки httptrace могут приходить из разных горутин (например, при параллельном dial), поэтому под мьютексом.
+type requestTrace struct {
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+	gotConn      bool
+	reused       bool
+	remoteIP     string
+	mutex        sync.Mutex
+}
+
+func newRequestTrace(ctx context.Context) (context.Context, *requestTrace) {
+	t := &requestTrace{}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mark(&t.dnsDone)
+		},
+		ConnectStart: func(string, string) {
+			t.markFirst(&t.connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.mark(&t.tlsDone)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			t.gotConn = true
+			t.reused = info.Reused
+			t.remoteIP = remoteIP(info.Conn.RemoteAddr())
+			t.mutex.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *requestTrace) mark(field *time.Time) {
+	t.mutex.Lock()
+	*field = time.Now()
+	t.mutex.Unlock()
+}
+
+func (t *requestTrace) markFirst(field *time.Time) {
+	t.mutex.Lock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+	t.mutex.Unlock()
+}
+
+// finish отмечает конец чтения тела ответа.
+func (t *requestTrace) finish() {
+	t.mark(&t.bodyDone)
+}
+
+// phases возвращает длительности завершившихся фаз. Для переиспользованного соединения
+// dns, connect и tls отсутствуют.
+func (t *requestTrace) phases() map[string]time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	phases := make(map[string]time.Duration, len(httpPhases))
+	addPhase := func(name string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() && !end.Before(start) {
+			phases[name] = end.Sub(start)
+		}
+	}
+
+	addPhase(PhaseDNS, t.dnsStart, t.dnsDone)
+	addPhase(PhaseConnect, t.connectStart, t.connectDone)
+	addPhase(PhaseTLS, t.tlsStart, t.tlsDone)
+	addPhase(PhaseTTFB, t.wroteRequest, t.firstByte)
+	addPhase(PhaseTransfer, t.firstByte, t.bodyDone)
+	return phases
+}
+
+func (t *requestTrace) connection() (gotConn, reused bool, address string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.gotConn, t.reused, t.remoteIP
+}
+
+// countingConn считает байты, реально отправленные и полученные по соединению (с заголовками и TLS).
+// opened и requests нужны политике пересоздания соединений (HTTPConnectionOptions).
+type countingConn struct {
+	net.Conn
+	sent     *int64
+	received *int64
+	requests int64
+	opened   time.Time
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		atomic.AddInt64(c.received, int64(n))
+		metrics.HTTPBytesReceivedCounter.Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		atomic.AddInt64(c.sent, int64(n))
+		metrics.HTTPBytesSentCounter.Add(float64(n))
+	}
+	return n, err
+}
+
+// countingDialer оборачивает соединения в countingConn; source задаёт локальные адреса (nil - любой).
+func countingDialer(source *SourceBinder, sent, received *int64) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := source.dialer(&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, sent: sent, received: received, opened: time.Now()}, nil
+	}
+}
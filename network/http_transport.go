@@ -0,0 +1,342 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Протоколы HTTP генератора. auto - HTTP/2 через ALPN для https и HTTP/1.1 для http,
+// h2 - только HTTP/2 поверх TLS, h2c - HTTP/2 без TLS (prior knowledge).
+const (
+	HTTPProtocolAuto = "auto"
+	HTTPProtocolH1   = "h1"
+	HTTPProtocolH2   = "h2"
+	HTTPProtocolH2C  = "h2c"
+)
+
+var httpProtocols = []string{HTTPProtocolAuto, HTTPProtocolH1, HTTPProtocolH2, HTTPProtocolH2C}
+
+const (
+	defaultMaxIdleConns        = 200
+	defaultMaxIdleConnsPerHost = 50
+	httpIdleConnTimeout        = 30 * time.Second
+)
+
+// HTTPConnectionOptions - протокол и политика соединений HTTP генератора. Нулевые значения
+// означают поведение по умолчанию: auto, keep-alive, без ограничений.
+type HTTPConnectionOptions struct {
+	Protocol           string   `json:"protocol,omitempty"`
+	DisableKeepAlive   bool     `json:"disableKeepAlive,omitempty"`
+	MaxConnsPerHost    int      `json:"maxConnsPerHost,omitempty"`
+	MaxConnLifetime    Duration `json:"maxConnLifetime,omitempty"`
+	MaxRequestsPerConn int      `json:"maxRequestsPerConn,omitempty"`
+	// MaxStreamsPerConn ограничивает одновременные запросы в одном HTTP/2 соединении.
+	// HTTP/1.1 клиент Go не использует pipelining, там в соединении всегда один запрос.
+	MaxStreamsPerConn int `json:"maxStreamsPerConn,omitempty"`
+}
+
+func (o *HTTPConnectionOptions) protocol() string {
+	if o.Protocol == "" {
+		return HTTPProtocolAuto
+	}
+	return o.Protocol
+}
+
+func (o *HTTPConnectionOptions) Validate() error {
+	valid := false
+	for _, protocol := range httpProtocols {
+		if o.protocol() == protocol {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid HTTP protocol %q, expected %s", o.Protocol, strings.Join(httpProtocols, ", "))
+	}
+	if o.MaxConnsPerHost < 0 || o.MaxConnLifetime < 0 || o.MaxRequestsPerConn < 0 || o.MaxStreamsPerConn < 0 {
+		return fmt.Errorf("HTTP connection limits must be non-negative")
+	}
+	if o.MaxStreamsPerConn > 0 && o.protocol() != HTTPProtocolH2 && o.protocol() != HTTPProtocolH2C {
+		return fmt.Errorf("max streams per connection requires protocol h2 or h2c")
+	}
+	return nil
+}
+
+// Describe кратко описывает настройки для логов.
+func (o *HTTPConnectionOptions) Describe() string {
+	parts := []string{"protocol=" + o.protocol(), fmt.Sprintf("keep-alive=%t", !o.DisableKeepAlive)}
+	if o.MaxConnsPerHost > 0 {
+		parts = append(parts, fmt.Sprintf("max conns/host=%d", o.MaxConnsPerHost))
+	}
+	if o.MaxConnLifetime > 0 {
+		parts = append(parts, "conn lifetime="+time.Duration(o.MaxConnLifetime).String())
+	}
+	if o.MaxRequestsPerConn > 0 {
+		parts = append(parts, fmt.Sprintf("max requests/conn=%d", o.MaxRequestsPerConn))
+	}
+	if o.MaxStreamsPerConn > 0 {
+		parts = append(parts, fmt.Sprintf("max streams/conn=%d", o.MaxStreamsPerConn))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// recyclesConnections - нужно ли следить за возрастом и числом запросов соединений.
+func (o *HTTPConnectionOptions) recyclesConnections() bool {
+	return o.MaxConnLifetime > 0 || o.MaxRequestsPerConn > 0
+}
+
+// shouldRetire считает запрос на выданном соединении и сообщает, что оно отслужило своё:
+// запрос уйдёт с Connection: close, и после ответа соединение закроется.
+func (o *HTTPConnectionOptions) shouldRetire(conn net.Conn) bool {
+	counted := unwrapCountingConn(conn)
+	if counted == nil {
+		return false
+	}
+	requests := atomic.AddInt64(&counted.requests, 1)
+	if o.MaxRequestsPerConn > 0 && requests >= int64(o.MaxRequestsPerConn) {
+		return true
+	}
+	return o.MaxConnLifetime > 0 && time.Since(counted.opened) >= time.Duration(o.MaxConnLifetime)
+}
+
+// unwrapCountingConn достаёт countingConn из-под TLS обёртки.
+func unwrapCountingConn(conn net.Conn) *countingConn {
+	for {
+		switch c := conn.(type) {
+		case *countingConn:
+			return c
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil
+		}
+	}
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newTransport собирает транспорт под выбранный протокол. maxIdlePerHost поднимается до предела
+// конкурентности, чтобы соединения не закрывались между всплесками запросов.
+func (o *HTTPConnectionOptions) newTransport(tlsConfig *tls.Config, dial dialFunc, maxIdlePerHost int) http.RoundTripper {
+	switch o.protocol() {
+	case HTTPProtocolH2, HTTPProtocolH2C:
+		pool := &http2ConnPool{
+			tlsConfig:       tlsConfig,
+			dial:            dial,
+			cleartext:       o.protocol() == HTTPProtocolH2C,
+			maxConnsPerHost: o.MaxConnsPerHost,
+			maxStreams:      o.MaxStreamsPerConn,
+			conns:           make(map[string][]*http2.ClientConn),
+			dialing:         make(map[string]int),
+		}
+		pool.dialed = sync.NewCond(&pool.mutex)
+		pool.transport = &http2.Transport{
+			AllowHTTP:       pool.cleartext,
+			TLSClientConfig: tlsConfig,
+			ConnPool:        pool,
+		}
+		return &http2RoundTripper{Transport: pool.transport, pool: pool}
+	}
+
+	if maxIdlePerHost < defaultMaxIdleConnsPerHost {
+		maxIdlePerHost = defaultMaxIdleConnsPerHost
+	}
+	maxIdle := defaultMaxIdleConns
+	if maxIdle < maxIdlePerHost {
+		maxIdle = maxIdlePerHost
+	}
+
+	transport := &http.Transport{
+		DialContext:         dial,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   o.protocol() == HTTPProtocolAuto,
+		MaxIdleConns:        maxIdle,
+		MaxIdleConnsPerHost: maxIdlePerHost,
+		MaxConnsPerHost:     o.MaxConnsPerHost,
+		IdleConnTimeout:     httpIdleConnTimeout,
+		DisableKeepAlives:   o.DisableKeepAlive,
+	}
+	if o.protocol() == HTTPProtocolH1 {
+		// Непустая карта без "h2" отключает HTTP/2 в стандартном транспорте.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return transport
+}
+
+// http2RoundTripper добавляет http2.Transport закрытие простаивающих соединений собственного пула.
+type http2RoundTripper struct {
+	*http2.Transport
+	pool *http2ConnPool
+}
+
+func (t *http2RoundTripper) CloseIdleConnections() {
+	t.pool.closeIdle()
+}
+
+// http2ConnPool - пул HTTP/2 соединений с пределами на число соединений к хосту и потоков в соединении.
+// Стандартный пул x/net держит одно соединение на хост и открывает новое, только когда сервер
+// не даёт больше потоков.
+type http2ConnPool struct {
+	transport       *http2.Transport
+	tlsConfig       *tls.Config
+	dial            dialFunc
+	cleartext       bool
+	maxConnsPerHost int
+	maxStreams      int
+
+	mutex   sync.Mutex
+	dialed  *sync.Cond
+	conns   map[string][]*http2.ClientConn
+	dialing map[string]int
+}
+
+func (p *http2ConnPool) GetClientConn(req *http.Request, addr string) (*http2.ClientConn, error) {
+	// Запрос с Connection: close получает своё соединение, которое закроется после ответа.
+	if req.Close {
+		return p.dialConn(req.Context(), addr)
+	}
+
+	p.mutex.Lock()
+	for {
+		conns := p.liveConns(addr)
+
+		var leastLoaded *http2.ClientConn
+		leastLoad := 0
+		for _, cc := range conns {
+			state := cc.State()
+			load := state.StreamsActive + state.StreamsReserved
+			if p.maxStreams > 0 && load >= p.maxStreams {
+				if leastLoaded == nil || load < leastLoad {
+					leastLoaded, leastLoad = cc, load
+				}
+				continue
+			}
+			if cc.ReserveNewRequest() {
+				p.mutex.Unlock()
+				return cc, nil
+			}
+		}
+
+		atLimit := p.maxConnsPerHost > 0 && len(conns)+p.dialing[addr] >= p.maxConnsPerHost
+		if !atLimit {
+			break
+		}
+		// На пределе соединений запрос уходит в наименее загруженное, даже сверх предела потоков.
+		if leastLoaded != nil && leastLoaded.ReserveNewRequest() {
+			p.mutex.Unlock()
+			return leastLoaded, nil
+		}
+		if p.dialing[addr] == 0 {
+			break
+		}
+		p.dialed.Wait()
+	}
+	p.dialing[addr]++
+	p.mutex.Unlock()
+
+	cc, err := p.dialConn(req.Context(), addr)
+
+	p.mutex.Lock()
+	p.dialing[addr]--
+	if err == nil {
+		p.conns[addr] = append(p.conns[addr], cc)
+		cc.ReserveNewRequest()
+	}
+	p.dialed.Broadcast()
+	p.mutex.Unlock()
+
+	return cc, err
+}
+
+func (p *http2ConnPool) MarkDead(cc *http2.ClientConn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for addr, conns := range p.conns {
+		for i, candidate := range conns {
+			if candidate == cc {
+				p.conns[addr] = append(conns[:i], conns[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// liveConns убирает закрытые и закрывающиеся соединения. Вызывается под mutex.
+func (p *http2ConnPool) liveConns(addr string) []*http2.ClientConn {
+	conns := p.conns[addr]
+	live := conns[:0]
+	for _, cc := range conns {
+		if state := cc.State(); !state.Closed && !state.Closing {
+			live = append(live, cc)
+		}
+	}
+	p.conns[addr] = live
+	return live
+}
+
+func (p *http2ConnPool) closeIdle() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for addr := range p.conns {
+		for _, cc := range p.liveConns(addr) {
+			if state := cc.State(); state.StreamsActive == 0 && state.StreamsReserved == 0 {
+				cc.Close()
+			}
+		}
+		p.liveConns(addr)
+	}
+}
+
+// dialConn открывает TCP (и для h2 TLS с ALPN h2) соединение, сообщая фазы в httptrace запроса.
+func (p *http2ConnPool) dialConn(ctx context.Context, addr string) (*http2.ClientConn, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+
+	// ConnectStart и ConnectDone вызывает net.Dialer по трассировке из ctx.
+	conn, err := p.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.cleartext {
+		host, _, _ := net.SplitHostPort(addr)
+		config := tlsConfigForHost(p.tlsConfig, host)
+		config.NextProtos = []string{http2.NextProtoTLS}
+
+		tlsConn := tls.Client(conn, config)
+		if trace != nil && trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		err := tlsConn.HandshakeContext(ctx)
+		if trace != nil && trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if protocol := tlsConn.ConnectionState().NegotiatedProtocol; protocol != http2.NextProtoTLS {
+			tlsConn.Close()
+			return nil, fmt.Errorf("server %s did not negotiate HTTP/2 (ALPN %q)", addr, protocol)
+		}
+		conn = tlsConn
+	}
+
+	cc, err := p.transport.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return cc, nil
+}
@@ -0,0 +1,287 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaTypes - поле type схемы: строка в OpenAPI 3.0 или список в 3.1 ("null" в списке разрешает null).
+type SchemaTypes []string
+
+func (t *SchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("schema type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (t SchemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t SchemaTypes) has(name string) bool {
+	for _, item := range t {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// primary возвращает первый тип, кроме null.
+func (t SchemaTypes) primary() string {
+	for _, item := range t {
+		if item != "null" {
+			return item
+		}
+	}
+	return ""
+}
+
+// Schema - подмножество JSON Schema из OpenAPI 3, которого хватает для генерации запросов
+// и проверки ответов. Ref разрешается при импорте спецификации, в проверках схемы уже без ссылок.
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       SchemaTypes        `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Example    interface{}        `json:"example,omitempty"`
+	Default    interface{}        `json:"default,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	ReadOnly   bool               `json:"readOnly,omitempty"`
+	WriteOnly  bool               `json:"writeOnly,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	AllOf      []*Schema          `json:"allOf,omitempty"`
+	OneOf      []*Schema          `json:"oneOf,omitempty"`
+	AnyOf      []*Schema          `json:"anyOf,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	MinItems   *int               `json:"minItems,omitempty"`
+	MaxItems   *int               `json:"maxItems,omitempty"`
+	Pattern    string             `json:"pattern,omitempty"`
+	pattern    *regexp.Regexp
+}
+
+// compile готовит регулярные выражения pattern во всём дереве схемы.
+func (s *Schema) compile() error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		return fmt.Errorf("unresolved schema reference %s", s.Ref)
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema pattern %q: %v", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+
+	children := append([]*Schema{s.Items}, s.AllOf...)
+	children = append(children, s.OneOf...)
+	children = append(children, s.AnyOf...)
+	for _, property := range s.Properties {
+		children = append(children, property)
+	}
+	for _, child := range children {
+		if err := child.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate проверяет значение из encoding/json и возвращает первое найденное расхождение
+// или пустую строку. oneOf проверяется как anyOf: достаточно совпасть с одним вариантом.
+func (s *Schema) validate(value interface{}, path string) string {
+	if s == nil {
+		return ""
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type.has("null") || len(s.Type) == 0 {
+			return ""
+		}
+		return path + " is null"
+	}
+
+	for _, sub := range s.AllOf {
+		if message := sub.validate(value, path); message != "" {
+			return message
+		}
+	}
+	for _, variants := range [][]*Schema{s.AnyOf, s.OneOf} {
+		if len(variants) == 0 {
+			continue
+		}
+		first := ""
+		matched := false
+		for _, sub := range variants {
+			message := sub.validate(value, path)
+			if message == "" {
+				matched = true
+				break
+			}
+			if first == "" {
+				first = message
+			}
+		}
+		if !matched {
+			return first
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if jsonValuesEqual(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("%s is %v, not one of enum values", path, value)
+		}
+	}
+
+	if len(s.Type) > 0 {
+		matched := false
+		for _, name := range s.Type {
+			if schemaTypeMatches(name, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("%s is %s, expected %s", path, jsonTypeName(value), strings.Join(s.Type, " or "))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				if property := s.Properties[name]; property != nil && property.WriteOnly {
+					continue
+				}
+				return fmt.Sprintf("%s.%s is required", path, name)
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if item, ok := v[name]; ok {
+				if message := s.Properties[name].validate(item, path+"."+name); message != "" {
+					return message
+				}
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Sprintf("%s has %d items, minimum is %d", path, len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Sprintf("%s has %d items, maximum is %d", path, len(v), *s.MaxItems)
+		}
+		for i, item := range v {
+			if message := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); message != "" {
+				return message
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fmt.Sprintf("%s is shorter than %d characters", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fmt.Sprintf("%s is longer than %d characters", path, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fmt.Sprintf("%s does not match pattern %s", path, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Sprintf("%s is %v, minimum is %v", path, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Sprintf("%s is %v, maximum is %v", path, v, *s.Maximum)
+		}
+	}
+
+	return ""
+}
+
+func schemaTypeMatches(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// schemaForStatus выбирает схему ответа по коду: точный код, затем диапазон вида 2XX, затем default.
+func schemaForStatus(schemas map[string]*Schema, status int) (*Schema, bool) {
+	for _, key := range []string{fmt.Sprint(status), fmt.Sprintf("%dXX", status/100), "default"} {
+		if schema, ok := schemas[key]; ok {
+			return schema, true
+		}
+	}
+	return nil, false
+}
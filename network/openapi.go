@@ -0,0 +1,724 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"stresspulse/logger"
+)
+
+const (
+	// Глубина вложенности схем при разворачивании $ref; глубже схема считается любой (для рекурсивных типов).
+	openAPIMaxDepth = 8
+	// Расширение операции со своим весом в наборе запросов.
+	openAPIWeightExtension = "x-stresspulse-weight"
+)
+
+var (
+	openAPIMethods       = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	openAPIPathParameter = regexp.MustCompile(`\{([^{}]+)\}`)
+)
+
+// OpenAPIOptions - какие операции брать из спецификации и куда отправлять запросы.
+// BaseURL заменяет схему и хост servers[0]; если в BaseURL есть путь, он заменяет и путь сервера.
+// Операция выбирается, если совпал любой тег из Tags или operationId из Operations; без фильтров берутся все.
+type OpenAPIOptions struct {
+	BaseURL    string   `json:"baseUrl,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Operations []string `json:"operations,omitempty"`
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Swagger    string                                `json:"swagger"`
+	Servers    []openAPIServer                       `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas       map[string]*Schema             `json:"schemas"`
+		Parameters    map[string]*openAPIParameter   `json:"parameters"`
+		RequestBodies map[string]*openAPIRequestBody `json:"requestBodies"`
+		Responses     map[string]*openAPIResponse    `json:"responses"`
+		Examples      map[string]*openAPIExample     `json:"examples"`
+	} `json:"components"`
+}
+
+type openAPIServer struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []*openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Weight      int                         `json:"x-stresspulse-weight"`
+}
+
+type openAPIParameter struct {
+	Ref      string                     `json:"$ref"`
+	Name     string                     `json:"name"`
+	In       string                     `json:"in"`
+	Required bool                       `json:"required"`
+	Schema   *Schema                    `json:"schema"`
+	Example  interface{}                `json:"example"`
+	Examples map[string]*openAPIExample `json:"examples"`
+}
+
+type openAPIRequestBody struct {
+	Ref      string                       `json:"$ref"`
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Ref     string                       `json:"$ref"`
+	Content map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema   *Schema                    `json:"schema"`
+	Example  interface{}                `json:"example"`
+	Examples map[string]*openAPIExample `json:"examples"`
+}
+
+type openAPIExample struct {
+	Ref   string      `json:"$ref"`
+	Value interface{} `json:"value"`
+}
+
+type openAPIBuilder struct {
+	doc     *openAPIDocument
+	baseURL string
+}
+
+// LoadOpenAPIRequests читает спецификацию OpenAPI 3 в JSON и строит по ней набор запросов.
+func LoadOpenAPIRequests(path string, options *OpenAPIOptions) ([]*RequestTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI spec: %v", err)
+	}
+	return BuildOpenAPIRequests(data, options)
+}
+
+// BuildOpenAPIRequests превращает операции спецификации во взвешенные шаблоны запросов для HTTPGenerator.
+// Параметры и JSON тела берутся из примеров, а без них синтезируются по схемам через функции шаблонов
+// (randInt, uuid, seq, pick), так что значения меняются от запроса к запросу. Каждый запрос получает
+// проверку объявленных кодов ответа и проверку тела по схеме ответа.
+func BuildOpenAPIRequests(data []byte, options *OpenAPIOptions) ([]*RequestTemplate, error) {
+	if options == nil {
+		options = &OpenAPIOptions{}
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, fmt.Errorf("OpenAPI spec must be JSON, convert YAML specs first")
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %v", err)
+	}
+	if doc.Swagger != "" || !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("only OpenAPI 3.x specs are supported")
+	}
+	if len(doc.Paths) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec contains no paths")
+	}
+
+	baseURL, err := openAPIBaseURL(doc.Servers, options.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	builder := &openAPIBuilder{doc: &doc, baseURL: baseURL}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var templates []*RequestTemplate
+	for _, path := range paths {
+		item := doc.Paths[path]
+
+		var shared []*openAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("%s: invalid parameters: %v", path, err)
+			}
+		}
+
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var operation openAPIOperation
+			if err := json.Unmarshal(raw, &operation); err != nil {
+				return nil, fmt.Errorf("%s %s: invalid operation: %v", strings.ToUpper(method), path, err)
+			}
+			if !options.selects(&operation) {
+				continue
+			}
+
+			tmpl, err := builder.operation(strings.ToUpper(method), path, shared, &operation)
+			if err != nil {
+				logger.Warning("OpenAPI: skipping %s %s: %v", strings.ToUpper(method), path, err)
+				continue
+			}
+			templates = append(templates, tmpl)
+		}
+	}
+
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no OpenAPI operations match the selected tags and operation IDs")
+	}
+	return templates, nil
+}
+
+func (o *OpenAPIOptions) selects(operation *openAPIOperation) bool {
+	if len(o.Tags) == 0 && len(o.Operations) == 0 {
+		return true
+	}
+	for _, id := range o.Operations {
+		if id == operation.OperationID {
+			return true
+		}
+	}
+	for _, tag := range o.Tags {
+		for _, operationTag := range operation.Tags {
+			if strings.EqualFold(tag, operationTag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// openAPIBaseURL подставляет переменные сервера по умолчанию и совмещает его с заданным адресом.
+func openAPIBaseURL(servers []openAPIServer, override string) (string, error) {
+	server := ""
+	if len(servers) > 0 {
+		server = servers[0].URL
+		for name, variable := range servers[0].Variables {
+			server = strings.ReplaceAll(server, "{"+name+"}", variable.Default)
+		}
+	}
+
+	if override == "" {
+		parsed, err := url.Parse(server)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return "", fmt.Errorf("OpenAPI server URL %q is not absolute, set a base URL", server)
+		}
+		return strings.TrimSuffix(server, "/"), nil
+	}
+
+	base, err := url.Parse(override)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("invalid OpenAPI base URL: %s", override)
+	}
+	if strings.Trim(base.Path, "/") != "" {
+		return strings.TrimSuffix(override, "/"), nil
+	}
+
+	serverPath := server
+	if parsed, err := url.Parse(server); err == nil && parsed.Host != "" {
+		serverPath = parsed.Path
+	}
+	return strings.TrimSuffix(base.Scheme+"://"+base.Host+"/"+strings.Trim(serverPath, "/"), "/"), nil
+}
+
+func (b *openAPIBuilder) operation(method, path string, shared []*openAPIParameter, operation *openAPIOperation) (*RequestTemplate, error) {
+	name := operation.OperationID
+	if name == "" {
+		name = method + " " + path
+	}
+	tmpl := &RequestTemplate{
+		Name:   name,
+		Method: method,
+		Weight: operation.Weight,
+	}
+	if tmpl.Weight < 0 {
+		return nil, fmt.Errorf("%s must be non-negative", openAPIWeightExtension)
+	}
+
+	// Параметры операции переопределяют параметры пути с тем же именем и местом.
+	parameters := make(map[string]*openAPIParameter)
+	var order []string
+	for _, parameter := range append(append([]*openAPIParameter{}, shared...), operation.Parameters...) {
+		resolved, err := b.parameter(parameter)
+		if err != nil {
+			return nil, err
+		}
+		key := resolved.In + ":" + resolved.Name
+		if _, ok := parameters[key]; !ok {
+			order = append(order, key)
+		}
+		parameters[key] = resolved
+	}
+
+	requestPath := path
+	var query []string
+	for _, key := range order {
+		parameter := parameters[key]
+		switch parameter.In {
+		case "path":
+			requestPath = strings.ReplaceAll(requestPath, "{"+parameter.Name+"}", b.parameterValue(parameter, url.PathEscape))
+		case "query":
+			if parameter.Required || b.hasExample(parameter) {
+				query = append(query, url.QueryEscape(parameter.Name)+"="+b.parameterValue(parameter, url.QueryEscape))
+			}
+		case "header":
+			if parameter.Required || b.hasExample(parameter) {
+				if tmpl.Headers == nil {
+					tmpl.Headers = make(map[string]string)
+				}
+				tmpl.Headers[parameter.Name] = b.parameterValue(parameter, func(value string) string { return value })
+			}
+		}
+	}
+	for _, match := range openAPIPathParameter.FindAllStringSubmatch(path, -1) {
+		if _, ok := parameters["path:"+match[1]]; !ok {
+			return nil, fmt.Errorf("path parameter %s is not declared", match[1])
+		}
+	}
+
+	tmpl.URL = b.baseURL + "/" + strings.TrimPrefix(requestPath, "/")
+	if len(query) > 0 {
+		tmpl.URL += "?" + strings.Join(query, "&")
+	}
+
+	if err := b.requestBody(tmpl, operation.RequestBody); err != nil {
+		return nil, err
+	}
+	tmpl.Checks = b.responseChecks(name, operation.Responses)
+	return tmpl, nil
+}
+
+func (b *openAPIBuilder) parameter(parameter *openAPIParameter) (*openAPIParameter, error) {
+	if parameter == nil {
+		return nil, fmt.Errorf("empty parameter")
+	}
+	if parameter.Ref != "" {
+		resolved := b.doc.Components.Parameters[strings.TrimPrefix(parameter.Ref, "#/components/parameters/")]
+		if !strings.HasPrefix(parameter.Ref, "#/components/parameters/") || resolved == nil {
+			return nil, fmt.Errorf("unresolved parameter reference %s", parameter.Ref)
+		}
+		parameter = resolved
+	}
+	copied := *parameter
+	copied.Schema = b.inline(parameter.Schema, 0)
+	return &copied, nil
+}
+
+// inline копирует схему, разворачивая $ref на components/schemas.
+func (b *openAPIBuilder) inline(schema *Schema, depth int) *Schema {
+	if schema == nil {
+		return nil
+	}
+	if depth > openAPIMaxDepth {
+		return &Schema{}
+	}
+	if schema.Ref != "" {
+		target := b.doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !strings.HasPrefix(schema.Ref, "#/components/schemas/") || target == nil {
+			logger.Warning("OpenAPI: unresolved schema reference %s, accepting any value", schema.Ref)
+			return &Schema{}
+		}
+		return b.inline(target, depth+1)
+	}
+
+	copied := *schema
+	copied.Items = b.inline(schema.Items, depth+1)
+	if schema.Properties != nil {
+		copied.Properties = make(map[string]*Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			copied.Properties[name] = b.inline(property, depth+1)
+		}
+	}
+	for _, list := range []*[]*Schema{&copied.AllOf, &copied.OneOf, &copied.AnyOf} {
+		if *list == nil {
+			continue
+		}
+		inlined := make([]*Schema, len(*list))
+		for i, sub := range *list {
+			inlined[i] = b.inline(sub, depth+1)
+		}
+		*list = inlined
+	}
+	return &copied
+}
+
+func (b *openAPIBuilder) example(value interface{}, examples map[string]*openAPIExample) (interface{}, bool) {
+	if value != nil {
+		return value, true
+	}
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		example := examples[name]
+		if example != nil && example.Ref != "" {
+			example = b.doc.Components.Examples[strings.TrimPrefix(example.Ref, "#/components/examples/")]
+		}
+		if example != nil && example.Value != nil {
+			return example.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (b *openAPIBuilder) hasExample(parameter *openAPIParameter) bool {
+	if _, ok := b.example(parameter.Example, parameter.Examples); ok {
+		return true
+	}
+	return parameter.Schema != nil && (parameter.Schema.Example != nil || parameter.Schema.Default != nil)
+}
+
+// parameterValue возвращает значение параметра для URL или заголовка; escape применяется к постоянным частям.
+func (b *openAPIBuilder) parameterValue(parameter *openAPIParameter, escape func(string) string) string {
+	if example, ok := b.example(parameter.Example, parameter.Examples); ok {
+		return escapeTemplateText(escape(scalarText(example)))
+	}
+	schema := parameter.Schema
+	if schema == nil {
+		return escape(parameter.Name) + "-{{seq}}"
+	}
+	if schema.Items != nil && schema.Type.primary() == "array" {
+		schema = schema.Items
+	}
+	value, _ := sampleScalar(schema, parameter.Name, escape)
+	return value
+}
+
+func scalarText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = scalarText(item)
+		}
+		return strings.Join(parts, ",")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// sampleScalar синтезирует значение простого типа. Возвращает текст шаблона и признак строки,
+// который нужен, чтобы взять значение в кавычки в JSON теле.
+func sampleScalar(schema *Schema, name string, escape func(string) string) (string, bool) {
+	if example := firstNonNil(schema.Example, schema.Default); example != nil {
+		_, isString := example.(string)
+		return escapeTemplateText(escape(scalarText(example))), isString
+	}
+
+	if len(schema.Enum) > 0 {
+		_, isString := schema.Enum[0].(string)
+		options := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			if value != nil {
+				options = append(options, strconv.Quote(escape(scalarText(value))))
+			}
+		}
+		if len(options) == 1 {
+			return escapeTemplateText(escape(scalarText(schema.Enum[0]))), isString
+		}
+		return "{{pick " + strings.Join(options, " ") + "}}", isString
+	}
+
+	switch schema.Type.primary() {
+	case "integer", "number":
+		low, high := 1.0, 1000.0
+		if schema.Minimum != nil {
+			low = *schema.Minimum
+			if schema.Maximum == nil {
+				high = low + 1000
+			}
+		}
+		if schema.Maximum != nil {
+			high = *schema.Maximum
+			if schema.Minimum == nil && high < low {
+				low = high - 1000
+			}
+		}
+		return fmt.Sprintf("{{randInt %d %d}}", int64(math.Ceil(low)), int64(math.Floor(high))), false
+	case "boolean":
+		return `{{pick "true" "false"}}`, false
+	}
+
+	switch schema.Format {
+	case "uuid":
+		return "{{uuid}}", true
+	case "date-time":
+		return escape("2026-01-01T00:00:00Z"), true
+	case "date":
+		return "2026-01-01", true
+	case "email":
+		return escape("user") + "{{seq}}" + escape("@example.com"), true
+	case "uri", "url":
+		return escape("https://example.com/") + "{{seq}}", true
+	case "byte":
+		return escape("c3RyZXNzcHVsc2U="), true
+	}
+
+	if schema.MaxLength != nil && *schema.MaxLength < len(name)+8 {
+		length := *schema.MaxLength
+		if schema.MinLength != nil && *schema.MinLength > 0 {
+			length = *schema.MinLength
+		}
+		if length < 1 {
+			length = 1
+		}
+		return strings.Repeat("x", length), true
+	}
+	return escapeTemplateText(escape(name)) + "-{{seq}}", true
+}
+
+func firstNonNil(values ...interface{}) interface{} {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// sampleJSON синтезирует JSON текст значения по схеме; readOnly поля в запрос не попадают.
+func sampleJSON(schema *Schema, name string, depth int) string {
+	if schema == nil || depth > openAPIMaxDepth {
+		return "null"
+	}
+	if example := firstNonNil(schema.Example, schema.Default); example != nil {
+		encoded, err := json.Marshal(example)
+		if err == nil {
+			return escapeTemplateText(string(encoded))
+		}
+	}
+
+	// allOf объектов сливается в один объект, иначе берётся первая часть.
+	if len(schema.AllOf) > 0 {
+		merged := &Schema{Type: SchemaTypes{"object"}, Properties: make(map[string]*Schema)}
+		for _, sub := range append([]*Schema{schema}, schema.AllOf...) {
+			for property, propertySchema := range sub.Properties {
+				merged.Properties[property] = propertySchema
+			}
+		}
+		if len(merged.Properties) == 0 {
+			return sampleJSON(schema.AllOf[0], name, depth+1)
+		}
+		return sampleJSON(merged, name, depth+1)
+	}
+	if len(schema.OneOf) > 0 {
+		return sampleJSON(schema.OneOf[0], name, depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return sampleJSON(schema.AnyOf[0], name, depth+1)
+	}
+
+	kind := schema.Type.primary()
+	if kind == "" && schema.Properties != nil {
+		kind = "object"
+	}
+
+	switch kind {
+	case "object":
+		names := make([]string, 0, len(schema.Properties))
+		for property, propertySchema := range schema.Properties {
+			if propertySchema != nil && !propertySchema.ReadOnly {
+				names = append(names, property)
+			}
+		}
+		sort.Strings(names)
+		fields := make([]string, 0, len(names))
+		for _, property := range names {
+			key, _ := json.Marshal(property)
+			fields = append(fields, escapeTemplateText(string(key))+": "+sampleJSON(schema.Properties[property], property, depth+1))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case "array":
+		count := 1
+		if schema.MinItems != nil && *schema.MinItems > count {
+			count = *schema.MinItems
+		}
+		items := make([]string, count)
+		for i := range items {
+			items[i] = sampleJSON(schema.Items, name, depth+1)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case "":
+		if len(schema.Enum) == 0 {
+			return `"value"`
+		}
+	}
+
+	value, quoted := sampleScalar(schema, name, jsonStringContent)
+	if quoted {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// jsonStringContent экранирует текст для вставки внутрь JSON строки.
+func jsonStringContent(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded[1 : len(encoded)-1])
+}
+
+func isJSONMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (b *openAPIBuilder) requestBody(tmpl *RequestTemplate, body *openAPIRequestBody) error {
+	if body == nil {
+		return nil
+	}
+	if body.Ref != "" {
+		resolved := b.doc.Components.RequestBodies[strings.TrimPrefix(body.Ref, "#/components/requestBodies/")]
+		if resolved == nil {
+			return fmt.Errorf("unresolved request body reference %s", body.Ref)
+		}
+		body = resolved
+	}
+
+	mediaTypes := make([]string, 0, len(body.Content))
+	for mediaType := range body.Content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+
+	for _, mediaType := range mediaTypes {
+		media := body.Content[mediaType]
+		if media == nil {
+			continue
+		}
+		isForm := strings.HasPrefix(strings.ToLower(mediaType), "application/x-www-form-urlencoded")
+		if !isJSONMediaType(mediaType) && !isForm {
+			continue
+		}
+
+		if tmpl.Headers == nil {
+			tmpl.Headers = make(map[string]string)
+		}
+		tmpl.Headers["Content-Type"] = mediaType
+
+		example, hasExample := b.example(media.Example, media.Examples)
+		schema := b.inline(media.Schema, 0)
+		switch {
+		case isForm:
+			tmpl.Body = b.formBody(schema, example, hasExample)
+		case hasExample:
+			encoded, err := json.Marshal(example)
+			if err != nil {
+				return fmt.Errorf("invalid request body example: %v", err)
+			}
+			tmpl.Body = escapeTemplateText(string(encoded))
+		default:
+			tmpl.Body = sampleJSON(schema, "value", 0)
+		}
+		return nil
+	}
+
+	if body.Required {
+		return fmt.Errorf("request body has no JSON or form content type")
+	}
+	return nil
+}
+
+func (b *openAPIBuilder) formBody(schema *Schema, example interface{}, hasExample bool) string {
+	if values, ok := example.(map[string]interface{}); hasExample && ok {
+		form := url.Values{}
+		for key, value := range values {
+			form.Set(key, scalarText(value))
+		}
+		return escapeTemplateText(form.Encode())
+	}
+	if schema == nil {
+		return ""
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name, property := range schema.Properties {
+		if property != nil && !property.ReadOnly {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		value, _ := sampleScalar(schema.Properties[name], name, url.QueryEscape)
+		fields = append(fields, url.QueryEscape(name)+"="+value)
+	}
+	return strings.Join(fields, "&")
+}
+
+// responseChecks собирает проверку объявленных кодов ответа и схем JSON тел.
+// Если объявлен default, любой код считается допустимым.
+func (b *openAPIBuilder) responseChecks(name string, responses map[string]*openAPIResponse) []*ResponseCheck {
+	var statuses []int
+	schemas := make(map[string]*Schema)
+	anyStatus := false
+
+	for code, response := range responses {
+		code = strings.ToUpper(code)
+		switch {
+		case code == "DEFAULT":
+			anyStatus = true
+			code = "default"
+		case len(code) == 3 && strings.HasSuffix(code, "XX"):
+			class, err := strconv.Atoi(code[:1])
+			if err != nil {
+				continue
+			}
+			for status := class * 100; status < class*100+100; status++ {
+				statuses = append(statuses, status)
+			}
+		default:
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				continue
+			}
+			statuses = append(statuses, status)
+		}
+
+		if response != nil && response.Ref != "" {
+			response = b.doc.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+		}
+		if response == nil {
+			continue
+		}
+		for mediaType, media := range response.Content {
+			if media != nil && media.Schema != nil && isJSONMediaType(mediaType) {
+				schemas[code] = b.inline(media.Schema, 0)
+				break
+			}
+		}
+	}
+
+	var checks []*ResponseCheck
+	if len(statuses) > 0 && !anyStatus {
+		sort.Ints(statuses)
+		checks = append(checks, &ResponseCheck{Name: name + ": declared status", Status: statuses})
+	}
+	if len(schemas) > 0 {
+		checks = append(checks, &ResponseCheck{Name: name + ": response schema", Schemas: schemas})
+	}
+	return checks
+}
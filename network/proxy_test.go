@@ -0,0 +1,366 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"stresspulse/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init("error")
+	os.Exit(m.Run())
+}
+
+// testProxy - HTTP (CONNECT и обычные запросы) или SOCKS5 прокси на 127.0.0.1, который запоминает
+// учётные данные и адреса целей каждого соединения.
+type testProxy struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	auth     []string
+	targets  []string
+}
+
+func startHTTPProxy(t *testing.T) *testProxy {
+	t.Helper()
+	p := &testProxy{listener: listen(t)}
+	server := &http.Server{Handler: p}
+	go server.Serve(p.listener)
+	t.Cleanup(func() { server.Close() })
+	return p
+}
+
+func startSOCKS5Proxy(t *testing.T) *testProxy {
+	t.Helper()
+	p := &testProxy{listener: listen(t)}
+	go func() {
+		for {
+			conn, err := p.listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serveSOCKS5(conn)
+		}
+	}()
+	t.Cleanup(func() { p.listener.Close() })
+	return p
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	return listener
+}
+
+func (p *testProxy) url(scheme, user, password string) string {
+	return (&url.URL{Scheme: scheme, User: url.UserPassword(user, password), Host: p.listener.Addr().String()}).String()
+}
+
+func (p *testProxy) record(auth, target string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.auth = append(p.auth, auth)
+	p.targets = append(p.targets, target)
+}
+
+// check проверяет, что через прокси прошло хотя бы одно соединение к target и все - с ожидаемыми учётными данными.
+func (p *testProxy) check(t *testing.T, auth, target string) {
+	t.Helper()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.targets) == 0 {
+		t.Fatalf("no connections went through the proxy")
+	}
+	for i := range p.targets {
+		if p.auth[i] != auth {
+			t.Errorf("proxy credentials = %q, want %q", p.auth[i], auth)
+		}
+		if p.targets[i] != target {
+			t.Errorf("proxy target = %q, want %q", p.targets[i], target)
+		}
+	}
+}
+
+func (p *testProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.record(r.Header.Get("Proxy-Authorization"), r.Host)
+
+	if r.Method == http.MethodConnect {
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		pipe(conn, target)
+		return
+	}
+
+	r.RequestURI = ""
+	r.Header.Del("Proxy-Authorization")
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// serveSOCKS5 обслуживает одно соединение: аутентификация по имени и паролю (RFC 1929) и CONNECT.
+func (p *testProxy) serveSOCKS5(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 2})
+
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	user := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, header[:1]); err != nil {
+		return
+	}
+	password := make([]byte, header[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return
+	}
+	conn.Write([]byte{1, 0})
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		if _, err := io.ReadFull(conn, header[:1]); err != nil {
+			return
+		}
+		name := make([]byte, header[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	p.record(string(user)+":"+string(password), target)
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	pipe(conn, upstream)
+}
+
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(a, b); done <- struct{}{} }()
+	go func() { io.Copy(b, a); done <- struct{}{} }()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// hitServer оборачивает обработчик тестового сервера и сообщает о первом обращении.
+func hitServer(handler http.HandlerFunc) (http.HandlerFunc, <-chan struct{}) {
+	hit := make(chan struct{})
+	var once sync.Once
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(hit) })
+		handler(w, r)
+	}, hit
+}
+
+func waitHit(t *testing.T, hit <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-hit:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("target was not reached through the proxy")
+	}
+}
+
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestHTTPGeneratorProxy(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme string
+		tls    bool
+		socks  bool
+	}{
+		{name: "http forward", scheme: "http"},
+		{name: "http connect", scheme: "http", tls: true},
+		{name: "socks5", scheme: "socks5", socks: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, hit := hitServer(func(w http.ResponseWriter, r *http.Request) {})
+			var target *httptest.Server
+			if tt.tls {
+				target = httptest.NewTLSServer(handler)
+			} else {
+				target = httptest.NewServer(handler)
+			}
+			defer target.Close()
+
+			var proxy *testProxy
+			want := basicAuth("user", "secret")
+			if tt.socks {
+				proxy = startSOCKS5Proxy(t)
+				want = "user:secret"
+			} else {
+				proxy = startHTTPProxy(t)
+			}
+
+			generator := NewHTTPGenerator(target.URL, 20, "constant", http.MethodGet, 5*time.Second)
+			if tt.tls {
+				generator.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+			}
+			if err := generator.SetProxy(&ProxyOptions{URL: proxy.url(tt.scheme, "user", "secret")}); err != nil {
+				t.Fatalf("SetProxy: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			generator.Start(ctx)
+			defer generator.Stop()
+
+			waitHit(t, hit)
+			proxy.check(t, want, target.Listener.Addr().String())
+		})
+	}
+}
+
+func TestWebSocketGeneratorProxy(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme string
+		socks  bool
+	}{
+		{name: "http connect", scheme: "http"},
+		{name: "socks5", scheme: "socks5", socks: true},
+	}
+
+	upgrader := websocket.Upgrader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, hit := hitServer(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						return
+					}
+				}
+			})
+			target := httptest.NewServer(handler)
+			defer target.Close()
+
+			var proxy *testProxy
+			want := basicAuth("user", "secret")
+			if tt.socks {
+				proxy = startSOCKS5Proxy(t)
+				want = "user:secret"
+			} else {
+				proxy = startHTTPProxy(t)
+			}
+
+			generator := NewWebSocketGenerator("ws://"+target.Listener.Addr().String()+"/ws", 10, "constant", time.Second, 16)
+			if err := generator.SetProxy(&ProxyOptions{URL: proxy.url(tt.scheme, "user", "secret")}); err != nil {
+				t.Fatalf("SetProxy: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			generator.Start(ctx)
+			defer generator.Stop()
+
+			waitHit(t, hit)
+			proxy.check(t, want, target.Listener.Addr().String())
+		})
+	}
+}
+
+// Для socks5:// имя цели разрешается Resolver генератора, так что overrides действуют и через прокси.
+func TestSOCKS5ProxyUsesResolver(t *testing.T) {
+	handler, hit := hitServer(func(w http.ResponseWriter, r *http.Request) {})
+	target := httptest.NewServer(handler)
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	resolver, err := NewResolver(&ResolveOptions{Overrides: []string{"api.stresspulse.test:" + port + ":127.0.0.1"}})
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	proxy := startSOCKS5Proxy(t)
+
+	generator := NewHTTPGenerator("http://api.stresspulse.test:"+port+"/", 20, "constant", http.MethodGet, 5*time.Second)
+	generator.SetResolver(resolver)
+	if err := generator.SetProxy(&ProxyOptions{URL: proxy.url("socks5", "user", "secret")}); err != nil {
+		t.Fatalf("SetProxy: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	generator.Start(ctx)
+	defer generator.Stop()
+
+	waitHit(t, hit)
+	proxy.check(t, "user:secret", net.JoinHostPort("127.0.0.1", port))
+}
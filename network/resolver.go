@@ -0,0 +1,260 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Политики выбора IP, когда имя разрешается в несколько адресов. default - как Go: адреса
+// по порядку, следующий только при ошибке; round-robin - каждое новое соединение к следующему
+// адресу; random - к случайному; pin - все соединения к одному адресу (PinIP или первому).
+const (
+	DNSPolicyDefault    = "default"
+	DNSPolicyRoundRobin = "round-robin"
+	DNSPolicyRandom     = "random"
+	DNSPolicyPin        = "pin"
+)
+
+var dnsPolicies = []string{DNSPolicyDefault, DNSPolicyRoundRobin, DNSPolicyRandom, DNSPolicyPin}
+
+// ResolveOptions - разрешение имён для HTTP, WebSocket и gRPC генераторов.
+// Overrides в формате curl --resolve: "host:port:addr[,addr]", порт "*" - любой.
+// Server - DNS сервер "host:port" вместо системного резолвера.
+type ResolveOptions struct {
+	Overrides []string `json:"overrides,omitempty"`
+	Server    string   `json:"server,omitempty"`
+	Policy    string   `json:"policy,omitempty"`
+	PinIP     string   `json:"pinIP,omitempty"`
+}
+
+func (o *ResolveOptions) policy() string {
+	if o.Policy == "" {
+		return DNSPolicyDefault
+	}
+	return o.Policy
+}
+
+func (o *ResolveOptions) enabled() bool {
+	return len(o.Overrides) > 0 || o.Server != "" || o.policy() != DNSPolicyDefault
+}
+
+func (o *ResolveOptions) Validate() error {
+	if _, err := parseResolveOverrides(o.Overrides); err != nil {
+		return err
+	}
+	if o.Server != "" {
+		if _, _, err := net.SplitHostPort(o.Server); err != nil {
+			return fmt.Errorf("invalid DNS server %q, expected host:port", o.Server)
+		}
+	}
+	valid := false
+	for _, policy := range dnsPolicies {
+		if o.policy() == policy {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid DNS policy %q, expected %s", o.Policy, strings.Join(dnsPolicies, ", "))
+	}
+	if o.PinIP != "" {
+		if net.ParseIP(o.PinIP) == nil {
+			return fmt.Errorf("invalid pinned IP %q", o.PinIP)
+		}
+		if o.policy() != DNSPolicyPin {
+			return fmt.Errorf("pinned IP requires DNS policy pin")
+		}
+	}
+	return nil
+}
+
+// Describe кратко описывает настройки для логов.
+func (o *ResolveOptions) Describe() string {
+	parts := []string{"policy=" + o.policy()}
+	if o.PinIP != "" {
+		parts = append(parts, "pin="+o.PinIP)
+	}
+	if o.Server != "" {
+		parts = append(parts, "server="+o.Server)
+	}
+	if len(o.Overrides) > 0 {
+		parts = append(parts, "resolve="+strings.Join(o.Overrides, " "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseResolveOverrides разбирает записи "host:port:addr[,addr]" в карту "host:port" -> адреса.
+func parseResolveOverrides(entries []string) (map[string][]string, error) {
+	overrides := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid resolve entry %q, expected host:port:addr[,addr]", entry)
+		}
+		var addresses []string
+		for _, address := range strings.Split(parts[2], ",") {
+			address = strings.Trim(strings.TrimSpace(address), "[]")
+			if net.ParseIP(address) == nil {
+				return nil, fmt.Errorf("invalid address %q in resolve entry %q", address, entry)
+			}
+			addresses = append(addresses, address)
+		}
+		overrides[strings.ToLower(parts[0])+":"+parts[1]] = addresses
+	}
+	return overrides, nil
+}
+
+// Resolver разрешает имена по настройкам ResolveOptions и раскладывает новые соединения по адресам.
+// Один Resolver общий для всех генераторов запуска, поэтому round-robin идёт по всем соединениям.
+type Resolver struct {
+	options   ResolveOptions
+	overrides map[string][]string
+	resolver  *net.Resolver
+	next      map[string]int
+	mutex     sync.Mutex
+}
+
+// NewResolver возвращает nil, если ничего не настроено: тогда имена разрешает net.Dialer как обычно.
+func NewResolver(options *ResolveOptions) (*Resolver, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if !options.enabled() {
+		return nil, nil
+	}
+
+	overrides, _ := parseResolveOverrides(options.Overrides)
+	r := &Resolver{
+		options:   *options,
+		overrides: overrides,
+		resolver:  net.DefaultResolver,
+		next:      make(map[string]int),
+	}
+	if options.Server != "" {
+		dialer := &net.Dialer{Timeout: 5 * time.Second}
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, options.Server)
+			},
+		}
+	}
+	return r, nil
+}
+
+func (r *Resolver) Describe() string {
+	return r.options.Describe()
+}
+
+// lookup возвращает адреса хоста: из overrides или через DNS. Запрос к DNS отмечается
+// в httptrace, чтобы фаза dns HTTP запросов не пропадала.
+func (r *Resolver) lookup(ctx context.Context, host, port string) ([]string, error) {
+	host = strings.ToLower(host)
+	if addresses, ok := r.overrides[host+":"+port]; ok {
+		return addresses, nil
+	}
+	if addresses, ok := r.overrides[host+":*"]; ok {
+		return addresses, nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	resolved, err := r.resolver.LookupIPAddr(ctx, host)
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: resolved, Err: err})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, len(resolved))
+	for i, address := range resolved {
+		addresses[i] = address.IP.String()
+	}
+	return addresses, nil
+}
+
+// order раскладывает адреса по политике; остальные адреса остаются запасными на случай ошибки.
+func (r *Resolver) order(host string, addresses []string) ([]string, error) {
+	switch r.options.policy() {
+	case DNSPolicyRoundRobin:
+		r.mutex.Lock()
+		start := r.next[host] % len(addresses)
+		r.next[host] = start + 1
+		r.mutex.Unlock()
+		return append(append([]string{}, addresses[start:]...), addresses[:start]...), nil
+	case DNSPolicyRandom:
+		ordered := append([]string{}, addresses...)
+		rand.Shuffle(len(ordered), func(i, j int) { ordered[i], ordered[j] = ordered[j], ordered[i] })
+		return ordered, nil
+	case DNSPolicyPin:
+		if r.options.PinIP == "" {
+			return addresses[:1], nil
+		}
+		pinned := net.ParseIP(r.options.PinIP)
+		for _, address := range addresses {
+			if net.ParseIP(address).Equal(pinned) {
+				return []string{address}, nil
+			}
+		}
+		return nil, fmt.Errorf("pinned address %s is not among addresses of %s: %s", r.options.PinIP, host, strings.Join(addresses, ", "))
+	}
+	return addresses, nil
+}
+
+// dialer оборачивает dial: имя разрешается здесь, а dial получает уже IP.
+func (r *Resolver) dialer(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addresses, err := r.lookup(ctx, host, port)
+		if err != nil {
+			return nil, err
+		}
+		if len(addresses) == 0 {
+			return nil, fmt.Errorf("no addresses for %s", host)
+		}
+		addresses, err = r.order(host, addresses)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, address := range addresses {
+			conn, err := dial(ctx, network, net.JoinHostPort(address, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
+
+// remoteIP - IP удалённой стороны соединения для статистики по адресам.
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
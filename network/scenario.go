@@ -0,0 +1,119 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+// Scenario - путь виртуального пользователя: упорядоченные шаги, которые выполняются по кругу.
+// ThinkTime - пауза по умолчанию для шагов без своей, Stages - изменение числа пользователей во времени.
+type Scenario struct {
+	Name      string            `json:"name,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	ThinkTime *ThinkTime        `json:"thinkTime,omitempty"`
+	Stages    []Stage           `json:"stages,omitempty"`
+	Steps     []*ScenarioStep   `json:"steps"`
+}
+
+// ScenarioStep - HTTP запрос шага, значения, которые из него нужно извлечь, и пауза после него.
+type ScenarioStep struct {
+	RequestTemplate
+	Extract   []*Extractor `json:"extract,omitempty"`
+	ThinkTime *ThinkTime   `json:"thinkTime,omitempty"`
+}
+
+// Extractor сохраняет значение из ответа в переменную {{.Vars.<var>}}.
+// Источник - JSON путь, первая группа regex (или всё совпадение) либо заголовок ответа.
+type Extractor struct {
+	Var      string `json:"var"`
+	JSONPath string `json:"jsonPath,omitempty"`
+	Regex    string `json:"regex,omitempty"`
+	Header   string `json:"header,omitempty"`
+	regex    *regexp.Regexp
+	path     []string
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %v", err)
+	}
+
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file: %v", err)
+	}
+
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %s contains no steps", path)
+	}
+
+	return &scenario, nil
+}
+
+func (e *Extractor) compile() error {
+	if e.Var == "" {
+		return fmt.Errorf("extractor variable name is empty")
+	}
+
+	sources := 0
+	if e.JSONPath != "" {
+		sources++
+		e.path = parseJSONPath(e.JSONPath)
+	}
+	if e.Regex != "" {
+		sources++
+		regex, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid extractor regex %q: %v", e.Regex, err)
+		}
+		e.regex = regex
+	}
+	if e.Header != "" {
+		sources++
+	}
+
+	if sources != 1 {
+		return fmt.Errorf("extractor %s must define exactly one of jsonPath, regex, header", e.Var)
+	}
+
+	return nil
+}
+
+func (e *Extractor) needsBody() bool {
+	return e.JSONPath != "" || e.Regex != ""
+}
+
+func (e *Extractor) extract(resp *http.Response, body []byte, parsed *jsonBody) (string, bool) {
+	switch {
+	case e.JSONPath != "":
+		doc, err := parsed.get(body)
+		if err != nil {
+			return "", false
+		}
+		value, ok := lookupJSONPath(doc, e.path)
+		if !ok {
+			return "", false
+		}
+		if text, isString := value.(string); isString {
+			return text, true
+		}
+		encoded, _ := json.Marshal(value)
+		return string(encoded), true
+	case e.regex != nil:
+		match := e.regex.FindSubmatch(body)
+		if match == nil {
+			return "", false
+		}
+		if len(match) > 1 {
+			return string(match[1]), true
+		}
+		return string(match[0]), true
+	default:
+		value := resp.Header.Get(e.Header)
+		return value, value != ""
+	}
+}
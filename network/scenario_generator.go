@@ -0,0 +1,659 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+// ScenarioGenerator запускает виртуальных пользователей, каждый из которых со своими cookie
+// по кругу проходит шаги сценария, передавая извлечённые значения в следующие запросы.
+// Это закрытая модель: задаётся число пользователей, а RPS получается как результат.
+type ScenarioGenerator struct {
+	scenario      *Scenario
+	baseURL       string
+	users         int
+	pattern       string
+	stages        []Stage
+	thinkTime     *ThinkTime
+	timeout       time.Duration
+	enabled       bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+	stats         *ScenarioStats
+	steps         []*scenarioStep
+	templates     *TemplateEngine
+	checks        *checkRegistry
+	transport     *http.Transport
+	auth          AuthProvider
+	userCancels   []context.CancelFunc
+	nextUserID    int
+	wg            sync.WaitGroup
+	settingsMutex sync.RWMutex
+	patternStart  time.Time
+}
+
+type ScenarioStats struct {
+	ActiveUsers       int64
+	TargetUsers       int64
+	TotalIterations   int64
+	SuccessIterations int64
+	FailedIterations  int64
+	TotalRequests     int64
+	CurrentIPS        int64
+	CurrentRPS        int64
+	AvgIPS            float64
+	AvgRPS            float64
+	StartTime         time.Time
+	ErrorClasses      map[string]int64
+	Iteration         LatencyPercentiles
+	Steps             []EndpointStats
+	Checks            []CheckStats
+	CheckFailures     []CheckFailure
+	iteration         *LatencyHistogram
+	mutex             sync.RWMutex
+}
+
+type scenarioStep struct {
+	*httpEndpoint
+	extract   []*Extractor
+	thinkTime *ThinkTime
+}
+
+func NewScenarioGenerator(scenario *Scenario, baseURL string, users int, timeout time.Duration) *ScenarioGenerator {
+	return &ScenarioGenerator{
+		scenario:  scenario,
+		baseURL:   baseURL,
+		users:     users,
+		pattern:   "constant",
+		stages:    scenario.Stages,
+		thinkTime: scenario.ThinkTime,
+		timeout:   timeout,
+		enabled:   false,
+		templates: NewTemplateEngine(nil),
+		checks:    newCheckRegistry(),
+		transport: &http.Transport{
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 50,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		stats: &ScenarioStats{
+			StartTime:    time.Now(),
+			ErrorClasses: make(map[string]int64),
+			iteration:    NewLatencyHistogram(),
+		},
+	}
+}
+
+func (sg *ScenarioGenerator) SetDataFeeder(feeder *DataFeeder) {
+	sg.templates.feeder = feeder
+}
+
+// SetTLSConfig задаёт TLS настройки для https:// шагов.
+func (sg *ScenarioGenerator) SetTLSConfig(config *tls.Config) {
+	sg.transport.TLSClientConfig = config
+}
+
+// SetAuth задаёт провайдер аутентификации для запросов шагов.
+func (sg *ScenarioGenerator) SetAuth(auth AuthProvider) {
+	sg.auth = auth
+}
+
+// SetUserPattern задаёт паттерн изменения числа пользователей: constant, spike, cycle, ramp, random.
+// Можно вызывать на ходу: cycle и ramp отсчитываются заново с момента смены.
+func (sg *ScenarioGenerator) SetUserPattern(pattern string) {
+	if pattern != "" {
+		sg.settingsMutex.Lock()
+		sg.pattern = pattern
+		sg.patternStart = time.Now()
+		sg.settingsMutex.Unlock()
+	}
+}
+
+// SetUsers меняет базовое число пользователей на ходу. Ступени при этом отключаются,
+// дальше число пользователей задают users и паттерн.
+func (sg *ScenarioGenerator) SetUsers(users int) {
+	sg.settingsMutex.Lock()
+	sg.users = users
+	sg.stages = nil
+	sg.settingsMutex.Unlock()
+}
+
+func (sg *ScenarioGenerator) Users() int {
+	sg.settingsMutex.RLock()
+	defer sg.settingsMutex.RUnlock()
+	return sg.users
+}
+
+// SetStages задаёт ступени нагрузки; если они есть, паттерн и число пользователей не используются.
+func (sg *ScenarioGenerator) SetStages(stages []Stage) {
+	sg.settingsMutex.Lock()
+	sg.stages = stages
+	sg.settingsMutex.Unlock()
+}
+
+// SetThinkTime задаёт паузу по умолчанию для шагов без своей паузы.
+func (sg *ScenarioGenerator) SetThinkTime(thinkTime *ThinkTime) {
+	sg.thinkTime = thinkTime
+}
+
+// Prepare компилирует шаги сценария: шаблоны, проверки и извлечения.
+func (sg *ScenarioGenerator) Prepare() error {
+	if sg.scenario == nil || len(sg.scenario.Steps) == 0 {
+		return fmt.Errorf("scenario has no steps")
+	}
+
+	steps := make([]*scenarioStep, 0, len(sg.scenario.Steps))
+	names := make(map[string]bool)
+
+	for i, source := range sg.scenario.Steps {
+		if source == nil {
+			return fmt.Errorf("scenario step %d is empty", i)
+		}
+
+		tmpl := source.RequestTemplate
+		if tmpl.Method == "" {
+			tmpl.Method = http.MethodGet
+		}
+		tmpl.Method = strings.ToUpper(tmpl.Method)
+		if tmpl.Name == "" {
+			tmpl.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if names[tmpl.Name] {
+			return fmt.Errorf("duplicate scenario step name: %s", tmpl.Name)
+		}
+		names[tmpl.Name] = true
+
+		resolvedURL, err := resolveTemplateURL(sg.baseURL, tmpl.URL)
+		if err != nil {
+			return fmt.Errorf("scenario step %s: %v", tmpl.Name, err)
+		}
+		tmpl.URL = resolvedURL
+
+		step := &scenarioStep{
+			httpEndpoint: newHTTPEndpoint(&tmpl),
+			thinkTime:    source.ThinkTime,
+		}
+
+		urlTemplate, err := sg.templates.Compile("url", tmpl.URL)
+		if err != nil {
+			return fmt.Errorf("scenario step %s: %v", tmpl.Name, err)
+		}
+		step.url.Store(urlTemplate)
+		if step.body, err = sg.templates.Compile("body", tmpl.Body); err != nil {
+			return fmt.Errorf("scenario step %s: %v", tmpl.Name, err)
+		}
+		step.headers = make(map[string]*TextTemplate)
+		for key, value := range tmpl.Headers {
+			if step.headers[key], err = sg.templates.Compile(key, value); err != nil {
+				return fmt.Errorf("scenario step %s: %v", tmpl.Name, err)
+			}
+		}
+
+		if step.ownChecks, err = sg.checks.compile(tmpl.Checks, tmpl.Name); err != nil {
+			return fmt.Errorf("scenario step %s: %v", tmpl.Name, err)
+		}
+		step.setChecks(nil)
+
+		for _, extractor := range source.Extract {
+			if extractor == nil {
+				continue
+			}
+			e := *extractor
+			if err := e.compile(); err != nil {
+				return fmt.Errorf("scenario step %s: %v", tmpl.Name, err)
+			}
+			if e.needsBody() {
+				step.needsBody = true
+			}
+			step.extract = append(step.extract, &e)
+		}
+
+		steps = append(steps, step)
+	}
+
+	sg.steps = steps
+	return nil
+}
+
+func (sg *ScenarioGenerator) Start(ctx context.Context) error {
+	if sg.enabled {
+		return nil
+	}
+
+	if sg.steps == nil {
+		if err := sg.Prepare(); err != nil {
+			return err
+		}
+	}
+
+	sg.enabled = true
+	sg.ctx, sg.cancel = context.WithCancel(ctx)
+	sg.stats.StartTime = time.Now()
+	sg.patternStart = sg.stats.StartTime
+
+	if len(sg.stages) > 0 {
+		logger.Info("Starting scenario generator: %s, %d steps, %d stages, think time: %s",
+			sg.scenario.Name, len(sg.steps), len(sg.stages), sg.thinkTime)
+	} else {
+		logger.Info("Starting scenario generator: %s, %d steps, %d virtual users, pattern: %s, think time: %s",
+			sg.scenario.Name, len(sg.steps), sg.users, sg.pattern, sg.thinkTime)
+	}
+
+	sg.wg.Add(1)
+	go sg.userController()
+
+	go sg.statsCollector()
+
+	return nil
+}
+
+func (sg *ScenarioGenerator) Stop() {
+	if !sg.enabled {
+		return
+	}
+
+	sg.enabled = false
+	sg.cancel()
+	sg.wg.Wait()
+	sg.userCancels = nil
+
+	logger.Info("Scenario generator stopped")
+}
+
+// userController раз в секунду подгоняет число виртуальных пользователей под паттерн или ступени.
+func (sg *ScenarioGenerator) userController() {
+	defer sg.wg.Done()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		sg.scaleUsers(sg.calculateTargetUsers())
+
+		select {
+		case <-sg.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (sg *ScenarioGenerator) calculateTargetUsers() int {
+	sg.settingsMutex.RLock()
+	baseUsers, pattern, stages, patternStart := sg.users, sg.pattern, sg.stages, sg.patternStart
+	sg.settingsMutex.RUnlock()
+
+	if len(stages) > 0 {
+		return stageUsers(stages, time.Since(sg.stats.StartTime))
+	}
+
+	elapsed := time.Since(patternStart)
+	users := baseUsers
+	switch pattern {
+	case "spike":
+		if rand.Intn(10) == 0 {
+			users = baseUsers * 3
+		}
+	case "cycle":
+		switch (int(elapsed.Seconds()) / 30) % 4 {
+		case 0:
+			users = baseUsers / 4
+		case 2:
+			users = baseUsers / 2
+		case 3:
+			users = baseUsers / 8
+		}
+	case "ramp":
+		rampMultiplier := float64(int(elapsed.Minutes())+1) * 0.2
+		if rampMultiplier > 1.0 {
+			rampMultiplier = 1.0
+		}
+		users = int(float64(baseUsers) * rampMultiplier)
+	case "random":
+		users = baseUsers * (rand.Intn(140) + 10) / 100
+	}
+
+	// Паттерн только масштабирует нагрузку, поэтому хотя бы один пользователь работает всегда.
+	if users < 1 {
+		users = 1
+	}
+	return users
+}
+
+// scaleUsers запускает недостающих пользователей или останавливает последних запущенных.
+// У остановленного пользователя отменяется текущий запрос, прерванная итерация не записывается.
+func (sg *ScenarioGenerator) scaleUsers(target int) {
+	if sg.ctx.Err() != nil {
+		return
+	}
+
+	atomic.StoreInt64(&sg.stats.TargetUsers, int64(target))
+
+	for len(sg.userCancels) < target {
+		userCtx, cancel := context.WithCancel(sg.ctx)
+		sg.userCancels = append(sg.userCancels, cancel)
+		sg.wg.Add(1)
+		go sg.virtualUser(userCtx, sg.nextUserID)
+		sg.nextUserID++
+	}
+
+	for len(sg.userCancels) > target {
+		last := len(sg.userCancels) - 1
+		sg.userCancels[last]()
+		sg.userCancels = sg.userCancels[:last]
+	}
+}
+
+func (sg *ScenarioGenerator) virtualUser(ctx context.Context, userID int) {
+	defer sg.wg.Done()
+
+	atomic.AddInt64(&sg.stats.ActiveUsers, 1)
+	defer atomic.AddInt64(&sg.stats.ActiveUsers, -1)
+
+	logger.Debug("Virtual user %d started", userID)
+	defer logger.Debug("Virtual user %d stopped", userID)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Transport: sg.transport,
+		Jar:       jar,
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			sg.runIteration(ctx, client)
+		}
+	}
+}
+
+// runIteration проходит все шаги сценария; при провале шага итерация прерывается.
+func (sg *ScenarioGenerator) runIteration(ctx context.Context, client *http.Client) {
+	startTime := time.Now()
+
+	data, err := sg.templates.NewData(copyVars(sg.scenario.Variables))
+	if err != nil {
+		sg.recordIteration(false, time.Since(startTime))
+		sg.recordError(ErrorClassData)
+		logger.Debug("Failed to get scenario data: %v", err)
+		sleepContext(ctx, time.Second)
+		return
+	}
+
+	for _, step := range sg.steps {
+		ok := sg.runStep(ctx, client, step, data)
+
+		if ctx.Err() != nil {
+			return
+		}
+		// Проваленная итерация записывается сразу: think time не входит в её длительность.
+		if !ok {
+			sg.recordIteration(false, time.Since(startTime))
+			return
+		}
+
+		thinkTime := step.thinkTime
+		if thinkTime == nil {
+			thinkTime = sg.thinkTime
+		}
+		sleepContext(ctx, thinkTime.Next())
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	sg.recordIteration(true, time.Since(startTime))
+}
+
+// runStep выполняет шаг в контексте виртуального пользователя: при его остановке запрос отменяется.
+func (sg *ScenarioGenerator) runStep(ctx context.Context, client *http.Client, step *scenarioStep, data *TemplateData) bool {
+	tmpl := step.template
+
+	timeout := sg.timeout
+	if tmpl.Timeout > 0 {
+		timeout = time.Duration(tmpl.Timeout)
+	}
+	requestCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startTime := time.Now()
+
+	targetURL, headers, body, err := step.render(data)
+	if err != nil {
+		sg.recordStep(step, startTime, false, ErrorClassRequest)
+		logger.Debug("Failed to render scenario step %s: %v", tmpl.Name, err)
+		return false
+	}
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = bytes.NewReader([]byte(body))
+	}
+
+	req, err := http.NewRequestWithContext(requestCtx, tmpl.Method, targetURL, bodyReader)
+	if err != nil {
+		sg.recordStep(step, startTime, false, ErrorClassRequest)
+		logger.Debug("Failed to create scenario request %s: %v", tmpl.Name, err)
+		return false
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "StressPulse/1.0")
+	}
+
+	if sg.auth != nil {
+		if err := sg.auth.Authorize(&AuthRequest{Method: req.Method, URL: req.URL, Host: req.Host, Header: req.Header, Body: []byte(body)}); err != nil {
+			sg.recordStep(step, startTime, false, ErrorClassAuth)
+			logger.Debug("Failed to authorize scenario step %s: %v", tmpl.Name, err)
+			return false
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		errorClass := classifyHTTPError(err)
+		sg.recordStep(step, startTime, false, errorClass)
+		logger.Debug("Scenario step %s failed (%s): %v", tmpl.Name, errorClass, err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	step.recordStatusCode(resp.StatusCode)
+
+	var responseBody []byte
+	if step.needsBody {
+		responseBody, err = io.ReadAll(io.LimitReader(resp.Body, maxCheckBodySize))
+		if err == nil {
+			_, err = io.Copy(io.Discard, resp.Body)
+		}
+	} else {
+		_, err = io.Copy(io.Discard, resp.Body)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		errorClass := classifyHTTPError(err)
+		if errorClass == ErrorClassOther || errorClass == ErrorClassReset {
+			errorClass = ErrorClassRead
+		}
+		sg.recordStep(step, startTime, false, errorClass)
+		return false
+	}
+
+	if !step.hasStatusCheck && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
+		sg.recordStep(step, startTime, false, classifyHTTPStatus(resp.StatusCode))
+		logger.Debug("Scenario step %s failed with status: %d", tmpl.Name, resp.StatusCode)
+		return false
+	}
+
+	if len(step.checks) > 0 && sg.checks.run(tmpl.Name, step.checks, resp, responseBody, time.Since(startTime)) != nil {
+		sg.recordStep(step, startTime, false, ErrorClassCheck)
+		return false
+	}
+
+	var parsed jsonBody
+	for _, extractor := range step.extract {
+		value, ok := extractor.extract(resp, responseBody, &parsed)
+		if !ok {
+			sg.recordStep(step, startTime, false, ErrorClassExtract)
+			logger.Debug("Scenario step %s: failed to extract %s", tmpl.Name, extractor.Var)
+			return false
+		}
+		data.Vars[extractor.Var] = value
+	}
+
+	sg.recordStep(step, startTime, true, "")
+	return true
+}
+
+func (sg *ScenarioGenerator) recordStep(step *scenarioStep, startTime time.Time, success bool, errorClass string) {
+	responseTime := time.Since(startTime)
+
+	atomic.AddInt64(&sg.stats.TotalRequests, 1)
+	step.recordResult(success, responseTime, responseTime, errorClass)
+
+	result := "success"
+	if !success {
+		result = "failed"
+		sg.recordError(errorClass)
+	}
+	metrics.ScenarioStepRequestsCounter.WithLabelValues(step.template.Name, result).Inc()
+	metrics.ScenarioStepResponseTimeHistogram.WithLabelValues(step.template.Name).Observe(responseTime.Seconds())
+}
+
+func (sg *ScenarioGenerator) recordError(errorClass string) {
+	sg.stats.mutex.Lock()
+	sg.stats.ErrorClasses[errorClass]++
+	sg.stats.mutex.Unlock()
+}
+
+func (sg *ScenarioGenerator) recordIteration(success bool, duration time.Duration) {
+	atomic.AddInt64(&sg.stats.TotalIterations, 1)
+
+	result := "success"
+	if success {
+		atomic.AddInt64(&sg.stats.SuccessIterations, 1)
+	} else {
+		atomic.AddInt64(&sg.stats.FailedIterations, 1)
+		result = "failed"
+	}
+
+	sg.stats.iteration.Record(duration)
+	metrics.ScenarioIterationsCounter.WithLabelValues(result).Inc()
+	metrics.ScenarioIterationDurationHistogram.Observe(duration.Seconds())
+}
+
+func (sg *ScenarioGenerator) statsCollector() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	lastIterations := int64(0)
+	lastRequests := int64(0)
+
+	for {
+		select {
+		case <-sg.ctx.Done():
+			return
+		case <-ticker.C:
+			currentIterations := atomic.LoadInt64(&sg.stats.TotalIterations)
+			currentRequests := atomic.LoadInt64(&sg.stats.TotalRequests)
+			atomic.StoreInt64(&sg.stats.CurrentIPS, currentIterations-lastIterations)
+			atomic.StoreInt64(&sg.stats.CurrentRPS, currentRequests-lastRequests)
+			lastIterations = currentIterations
+			lastRequests = currentRequests
+		}
+	}
+}
+
+func (sg *ScenarioGenerator) GetStats() *ScenarioStats {
+	sg.stats.mutex.RLock()
+	errorClasses := make(map[string]int64)
+	for class, count := range sg.stats.ErrorClasses {
+		errorClasses[class] = count
+	}
+	sg.stats.mutex.RUnlock()
+
+	steps := make([]EndpointStats, 0, len(sg.steps))
+	for _, step := range sg.steps {
+		steps = append(steps, step.snapshot())
+	}
+
+	checks, checkFailures := sg.checks.stats()
+
+	totalIterations := atomic.LoadInt64(&sg.stats.TotalIterations)
+	totalRequests := atomic.LoadInt64(&sg.stats.TotalRequests)
+	var avgIPS, avgRPS float64
+	if elapsed := time.Since(sg.stats.StartTime).Seconds(); elapsed > 0 {
+		avgIPS = float64(totalIterations) / elapsed
+		avgRPS = float64(totalRequests) / elapsed
+	}
+
+	return &ScenarioStats{
+		ActiveUsers:       atomic.LoadInt64(&sg.stats.ActiveUsers),
+		TargetUsers:       atomic.LoadInt64(&sg.stats.TargetUsers),
+		TotalIterations:   totalIterations,
+		SuccessIterations: atomic.LoadInt64(&sg.stats.SuccessIterations),
+		FailedIterations:  atomic.LoadInt64(&sg.stats.FailedIterations),
+		TotalRequests:     totalRequests,
+		CurrentIPS:        atomic.LoadInt64(&sg.stats.CurrentIPS),
+		CurrentRPS:        atomic.LoadInt64(&sg.stats.CurrentRPS),
+		AvgIPS:            avgIPS,
+		AvgRPS:            avgRPS,
+		StartTime:         sg.stats.StartTime,
+		ErrorClasses:      errorClasses,
+		Iteration:         sg.stats.iteration.Percentiles(),
+		Steps:             steps,
+		Checks:            checks,
+		CheckFailures:     checkFailures,
+	}
+}
+
+func (sg *ScenarioGenerator) GetSuccessRate() float64 {
+	stats := sg.GetStats()
+	if stats.TotalIterations == 0 {
+		return 0
+	}
+	return float64(stats.SuccessIterations) / float64(stats.TotalIterations) * 100.0
+}
+
+func copyVars(vars map[string]string) map[string]string {
+	copied := make(map[string]string, len(vars))
+	for key, value := range vars {
+		copied[key] = value
+	}
+	return copied
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
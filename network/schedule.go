@@ -0,0 +1,30 @@
+package network
+
+import (
+	"context"
+	"time"
+)
+
+// Запрос, начатый позже запланированного на lateRequestThreshold и более, считается опоздавшим.
+const lateRequestThreshold = 10 * time.Millisecond
+
+// scheduledTime равномерно распределяет запросы тика по его интервалу, чтобы не отправлять их пачкой.
+func scheduledTime(tickTime time.Time, tickInterval time.Duration, index, count int) time.Time {
+	if count <= 1 {
+		return tickTime
+	}
+	return tickTime.Add(tickInterval * time.Duration(index) / time.Duration(count))
+}
+
+// waitForSchedule дожидается запланированного времени отправки и возвращает опоздание относительно него.
+func waitForSchedule(ctx context.Context, intendedTime time.Time) time.Duration {
+	if wait := time.Until(intendedTime); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	return time.Since(intendedTime)
+}
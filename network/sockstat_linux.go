@@ -0,0 +1,39 @@
+//go:build linux
+
+package network
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+const tcpStateTimeWait = "06"
+
+// countTimeWaitSockets считает TCP сокеты в состоянии TIME_WAIT по /proc/net/tcp{,6}.
+func countTimeWaitSockets() int64 {
+	var total int64
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		total += countTimeWaitInFile(path)
+	}
+	return total
+}
+
+func countTimeWaitInFile(path string) int64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	var count int64
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // заголовок
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 3 && fields[3] == tcpStateTimeWait {
+			count++
+		}
+	}
+	return count
+}
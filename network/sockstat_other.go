@@ -0,0 +1,8 @@
+//go:build !linux
+
+package network
+
+// countTimeWaitSockets на платформах без /proc не поддерживается.
+func countTimeWaitSockets() int64 {
+	return -1
+}
@@ -0,0 +1,238 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+// SourceOptions - локальные адреса, с которых генераторы открывают соединения. Addresses - список IP,
+// Interface - сетевой интерфейс, адреса которого берутся при запуске. Новые соединения
+// раскладываются по адресам по кругу.
+type SourceOptions struct {
+	Addresses []string `json:"addresses,omitempty"`
+	Interface string   `json:"interface,omitempty"`
+}
+
+func (o *SourceOptions) enabled() bool {
+	return len(o.Addresses) > 0 || o.Interface != ""
+}
+
+func (o *SourceOptions) Validate() error {
+	if len(o.Addresses) > 0 && o.Interface != "" {
+		return fmt.Errorf("source addresses and source interface cannot be used together")
+	}
+	for _, address := range o.Addresses {
+		if net.ParseIP(strings.TrimSpace(address)) == nil {
+			return fmt.Errorf("invalid source address %q", address)
+		}
+	}
+	return nil
+}
+
+// PortExhaustionError - у локального адреса кончились эфемерные порты (EADDRNOTAVAIL или EADDRINUSE при connect).
+type PortExhaustionError struct {
+	Source string
+	Err    error
+}
+
+func (e *PortExhaustionError) Error() string {
+	return fmt.Sprintf("local ports exhausted on source address %s: %v", e.Source, e.Err)
+}
+
+func (e *PortExhaustionError) Unwrap() error {
+	return e.Err
+}
+
+func isPortExhaustion(err error) bool {
+	var exhaustion *PortExhaustionError
+	return errors.As(err, &exhaustion) || errors.Is(err, syscall.EADDRNOTAVAIL) || errors.Is(err, syscall.EADDRINUSE)
+}
+
+// SourceAddressStats - соединения с одного локального адреса.
+type SourceAddressStats struct {
+	Connections   int64 `json:"connections"`
+	Failed        int64 `json:"failed"`
+	PortExhausted int64 `json:"portExhausted"`
+}
+
+// SourceBinder привязывает новые соединения к локальным адресам. Один SourceBinder общий
+// для всех генераторов запуска, поэтому адреса чередуются по всем соединениям.
+type SourceBinder struct {
+	options   SourceOptions
+	addresses []net.IP
+	next      int
+	stats     map[string]*SourceAddressStats
+	warned    map[string]bool
+	mutex     sync.Mutex
+}
+
+// NewSourceBinder возвращает nil, если адреса не заданы: тогда адрес выбирает ядро.
+func NewSourceBinder(options *SourceOptions) (*SourceBinder, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if !options.enabled() {
+		return nil, nil
+	}
+
+	var addresses []net.IP
+	if options.Interface != "" {
+		iface, err := net.InterfaceByName(options.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("source interface %s: %v", options.Interface, err)
+		}
+		interfaceAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("source interface %s: %v", options.Interface, err)
+		}
+		for _, addr := range interfaceAddrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+				addresses = append(addresses, ipNet.IP)
+			}
+		}
+		if len(addresses) == 0 {
+			return nil, fmt.Errorf("source interface %s has no usable addresses", options.Interface)
+		}
+	} else {
+		localAddrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range options.Addresses {
+			ip := net.ParseIP(strings.TrimSpace(address))
+			if !isLocalAddress(ip, localAddrs) {
+				return nil, fmt.Errorf("source address %s is not assigned to any interface", ip)
+			}
+			addresses = append(addresses, ip)
+		}
+	}
+
+	b := &SourceBinder{
+		options:   *options,
+		addresses: addresses,
+		stats:     make(map[string]*SourceAddressStats, len(addresses)),
+		warned:    make(map[string]bool),
+	}
+	for _, ip := range addresses {
+		b.stats[ip.String()] = &SourceAddressStats{}
+	}
+	return b, nil
+}
+
+// isLocalAddress проверяет, что адрес есть на интерфейсах; 127.0.0.0/8 на loopback целиком локальный.
+func isLocalAddress(ip net.IP, localAddrs []net.Addr) bool {
+	for _, addr := range localAddrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.Equal(ip) || (ip.IsLoopback() && ipNet.IP.IsLoopback() && ipNet.Contains(ip)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *SourceBinder) Describe() string {
+	addresses := make([]string, len(b.addresses))
+	for i, ip := range b.addresses {
+		addresses[i] = ip.String()
+	}
+	if b.options.Interface != "" {
+		return fmt.Sprintf("interface %s (%s)", b.options.Interface, strings.Join(addresses, ", "))
+	}
+	return strings.Join(addresses, ", ")
+}
+
+func (b *SourceBinder) GetStats() map[string]SourceAddressStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats := make(map[string]SourceAddressStats, len(b.stats))
+	for address, addressStats := range b.stats {
+		stats[address] = *addressStats
+	}
+	return stats
+}
+
+// pick выбирает следующий адрес по кругу. Если цель задана IP, берётся адрес того же семейства;
+// для имени семейство цели под выбранный адрес подбирает net.Dialer.
+func (b *SourceBinder) pick(addr string) net.IP {
+	var remote net.IP
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		remote = net.ParseIP(host)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i := 0; i < len(b.addresses); i++ {
+		ip := b.addresses[(b.next+i)%len(b.addresses)]
+		if remote == nil || (ip.To4() == nil) == (remote.To4() == nil) {
+			b.next = (b.next + i + 1) % len(b.addresses)
+			return ip
+		}
+	}
+	ip := b.addresses[b.next]
+	b.next = (b.next + 1) % len(b.addresses)
+	return ip
+}
+
+func (b *SourceBinder) record(source string, err error) {
+	result := "success"
+	exhausted := err != nil && isPortExhaustion(err)
+
+	b.mutex.Lock()
+	addressStats := b.stats[source]
+	if err != nil {
+		result = "failed"
+		addressStats.Failed++
+	} else {
+		addressStats.Connections++
+	}
+	warn := false
+	if exhausted {
+		addressStats.PortExhausted++
+		warn = !b.warned[source]
+		b.warned[source] = true
+	}
+	b.mutex.Unlock()
+
+	metrics.SourceConnectionsCounter.WithLabelValues(source, result).Inc()
+	if exhausted {
+		metrics.SourcePortExhaustionCounter.WithLabelValues(source).Inc()
+	}
+	if warn {
+		logger.Warning("Source address %s ran out of local ports: add source addresses or widen net.ipv4.ip_local_port_range", source)
+	}
+}
+
+// dialer открывает соединения с base, подставляя локальный адрес; без SourceBinder (nil) - просто base.
+// Порт выбирается при connect (IP_BIND_ADDRESS_NO_PORT на Linux), поэтому порты одного адреса
+// делятся между разными целями.
+func (b *SourceBinder) dialer(base *net.Dialer) dialFunc {
+	if b == nil {
+		return base.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		source := b.pick(addr)
+		dialer := *base
+		dialer.LocalAddr = &net.TCPAddr{IP: source}
+		dialer.Control = bindAddressNoPort
+
+		conn, err := dialer.DialContext(ctx, network, addr)
+		b.record(source.String(), err)
+		if err != nil && isPortExhaustion(err) {
+			return nil, &PortExhaustionError{Source: source.String(), Err: err}
+		}
+		return conn, err
+	}
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package network
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindAddressNoPort откладывает выбор локального порта до connect: без этого bind на адрес
+// с портом 0 занимает порт сразу для всех целей и адрес исчерпывается на ~28 тысячах соединений.
+func bindAddressNoPort(network, address string, conn syscall.RawConn) error {
+	if network != "tcp4" && network != "tcp6" {
+		return nil
+	}
+	var sockErr error
+	err := conn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_BIND_ADDRESS_NO_PORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
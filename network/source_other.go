@@ -0,0 +1,10 @@
+//go:build !linux
+
+package network
+
+import "syscall"
+
+// bindAddressNoPort на платформах без IP_BIND_ADDRESS_NO_PORT ничего не делает: порт выбирается при bind.
+func bindAddressNoPort(network, address string, conn syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,601 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+const (
+	DefaultSSEMaxStreams     = 1000
+	DefaultSSEReconnectDelay = 1 * time.Second
+	sseDefaultEventType      = "message"
+	sseOtherEventType        = "other"
+	sseMaxEventTypes         = 100
+	sseResponseHeaderTimeout = 30 * time.Second
+	sseConnectTimeout        = 30 * time.Second
+)
+
+// SSEGenerator держит долгие text/event-stream соединения. Новые потоки открываются с частотой
+// CPS по паттерну, пока открытых не станет maxStreams. Когда сервер закрывает поток, он
+// переподключается с Last-Event-ID через паузу retry, как EventSource в браузере.
+type SSEGenerator struct {
+	targetURL      string
+	targetCPS      int
+	pattern        string
+	maxStreams     int
+	holdDuration   time.Duration
+	reconnectDelay time.Duration
+	enabled        bool
+	ctx            context.Context
+	stats          *SSEStats
+	workers        *workerPool
+	client         *http.Client
+	tlsConfig      *tls.Config
+	headers        http.Header
+	resolver       *Resolver
+	source         *SourceBinder
+	auth           AuthProvider
+	streams        int64
+	settingsMutex  sync.RWMutex
+	patternStart   time.Time
+}
+
+type SSEStats struct {
+	TotalConnections   int64
+	FailedConnections  int64
+	ActiveStreams      int64
+	EventsReceived     int64
+	BytesReceived      int64
+	Reconnects         int64
+	CurrentCPS         int64
+	DroppedConnections int64
+	LateConnections    int64
+	Workers            WorkerPoolStats
+	StartTime          time.Time
+	Latency            LatencyPercentiles
+	ServiceTime        LatencyPercentiles
+	InterArrival       LatencyPercentiles
+	EventTypes         map[string]int64
+	latency            *LatencyHistogram
+	serviceTime        *LatencyHistogram
+	interArrival       *LatencyHistogram
+	mutex              sync.RWMutex
+}
+
+// sseStream - состояние одного потока, переживающее переподключения.
+type sseStream struct {
+	lastEventID string
+	retry       time.Duration
+	lastEvent   time.Time
+}
+
+func NewSSEGenerator(targetURL string, targetCPS int, pattern string, maxStreams int) *SSEGenerator {
+	workerCount := 10
+	if targetCPS > 100 {
+		workerCount = targetCPS / 10
+		if workerCount > 50 {
+			workerCount = 50
+		}
+	}
+
+	sg := &SSEGenerator{
+		targetURL:      targetURL,
+		targetCPS:      targetCPS,
+		pattern:        pattern,
+		maxStreams:     maxStreams,
+		reconnectDelay: DefaultSSEReconnectDelay,
+		headers:        http.Header{},
+		client:         &http.Client{},
+		stats: &SSEStats{
+			StartTime:    time.Now(),
+			EventTypes:   make(map[string]int64),
+			latency:      NewLatencyHistogram(),
+			serviceTime:  NewLatencyHistogram(),
+			interArrival: NewLatencyHistogram(),
+		},
+	}
+	sg.patternStart = sg.stats.StartTime
+	sg.workers = newWorkerPool("SSE", "-sse-max-concurrency", targetCPS*4, workerCount, DefaultSSEMaxConcurrency, func(workerID int, intendedTime time.Time) {
+		sg.openStream(intendedTime)
+	})
+	return sg
+}
+
+// SetMaxConcurrency задаёт предел одновременно открываемых соединений; открытые потоки в него не входят.
+func (sg *SSEGenerator) SetMaxConcurrency(maxConcurrency int) {
+	sg.workers.setMaxWorkers(maxConcurrency)
+}
+
+// SetTargetCPS меняет целевое число новых потоков в секунду на ходу; открытые потоки не закрываются.
+func (sg *SSEGenerator) SetTargetCPS(cps int) {
+	sg.settingsMutex.Lock()
+	sg.targetCPS = cps
+	sg.settingsMutex.Unlock()
+}
+
+// SetPattern меняет паттерн открытия потоков на ходу; cycle и ramp отсчитываются заново с момента смены.
+func (sg *SSEGenerator) SetPattern(pattern string) {
+	sg.settingsMutex.Lock()
+	sg.pattern = pattern
+	sg.patternStart = time.Now()
+	sg.settingsMutex.Unlock()
+}
+
+// SetTargetURL меняет адрес для новых потоков и переподключений; открытые потоки продолжают работать.
+func (sg *SSEGenerator) SetTargetURL(targetURL string) {
+	sg.settingsMutex.Lock()
+	sg.targetURL = targetURL
+	sg.settingsMutex.Unlock()
+}
+
+// SetHoldDuration ограничивает время жизни потока; 0 - поток держится до конца теста.
+func (sg *SSEGenerator) SetHoldDuration(holdDuration time.Duration) {
+	sg.holdDuration = holdDuration
+}
+
+// SetReconnectDelay задаёт паузу перед переподключением, пока сервер не пришлёт свой retry.
+func (sg *SSEGenerator) SetReconnectDelay(delay time.Duration) {
+	sg.reconnectDelay = delay
+}
+
+// SetTLSConfig задаёт TLS настройки для https:// потоков.
+func (sg *SSEGenerator) SetTLSConfig(config *tls.Config) {
+	sg.tlsConfig = config
+}
+
+// SetResolver задаёт разрешение имён и раскладку новых соединений по IP; nil - системный резолвер.
+func (sg *SSEGenerator) SetResolver(resolver *Resolver) {
+	sg.resolver = resolver
+}
+
+// SetSource задаёт локальные адреса новых соединений; nil - адрес выбирает ядро.
+func (sg *SSEGenerator) SetSource(source *SourceBinder) {
+	sg.source = source
+}
+
+// SetAuth задаёт провайдер аутентификации для запросов на открытие потока.
+func (sg *SSEGenerator) SetAuth(auth AuthProvider) {
+	sg.auth = auth
+}
+
+func (sg *SSEGenerator) SetHeaders(headers map[string]string) {
+	sg.headers = http.Header{}
+	for key, value := range headers {
+		sg.headers.Set(key, value)
+	}
+}
+
+func (sg *SSEGenerator) target() (int, string, time.Time) {
+	sg.settingsMutex.RLock()
+	defer sg.settingsMutex.RUnlock()
+	return sg.targetCPS, sg.pattern, sg.patternStart
+}
+
+func (sg *SSEGenerator) Start(ctx context.Context) {
+	if sg.enabled {
+		return
+	}
+
+	sg.enabled = true
+	sg.ctx = ctx
+	dial := sg.source.dialer(&net.Dialer{Timeout: sseConnectTimeout})
+	if sg.resolver != nil {
+		dial = sg.resolver.dialer(dial)
+	}
+	sg.client.Transport = &http.Transport{
+		DialContext:           dial,
+		TLSClientConfig:       sg.tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: sseResponseHeaderTimeout,
+		MaxIdleConnsPerHost:   100,
+	}
+
+	logger.Info("Starting SSE load generator: %s, target CPS: %d, pattern: %s, max streams: %d",
+		sg.targetURL, sg.targetCPS, sg.pattern, sg.maxStreams)
+
+	sg.workers.start(ctx)
+
+	go sg.generateConnectionLoad()
+	go sg.statsCollector()
+}
+
+func (sg *SSEGenerator) Stop() {
+	if !sg.enabled {
+		return
+	}
+
+	sg.enabled = false
+
+	logger.Info("SSE load generator stopped")
+}
+
+func (sg *SSEGenerator) generateConnectionLoad() {
+	targetCPS, _, _ := sg.target()
+	tickInterval := 100 * time.Millisecond
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	lastSecond := time.Now().Unix()
+	connectionsThisSecond := 0
+
+	for {
+		select {
+		case <-sg.ctx.Done():
+			return
+		case tickTime := <-ticker.C:
+			currentSecond := time.Now().Unix()
+
+			if currentSecond != lastSecond {
+				lastSecond = currentSecond
+				connectionsThisSecond = 0
+			}
+
+			// Цель могла измениться через SetTargetCPS: очередь растёт под новый темп.
+			if newTargetCPS, _, _ := sg.target(); newTargetCPS != targetCPS {
+				targetCPS = newTargetCPS
+				sg.workers.resize(targetCPS * 4)
+			}
+
+			currentCPS := sg.calculateCurrentCPS()
+			connectionsToCreate := sg.calculateConnectionsToCreate(currentCPS, connectionsThisSecond)
+
+			// Место под поток занимается при постановке в очередь, чтобы не открыть больше maxStreams.
+			if free := int64(sg.maxStreams) - atomic.LoadInt64(&sg.streams); int64(connectionsToCreate) > free {
+				connectionsToCreate = int(free)
+			}
+
+			for i := 0; i < connectionsToCreate; i++ {
+				atomic.AddInt64(&sg.streams, 1)
+				if sg.workers.submit(scheduledTime(tickTime, tickInterval, i, connectionsToCreate)) {
+					connectionsThisSecond++
+				} else {
+					atomic.AddInt64(&sg.streams, -1)
+					atomic.AddInt64(&sg.stats.DroppedConnections, 1)
+					metrics.SSEDroppedConnectionsCounter.Inc()
+				}
+			}
+		}
+	}
+}
+
+func (sg *SSEGenerator) calculateCurrentCPS() int {
+	targetCPS, pattern, patternStart := sg.target()
+	switch pattern {
+	case "constant":
+		return targetCPS
+	case "spike":
+		if rand.Intn(10) == 0 {
+			return targetCPS * 3
+		}
+		return targetCPS
+	case "cycle":
+		elapsedSeconds := int(time.Since(patternStart).Seconds())
+		cyclePosition := (elapsedSeconds / 30) % 4
+
+		switch cyclePosition {
+		case 0:
+			return targetCPS / 4
+		case 1:
+			return targetCPS
+		case 2:
+			return targetCPS / 2
+		case 3:
+			return targetCPS / 8
+		}
+	case "ramp":
+		elapsedMinutes := int(time.Since(patternStart).Minutes())
+		rampMultiplier := float64(elapsedMinutes+1) * 0.2
+		if rampMultiplier > 1.0 {
+			rampMultiplier = 1.0
+		}
+		return int(float64(targetCPS) * rampMultiplier)
+	case "random":
+		variation := rand.Intn(140) + 10
+		return (targetCPS * variation) / 100
+	default:
+		return targetCPS
+	}
+	return targetCPS
+}
+
+func (sg *SSEGenerator) calculateConnectionsToCreate(currentCPS, connectionsThisSecond int) int {
+	connectionsPer100ms := currentCPS / 10
+	if connectionsPer100ms == 0 && currentCPS > 0 {
+		connectionsPer100ms = 1
+	}
+
+	remainingCPS := currentCPS - connectionsThisSecond
+	if remainingCPS < 0 {
+		remainingCPS = 0
+	}
+
+	if connectionsPer100ms > remainingCPS {
+		connectionsPer100ms = remainingCPS
+	}
+
+	return connectionsPer100ms
+}
+
+// openStream открывает поток в воркере и передаёт его в отдельную горутину, чтобы воркер не был занят всё время жизни потока.
+func (sg *SSEGenerator) openStream(intendedTime time.Time) {
+	if lag := waitForSchedule(sg.ctx, intendedTime); lag >= lateRequestThreshold {
+		atomic.AddInt64(&sg.stats.LateConnections, 1)
+		metrics.SSELateConnectionsCounter.Inc()
+	}
+
+	streamCtx, streamCancel := sg.ctx, context.CancelFunc(func() {})
+	if sg.holdDuration > 0 {
+		streamCtx, streamCancel = context.WithTimeout(sg.ctx, sg.holdDuration)
+	}
+
+	stream := &sseStream{retry: sg.reconnectDelay}
+	resp, err := sg.connect(streamCtx, stream, intendedTime)
+	if err != nil {
+		streamCancel()
+		atomic.AddInt64(&sg.streams, -1)
+		return
+	}
+
+	go func() {
+		defer func() {
+			streamCancel()
+			atomic.AddInt64(&sg.streams, -1)
+		}()
+		sg.holdStream(streamCtx, stream, resp)
+	}()
+}
+
+// holdStream читает события, пока поток не закроется, и переподключается с Last-Event-ID.
+// Неудачное переподключение завершает поток: вместо него по расписанию откроется новый.
+func (sg *SSEGenerator) holdStream(ctx context.Context, stream *sseStream, resp *http.Response) {
+	for {
+		atomic.AddInt64(&sg.stats.ActiveStreams, 1)
+		metrics.SSEActiveStreamsGauge.Inc()
+		err := sg.readEvents(resp.Body, stream)
+		resp.Body.Close()
+		atomic.AddInt64(&sg.stats.ActiveStreams, -1)
+		metrics.SSEActiveStreamsGauge.Dec()
+
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Debug("SSE stream closed: %v, reconnecting in %s", err, stream.retry)
+
+		timer := time.NewTimer(stream.retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		atomic.AddInt64(&sg.stats.Reconnects, 1)
+		metrics.SSEReconnectsCounter.Inc()
+		resp, err = sg.connect(ctx, stream, time.Now())
+		if err != nil {
+			return
+		}
+	}
+}
+
+// connect отправляет запрос потока и ждёт заголовков ответа; время считается от intendedTime и от начала запроса.
+func (sg *SSEGenerator) connect(ctx context.Context, stream *sseStream, intendedTime time.Time) (*http.Response, error) {
+	startTime := time.Now()
+
+	sg.settingsMutex.RLock()
+	targetURL := sg.targetURL
+	sg.settingsMutex.RUnlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		sg.recordFailure(time.Since(startTime), time.Since(intendedTime))
+		logger.Debug("Failed to create SSE request: %v", err)
+		return nil, err
+	}
+	req.Header = sg.headers.Clone()
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if stream.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", stream.lastEventID)
+	}
+	if sg.auth != nil {
+		if err := sg.auth.Authorize(&AuthRequest{Method: req.Method, URL: req.URL, Host: req.Host, Header: req.Header}); err != nil {
+			sg.recordFailure(time.Since(startTime), time.Since(intendedTime))
+			logger.Debug("Failed to authorize SSE request: %v", err)
+			return nil, err
+		}
+	}
+
+	resp, err := sg.client.Do(req)
+	if err == nil {
+		err = checkSSEResponse(resp)
+		if err != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			sg.recordFailure(time.Since(startTime), time.Since(intendedTime))
+			logger.Debug("SSE connection failed: %v", err)
+		}
+		return nil, err
+	}
+
+	sg.recordSuccess(time.Since(startTime), time.Since(intendedTime))
+	return resp, nil
+}
+
+// checkSSEResponse проверяет, что сервер открыл поток событий: 200 и Content-Type text/event-stream.
+func checkSSEResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/event-stream" {
+		return fmt.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	return nil
+}
+
+// readEvents разбирает поток по формату text/event-stream. Событие отправляется по пустой
+// строке, если в нём было поле data; id и retry запоминаются для переподключения.
+func (sg *SSEGenerator) readEvents(body io.Reader, stream *sseStream) error {
+	reader := bufio.NewReader(body)
+	eventType := ""
+	hasData := false
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&sg.stats.BytesReceived, int64(len(line)))
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if hasData {
+				sg.recordEvent(stream, eventType)
+			}
+			eventType, hasData = "", false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				stream.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				stream.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// recordEvent учитывает событие; интервал считается от предыдущего события потока, в том числе через переподключение.
+func (sg *SSEGenerator) recordEvent(stream *sseStream, eventType string) {
+	now := time.Now()
+	if !stream.lastEvent.IsZero() {
+		interArrival := now.Sub(stream.lastEvent)
+		sg.stats.interArrival.Record(interArrival)
+		metrics.SSEEventInterArrivalHistogram.Observe(interArrival.Seconds())
+	}
+	stream.lastEvent = now
+
+	if eventType == "" {
+		eventType = sseDefaultEventType
+	}
+	atomic.AddInt64(&sg.stats.EventsReceived, 1)
+	metrics.SSEEventsReceivedCounter.Inc()
+
+	sg.stats.mutex.Lock()
+	if _, ok := sg.stats.EventTypes[eventType]; !ok && len(sg.stats.EventTypes) >= sseMaxEventTypes {
+		eventType = sseOtherEventType
+	}
+	sg.stats.EventTypes[eventType]++
+	sg.stats.mutex.Unlock()
+}
+
+func (sg *SSEGenerator) recordSuccess(serviceTime, responseTime time.Duration) {
+	atomic.AddInt64(&sg.stats.TotalConnections, 1)
+	metrics.SSEConnectionsCounter.WithLabelValues("success").Inc()
+	sg.recordLatency(serviceTime, responseTime)
+}
+
+func (sg *SSEGenerator) recordFailure(serviceTime, responseTime time.Duration) {
+	atomic.AddInt64(&sg.stats.TotalConnections, 1)
+	atomic.AddInt64(&sg.stats.FailedConnections, 1)
+	metrics.SSEConnectionsCounter.WithLabelValues("failed").Inc()
+	sg.recordLatency(serviceTime, responseTime)
+}
+
+func (sg *SSEGenerator) recordLatency(serviceTime, responseTime time.Duration) {
+	sg.stats.serviceTime.Record(serviceTime)
+	sg.stats.latency.Record(responseTime)
+	metrics.SSEConnectTimeHistogram.Observe(responseTime.Seconds())
+}
+
+func (sg *SSEGenerator) statsCollector() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	lastConnections := int64(0)
+
+	for {
+		select {
+		case <-sg.ctx.Done():
+			return
+		case <-ticker.C:
+			currentConnections := atomic.LoadInt64(&sg.stats.TotalConnections)
+			atomic.StoreInt64(&sg.stats.CurrentCPS, currentConnections-lastConnections)
+			lastConnections = currentConnections
+			sg.workers.checkBottleneck(atomic.LoadInt64(&sg.stats.DroppedConnections), atomic.LoadInt64(&sg.stats.LateConnections))
+		}
+	}
+}
+
+func (sg *SSEGenerator) GetStats() *SSEStats {
+	sg.stats.mutex.RLock()
+	eventTypes := make(map[string]int64, len(sg.stats.EventTypes))
+	for eventType, count := range sg.stats.EventTypes {
+		eventTypes[eventType] = count
+	}
+	sg.stats.mutex.RUnlock()
+
+	return &SSEStats{
+		TotalConnections:   atomic.LoadInt64(&sg.stats.TotalConnections),
+		FailedConnections:  atomic.LoadInt64(&sg.stats.FailedConnections),
+		ActiveStreams:      atomic.LoadInt64(&sg.stats.ActiveStreams),
+		EventsReceived:     atomic.LoadInt64(&sg.stats.EventsReceived),
+		BytesReceived:      atomic.LoadInt64(&sg.stats.BytesReceived),
+		Reconnects:         atomic.LoadInt64(&sg.stats.Reconnects),
+		CurrentCPS:         atomic.LoadInt64(&sg.stats.CurrentCPS),
+		DroppedConnections: atomic.LoadInt64(&sg.stats.DroppedConnections),
+		LateConnections:    atomic.LoadInt64(&sg.stats.LateConnections),
+		Workers:            sg.workers.stats(),
+		StartTime:          sg.stats.StartTime,
+		Latency:            sg.stats.latency.Percentiles(),
+		ServiceTime:        sg.stats.serviceTime.Percentiles(),
+		InterArrival:       sg.stats.interArrival.Percentiles(),
+		EventTypes:         eventTypes,
+	}
+}
+
+func (sg *SSEGenerator) GetLatencyHistogram() *LatencyHistogram {
+	return sg.stats.latency.Snapshot()
+}
+
+func (sg *SSEGenerator) GetSuccessRate() float64 {
+	stats := sg.GetStats()
+	if stats.TotalConnections == 0 {
+		return 0
+	}
+	successful := stats.TotalConnections - stats.FailedConnections
+	return float64(successful) / float64(stats.TotalConnections) * 100.0
+}
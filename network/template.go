@@ -0,0 +1,183 @@
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// TemplateData - то, что доступно в шаблоне через точку: {{.Data.user}}, {{.Vars.token}}, {{.Seq}}.
+type TemplateData struct {
+	Data map[string]string
+	Vars map[string]string
+	Seq  int64
+}
+
+// TemplateEngine компилирует шаблоны запросов на text/template и добавляет функции
+// uuid, seq, randInt, pick, now и timestamp. Строки данных берутся из DataFeeder, если он задан.
+type TemplateEngine struct {
+	feeder *DataFeeder
+	seq    int64 // номера запросов для .Seq и {{seq}}
+	funcs  template.FuncMap
+}
+
+// TextTemplate - скомпилированный шаблон; строка без {{ }} отдаётся как есть без выполнения шаблона.
+type TextTemplate struct {
+	raw  string
+	tmpl *template.Template
+}
+
+func NewTemplateEngine(feeder *DataFeeder) *TemplateEngine {
+	te := &TemplateEngine{feeder: feeder}
+	te.funcs = template.FuncMap{
+		"uuid": newUUID,
+		// Нужна только для разбора: Compile заменяет {{seq}} на $.Seq, номер запроса из NewData.
+		"seq": func() int64 {
+			return 0
+		},
+		"randInt": func(min, max int) int {
+			if max <= min {
+				return min
+			}
+			return min + mathrand.Intn(max-min+1)
+		},
+		"pick": func(values ...string) string {
+			if len(values) == 0 {
+				return ""
+			}
+			return values[mathrand.Intn(len(values))]
+		},
+		"now": func() string {
+			return time.Now().Format(time.RFC3339)
+		},
+		"timestamp": func() int64 {
+			return time.Now().UnixMilli()
+		},
+	}
+	return te
+}
+
+func (te *TemplateEngine) Compile(name, text string) (*TextTemplate, error) {
+	if !strings.Contains(text, "{{") {
+		return &TextTemplate{raw: text}, nil
+	}
+
+	tmpl, err := template.New(name).Funcs(te.funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %v", name, err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			bindSeq(t.Tree.Root)
+		}
+	}
+
+	return &TextTemplate{raw: text, tmpl: tmpl}, nil
+}
+
+// bindSeq заменяет вызовы seq на $.Seq, чтобы {{seq}} в URL, заголовках и теле одного запроса
+// давал тот же номер, что и {{.Seq}}. $ - корень данных, поэтому замена работает и внутри range и with.
+func bindSeq(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			bindSeq(child)
+		}
+	case *parse.ActionNode:
+		bindSeq(n.Pipe)
+	case *parse.IfNode:
+		bindSeq(&n.BranchNode)
+	case *parse.RangeNode:
+		bindSeq(&n.BranchNode)
+	case *parse.WithNode:
+		bindSeq(&n.BranchNode)
+	case *parse.BranchNode:
+		bindSeq(n.Pipe)
+		bindSeq(n.List)
+		bindSeq(n.ElseList)
+	case *parse.TemplateNode:
+		bindSeq(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			bindSeq(cmd)
+		}
+	case *parse.ChainNode:
+		bindSeq(n.Node)
+	case *parse.CommandNode:
+		for i, arg := range n.Args {
+			if ident, ok := arg.(*parse.IdentifierNode); ok && ident.Ident == "seq" {
+				n.Args[i] = &parse.VariableNode{NodeType: parse.NodeVariable, Pos: ident.Pos, Ident: []string{"$", "Seq"}}
+				continue
+			}
+			bindSeq(arg)
+		}
+	}
+}
+
+// NewData готовит данные для одного запроса: берёт очередную строку из DataFeeder
+// и номер запроса, общий для URL, заголовков и тела.
+func (te *TemplateEngine) NewData(vars map[string]string) (*TemplateData, error) {
+	data := &TemplateData{
+		Vars: vars,
+		Seq:  atomic.AddInt64(&te.seq, 1),
+	}
+
+	if te.feeder != nil {
+		row, err := te.feeder.Next()
+		if err != nil {
+			return nil, err
+		}
+		data.Data = row
+	}
+
+	return data, nil
+}
+
+func (te *TemplateEngine) HasFeeder() bool {
+	return te.feeder != nil
+}
+
+func (t *TextTemplate) IsStatic() bool {
+	return t == nil || t.tmpl == nil
+}
+
+func (t *TextTemplate) String() string {
+	if t == nil {
+		return ""
+	}
+	return t.raw
+}
+
+func (t *TextTemplate) Render(data *TemplateData) (string, error) {
+	if t.IsStatic() {
+		return t.String(), nil
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		mathrand.Read(b[:])
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
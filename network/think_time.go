@@ -0,0 +1,238 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ThinkTimeConstant    = "constant"
+	ThinkTimeUniform     = "uniform"
+	ThinkTimeExponential = "exponential"
+	ThinkTimeNormal      = "normal"
+)
+
+// ThinkTime - распределение паузы виртуального пользователя между запросами.
+// constant использует Mean, uniform - диапазон Min..Max, exponential - среднее Mean,
+// normal - Mean и StdDev. Min и Max ограничивают exponential и normal, если заданы.
+type ThinkTime struct {
+	Distribution string   `json:"distribution"`
+	Mean         Duration `json:"mean,omitempty"`
+	Min          Duration `json:"min,omitempty"`
+	Max          Duration `json:"max,omitempty"`
+	StdDev       Duration `json:"stdDev,omitempty"`
+}
+
+// Stage - ступень нагрузки: за Duration количество пользователей линейно меняется до Users.
+type Stage struct {
+	Duration Duration `json:"duration"`
+	Users    int      `json:"users"`
+}
+
+// ParseThinkTime разбирает паузу из строки: "2s", "uniform:2s-5s", "exponential:3s", "normal:3s,500ms".
+func ParseThinkTime(value string) (*ThinkTime, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	distribution, params, found := strings.Cut(value, ":")
+	if !found {
+		distribution, params = ThinkTimeConstant, value
+	}
+
+	think := &ThinkTime{Distribution: strings.ToLower(distribution)}
+	var err error
+
+	switch think.Distribution {
+	case ThinkTimeConstant, ThinkTimeExponential:
+		think.Mean, err = parseThinkDuration(params)
+	case ThinkTimeUniform:
+		low, high, ok := strings.Cut(params, "-")
+		if !ok {
+			return nil, fmt.Errorf("uniform think time must be min-max: %s", value)
+		}
+		if think.Min, err = parseThinkDuration(low); err == nil {
+			think.Max, err = parseThinkDuration(high)
+		}
+	case ThinkTimeNormal:
+		mean, stdDev, ok := strings.Cut(params, ",")
+		if !ok {
+			return nil, fmt.Errorf("normal think time must be mean,stddev: %s", value)
+		}
+		if think.Mean, err = parseThinkDuration(mean); err == nil {
+			think.StdDev, err = parseThinkDuration(stdDev)
+		}
+	default:
+		return nil, fmt.Errorf("unknown think time distribution: %s", distribution)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := think.validate(); err != nil {
+		return nil, err
+	}
+	return think, nil
+}
+
+func parseThinkDuration(value string) (Duration, error) {
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid think time %q: %v", value, err)
+	}
+	return Duration(parsed), nil
+}
+
+// UnmarshalJSON принимает строку в формате ParseThinkTime, число миллисекунд или объект.
+func (t *ThinkTime) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*t = ThinkTime{Distribution: ThinkTimeConstant, Mean: Duration(v * float64(time.Millisecond))}
+		return nil
+	case string:
+		parsed, err := ParseThinkTime(v)
+		if err != nil {
+			return err
+		}
+		if parsed == nil {
+			parsed = &ThinkTime{Distribution: ThinkTimeConstant}
+		}
+		*t = *parsed
+		return nil
+	}
+
+	type plain ThinkTime
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("invalid think time: %v", err)
+	}
+	*t = ThinkTime(decoded)
+	if t.Distribution == "" {
+		t.Distribution = ThinkTimeConstant
+	}
+	t.Distribution = strings.ToLower(t.Distribution)
+	return t.validate()
+}
+
+func (t *ThinkTime) validate() error {
+	if t.Mean < 0 || t.Min < 0 || t.Max < 0 || t.StdDev < 0 {
+		return fmt.Errorf("think time must not be negative")
+	}
+
+	switch t.Distribution {
+	case ThinkTimeConstant, ThinkTimeExponential, ThinkTimeNormal:
+		if t.Max > 0 && t.Max < t.Min {
+			return fmt.Errorf("think time max %s is less than min %s", time.Duration(t.Max), time.Duration(t.Min))
+		}
+	case ThinkTimeUniform:
+		if t.Max < t.Min {
+			return fmt.Errorf("think time max %s is less than min %s", time.Duration(t.Max), time.Duration(t.Min))
+		}
+	default:
+		return fmt.Errorf("unknown think time distribution: %s", t.Distribution)
+	}
+	return nil
+}
+
+// Next возвращает очередную паузу согласно распределению.
+func (t *ThinkTime) Next() time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	var d time.Duration
+	switch t.Distribution {
+	case ThinkTimeUniform:
+		d = time.Duration(t.Min)
+		if spread := int64(t.Max - t.Min); spread > 0 {
+			d += time.Duration(rand.Int63n(spread + 1))
+		}
+		return d
+	case ThinkTimeExponential:
+		d = time.Duration(rand.ExpFloat64() * float64(t.Mean))
+	case ThinkTimeNormal:
+		d = time.Duration(rand.NormFloat64()*float64(t.StdDev) + float64(t.Mean))
+	default:
+		return time.Duration(t.Mean)
+	}
+
+	if d < time.Duration(t.Min) {
+		d = time.Duration(t.Min)
+	}
+	if t.Max > 0 && d > time.Duration(t.Max) {
+		d = time.Duration(t.Max)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+func (t *ThinkTime) String() string {
+	if t == nil {
+		return "none"
+	}
+	switch t.Distribution {
+	case ThinkTimeUniform:
+		return fmt.Sprintf("uniform %s-%s", time.Duration(t.Min), time.Duration(t.Max))
+	case ThinkTimeExponential:
+		return fmt.Sprintf("exponential mean %s", time.Duration(t.Mean))
+	case ThinkTimeNormal:
+		return fmt.Sprintf("normal %s±%s", time.Duration(t.Mean), time.Duration(t.StdDev))
+	default:
+		return time.Duration(t.Mean).String()
+	}
+}
+
+// ParseStages разбирает ступени вида "30s:100,2m:500,30s:0".
+func ParseStages(value string) ([]Stage, error) {
+	var stages []Stage
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		duration, users, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("stage must be duration:users: %s", part)
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid stage duration: %s", duration)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(users))
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid stage users: %s", users)
+		}
+
+		stages = append(stages, Stage{Duration: Duration(parsed), Users: count})
+	}
+	return stages, nil
+}
+
+// stageUsers возвращает число пользователей через elapsed от начала ступеней.
+// После последней ступени держится её количество пользователей.
+func stageUsers(stages []Stage, elapsed time.Duration) int {
+	previous := 0
+	for _, stage := range stages {
+		duration := time.Duration(stage.Duration)
+		if elapsed < duration {
+			progress := float64(elapsed) / float64(duration)
+			return previous + int(float64(stage.Users-previous)*progress)
+		}
+		elapsed -= duration
+		previous = stage.Users
+	}
+	return previous
+}
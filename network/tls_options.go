@@ -0,0 +1,200 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Размер кэша TLS сессий клиента при включённом возобновлении handshake.
+const tlsSessionCacheSize = 1024
+
+// TLSOptions - общие настройки TLS для всех сетевых генераторов.
+// Пустые поля оставляют значения Go по умолчанию.
+type TLSOptions struct {
+	CAFile             string   `json:"caFile,omitempty"`
+	CertFile           string   `json:"certFile,omitempty"`
+	KeyFile            string   `json:"keyFile,omitempty"`
+	ServerName         string   `json:"serverName,omitempty"`
+	MinVersion         string   `json:"minVersion,omitempty"`
+	MaxVersion         string   `json:"maxVersion,omitempty"`
+	CipherSuites       []string `json:"cipherSuites,omitempty"`
+	ALPN               []string `json:"alpn,omitempty"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify,omitempty"`
+	DisableResumption  bool     `json:"disableResumption,omitempty"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion разбирает версию вида "1.2" или "tls1.2".
+func ParseTLSVersion(value string) (uint16, error) {
+	version := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "tls")
+	if parsed, ok := tlsVersions[strings.TrimPrefix(version, "v")]; ok {
+		return parsed, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version: %s", value)
+}
+
+// ParseCipherSuites переводит имена шифров (TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) в идентификаторы.
+// Шифры TLS 1.3 в Go не настраиваются, поэтому влияют только на TLS 1.2 и ниже.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SplitList разбивает список через запятую, пропуская пустые элементы.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate проверяет настройки без чтения файлов.
+func (o *TLSOptions) Validate() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("client certificate and key must be set together")
+	}
+
+	var minVersion, maxVersion uint16
+	var err error
+	if o.MinVersion != "" {
+		if minVersion, err = ParseTLSVersion(o.MinVersion); err != nil {
+			return err
+		}
+	}
+	if o.MaxVersion != "" {
+		if maxVersion, err = ParseTLSVersion(o.MaxVersion); err != nil {
+			return err
+		}
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		return fmt.Errorf("TLS min version %s is higher than max version %s", o.MinVersion, o.MaxVersion)
+	}
+
+	if _, err := ParseCipherSuites(o.CipherSuites); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Build собирает tls.Config: читает CA и клиентский сертификат, задаёт версии, шифры и ALPN.
+// При включённом возобновлении клиент кэширует сессии, при выключенном каждый handshake полный.
+func (o *TLSOptions) Build() (*tls.Config, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		NextProtos:         o.ALPN,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if o.MinVersion != "" {
+		config.MinVersion, _ = ParseTLSVersion(o.MinVersion)
+	}
+	if o.MaxVersion != "" {
+		config.MaxVersion, _ = ParseTLSVersion(o.MaxVersion)
+	}
+	if len(o.CipherSuites) > 0 {
+		config.CipherSuites, _ = ParseCipherSuites(o.CipherSuites)
+	}
+
+	if o.DisableResumption {
+		config.SessionTicketsDisabled = true
+	} else {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(tlsSessionCacheSize)
+	}
+
+	return config, nil
+}
+
+// Describe кратко описывает настройки для логов.
+func (o *TLSOptions) Describe() string {
+	var parts []string
+	if o.CAFile != "" {
+		parts = append(parts, "ca="+o.CAFile)
+	}
+	if o.CertFile != "" {
+		parts = append(parts, "client cert="+o.CertFile)
+	}
+	if o.ServerName != "" {
+		parts = append(parts, "sni="+o.ServerName)
+	}
+	if o.MinVersion != "" || o.MaxVersion != "" {
+		parts = append(parts, fmt.Sprintf("versions=%s-%s", o.MinVersion, o.MaxVersion))
+	}
+	if len(o.CipherSuites) > 0 {
+		parts = append(parts, fmt.Sprintf("ciphers=%d", len(o.CipherSuites)))
+	}
+	if len(o.ALPN) > 0 {
+		parts = append(parts, "alpn="+strings.Join(o.ALPN, ","))
+	}
+	if o.InsecureSkipVerify {
+		parts = append(parts, "skip verify")
+	}
+	parts = append(parts, fmt.Sprintf("resumption=%t", !o.DisableResumption))
+	return strings.Join(parts, ", ")
+}
+
+// tlsConfigForHost копирует общую конфигурацию и подставляет SNI по адресу, если он не задан явно.
+func tlsConfigForHost(config *tls.Config, host string) *tls.Config {
+	if config == nil {
+		return &tls.Config{ServerName: host}
+	}
+	cloned := config.Clone()
+	if cloned.ServerName == "" {
+		cloned.ServerName = host
+	}
+	return cloned
+}
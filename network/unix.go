@@ -0,0 +1,46 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// unixHost - хост в URL запросов к unix сокету; заголовок Host можно переопределить через заголовки запроса.
+const unixHost = "localhost"
+
+// IsUnixURL сообщает, что цель - unix сокет: unix:///path/to.sock.
+func IsUnixURL(target string) bool {
+	return strings.HasPrefix(target, "unix:")
+}
+
+// SplitUnixURL разбирает HTTP цель на unix сокете. Путь запроса отделяется двоеточием, как в nginx:
+// "unix:///run/envoy.sock:/stats?format=json" - сокет /run/envoy.sock и запрос
+// http://localhost/stats?format=json. Без пути запрос идёт на "/". Для обычных URL
+// возвращается пустой сокет и URL без изменений.
+func SplitUnixURL(target string) (socketPath, requestURL string, err error) {
+	if !IsUnixURL(target) {
+		return "", target, nil
+	}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(target, "unix:"), "//")
+	requestURI := "/"
+	if i := strings.Index(rest, ":"); i >= 0 {
+		rest, requestURI = rest[:i], rest[i+1:]
+	}
+	if !strings.HasPrefix(rest, "/") {
+		return "", "", fmt.Errorf("invalid unix socket target %q, expected unix:///path/to.sock[:/request/path]", target)
+	}
+	if !strings.HasPrefix(requestURI, "/") {
+		return "", "", fmt.Errorf("invalid request path %q in unix socket target %q, expected it to start with /", requestURI, target)
+	}
+	return rest, "http://" + unixHost + requestURI, nil
+}
+
+// unixDialer направляет все соединения в сокет, адрес из запроса не используется.
+func unixDialer(socketPath string, dial dialFunc) dialFunc {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dial(ctx, "unix", socketPath)
+	}
+}
@@ -2,7 +2,9 @@ package network
 
 import (
 	"context"
+	"crypto/tls"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"sync"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"stresspulse/logger"
+	"stresspulse/metrics"
 )
 
 type WebSocketGenerator struct {
@@ -23,10 +26,18 @@ type WebSocketGenerator struct {
 	ctx              context.Context
 	cancel           context.CancelFunc
 	stats            *WebSocketStats
-	connectionChan   chan struct{}
-	workerCount      int
+	workers          *workerPool
 	headers          http.Header
 	dialer           *websocket.Dialer
+	proxy            ProxyOptions
+	resolver         *Resolver
+	source           *SourceBinder
+	auth             AuthProvider
+	templates        *TemplateEngine
+	message          *TextTemplate
+	script           *webSocketScript
+	settingsMutex    sync.RWMutex
+	patternStart     time.Time
 }
 
 type WebSocketStats struct {
@@ -39,7 +50,18 @@ type WebSocketStats struct {
 	MinResponseTime     time.Duration
 	MaxResponseTime     time.Duration
 	CurrentCPS          int64
+	DroppedConnections  int64
+	LateConnections     int64
+	Workers             WorkerPoolStats
 	StartTime           time.Time
+	Latency             LatencyPercentiles
+	ServiceTime         LatencyPercentiles
+	ProxyConnect        LatencyPercentiles
+	Addresses           map[string]int64
+	Steps               []WebSocketStepStats
+	latency             *LatencyHistogram
+	serviceTime         *LatencyHistogram
+	proxyConnect        *LatencyHistogram
 	mutex               sync.RWMutex
 }
 
@@ -52,16 +74,15 @@ func NewWebSocketGenerator(targetURL string, targetCPS int, pattern string, mess
 		}
 	}
 
-	return &WebSocketGenerator{
+	wsg := &WebSocketGenerator{
 		targetURL:       targetURL,
 		targetCPS:       targetCPS,
 		pattern:         pattern,
 		messageInterval: messageInterval,
 		messageSize:     messageSize,
 		enabled:         false,
-		connectionChan:  make(chan struct{}, targetCPS*4),
-		workerCount:     workerCount,
 		headers:         http.Header{},
+		templates:       NewTemplateEngine(nil),
 		dialer: &websocket.Dialer{
 			HandshakeTimeout: 30 * time.Second,
 			ReadBufferSize:   4096,
@@ -70,8 +91,84 @@ func NewWebSocketGenerator(targetURL string, targetCPS int, pattern string, mess
 		stats: &WebSocketStats{
 			StartTime:       time.Now(),
 			MinResponseTime: time.Hour,
+			Addresses:       make(map[string]int64),
+			latency:         NewLatencyHistogram(),
+			serviceTime:     NewLatencyHistogram(),
+			proxyConnect:    NewLatencyHistogram(),
 		},
 	}
+	wsg.patternStart = wsg.stats.StartTime
+	wsg.workers = newWorkerPool("WebSocket", "-websocket-max-concurrency", targetCPS*4, workerCount, DefaultWebSocketMaxConcurrency, func(workerID int, intendedTime time.Time) {
+		wsg.createConnection(intendedTime)
+	})
+	return wsg
+}
+
+// SetMaxConcurrency задаёт предел одновременно открываемых соединений; пул воркеров растёт до него по мере нужды.
+func (wsg *WebSocketGenerator) SetMaxConcurrency(maxConcurrency int) {
+	wsg.workers.setMaxWorkers(maxConcurrency)
+}
+
+// SetTargetCPS меняет целевое число новых соединений в секунду на ходу; открытые соединения не закрываются.
+func (wsg *WebSocketGenerator) SetTargetCPS(cps int) {
+	wsg.settingsMutex.Lock()
+	wsg.targetCPS = cps
+	wsg.settingsMutex.Unlock()
+}
+
+// SetPattern меняет паттерн подключений на ходу; cycle и ramp отсчитываются заново с момента смены.
+func (wsg *WebSocketGenerator) SetPattern(pattern string) {
+	wsg.settingsMutex.Lock()
+	wsg.pattern = pattern
+	wsg.patternStart = time.Now()
+	wsg.settingsMutex.Unlock()
+}
+
+// SetTargetURL меняет адрес для новых соединений; уже открытые соединения продолжают работать.
+func (wsg *WebSocketGenerator) SetTargetURL(targetURL string) {
+	wsg.settingsMutex.Lock()
+	wsg.targetURL = targetURL
+	wsg.settingsMutex.Unlock()
+}
+
+func (wsg *WebSocketGenerator) TargetCPS() int {
+	targetCPS, _, _ := wsg.target()
+	return targetCPS
+}
+
+func (wsg *WebSocketGenerator) target() (int, string, time.Time) {
+	wsg.settingsMutex.RLock()
+	defer wsg.settingsMutex.RUnlock()
+	return wsg.targetCPS, wsg.pattern, wsg.patternStart
+}
+
+// SetTLSConfig задаёт TLS настройки для wss:// подключений.
+func (wsg *WebSocketGenerator) SetTLSConfig(config *tls.Config) {
+	wsg.dialer.TLSClientConfig = config
+}
+
+// SetProxy направляет соединения через upstream прокси; применяется до Start.
+func (wsg *WebSocketGenerator) SetProxy(options *ProxyOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	wsg.proxy = *options
+	return nil
+}
+
+// SetResolver задаёт разрешение имён и раскладку новых соединений по IP; nil - системный резолвер.
+func (wsg *WebSocketGenerator) SetResolver(resolver *Resolver) {
+	wsg.resolver = resolver
+}
+
+// SetSource задаёт локальные адреса новых соединений; nil - адрес выбирает ядро.
+func (wsg *WebSocketGenerator) SetSource(source *SourceBinder) {
+	wsg.source = source
+}
+
+// SetAuth задаёт провайдер аутентификации для запроса на открытие соединения.
+func (wsg *WebSocketGenerator) SetAuth(auth AuthProvider) {
+	wsg.auth = auth
 }
 
 func (wsg *WebSocketGenerator) SetHeaders(headers map[string]string) {
@@ -81,6 +178,20 @@ func (wsg *WebSocketGenerator) SetHeaders(headers map[string]string) {
 	}
 }
 
+// SetMessageTemplate задаёт шаблон сообщения вместо заполнителя размером messageSize.
+func (wsg *WebSocketGenerator) SetMessageTemplate(message string) error {
+	compiled, err := wsg.templates.Compile("message", message)
+	if err != nil {
+		return err
+	}
+	wsg.message = compiled
+	return nil
+}
+
+func (wsg *WebSocketGenerator) SetDataFeeder(feeder *DataFeeder) {
+	wsg.templates.feeder = feeder
+}
+
 func (wsg *WebSocketGenerator) Start(ctx context.Context) {
 	if wsg.enabled {
 		return
@@ -88,13 +199,21 @@ func (wsg *WebSocketGenerator) Start(ctx context.Context) {
 
 	wsg.enabled = true
 	wsg.ctx = ctx
+	if wsg.resolver != nil || wsg.proxy.enabled() || wsg.source != nil {
+		dial := wsg.source.dialer(&net.Dialer{Timeout: 30 * time.Second})
+		if wsg.resolver != nil {
+			dial = wsg.resolver.dialer(dial)
+		}
+		if wsg.proxy.enabled() {
+			dial = wsg.proxy.dialer(dial, wsg.dialer.TLSClientConfig, wsg.resolver, wsg.recordProxyConnect)
+		}
+		wsg.dialer.NetDialContext = dial
+	}
 	
 	logger.Info("Starting WebSocket load generator: %s, target CPS: %d, pattern: %s", 
 		wsg.targetURL, wsg.targetCPS, wsg.pattern)
 	
-	for i := 0; i < wsg.workerCount; i++ {
-		go wsg.websocketWorker(i)
-	}
+	wsg.workers.start(ctx)
 	
 	go wsg.generateConnectionLoad()
 	go wsg.statsCollector()
@@ -111,7 +230,9 @@ func (wsg *WebSocketGenerator) Stop() {
 }
 
 func (wsg *WebSocketGenerator) generateConnectionLoad() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+	targetCPS, _, _ := wsg.target()
+	tickInterval := 100 * time.Millisecond
+	ticker := time.NewTicker(tickInterval)
 	defer ticker.Stop()
 
 	lastSecond := time.Now().Unix()
@@ -121,7 +242,7 @@ func (wsg *WebSocketGenerator) generateConnectionLoad() {
 		select {
 		case <-wsg.ctx.Done():
 			return
-		case <-ticker.C:
+		case tickTime := <-ticker.C:
 			currentSecond := time.Now().Unix()
 			
 			if currentSecond != lastSecond {
@@ -129,14 +250,21 @@ func (wsg *WebSocketGenerator) generateConnectionLoad() {
 				connectionsThisSecond = 0
 			}
 			
+			// Цель могла измениться через SetTargetCPS: очередь растёт под новый темп.
+			if newTargetCPS, _, _ := wsg.target(); newTargetCPS != targetCPS {
+				targetCPS = newTargetCPS
+				wsg.workers.resize(targetCPS * 4)
+			}
+			
 			currentCPS := wsg.calculateCurrentCPS()
 			connectionsToCreate := wsg.calculateConnectionsToCreate(currentCPS, connectionsThisSecond)
 			
 			for i := 0; i < connectionsToCreate; i++ {
-				select {
-				case wsg.connectionChan <- struct{}{}:
+				if wsg.workers.submit(scheduledTime(tickTime, tickInterval, i, connectionsToCreate)) {
 					connectionsThisSecond++
-				default:
+				} else {
+					atomic.AddInt64(&wsg.stats.DroppedConnections, 1)
+					metrics.WebSocketDroppedConnectionsCounter.Inc()
 				}
 			}
 		}
@@ -144,42 +272,43 @@ func (wsg *WebSocketGenerator) generateConnectionLoad() {
 }
 
 func (wsg *WebSocketGenerator) calculateCurrentCPS() int {
-	switch wsg.pattern {
+	targetCPS, pattern, patternStart := wsg.target()
+	switch pattern {
 	case "constant":
-		return wsg.targetCPS
+		return targetCPS
 	case "spike":
 		if rand.Intn(10) == 0 {
-			return wsg.targetCPS * 3
+			return targetCPS * 3
 		}
-		return wsg.targetCPS
+		return targetCPS
 	case "cycle":
-		elapsedSeconds := int(time.Since(wsg.stats.StartTime).Seconds())
+		elapsedSeconds := int(time.Since(patternStart).Seconds())
 		cyclePosition := (elapsedSeconds / 30) % 4
 		
 		switch cyclePosition {
 		case 0:
-			return wsg.targetCPS / 4
+			return targetCPS / 4
 		case 1:
-			return wsg.targetCPS
+			return targetCPS
 		case 2:
-			return wsg.targetCPS / 2
+			return targetCPS / 2
 		case 3:
-			return wsg.targetCPS / 8
+			return targetCPS / 8
 		}
 	case "ramp":
-		elapsedMinutes := int(time.Since(wsg.stats.StartTime).Minutes())
+		elapsedMinutes := int(time.Since(patternStart).Minutes())
 		rampMultiplier := float64(elapsedMinutes+1) * 0.2
 		if rampMultiplier > 1.0 {
 			rampMultiplier = 1.0
 		}
-		return int(float64(wsg.targetCPS) * rampMultiplier)
+		return int(float64(targetCPS) * rampMultiplier)
 	case "random":
 		variation := rand.Intn(140) + 10
-		return (wsg.targetCPS * variation) / 100
+		return (targetCPS * variation) / 100
 	default:
-		return wsg.targetCPS
+		return targetCPS
 	}
-	return wsg.targetCPS
+	return targetCPS
 }
 
 func (wsg *WebSocketGenerator) calculateConnectionsToCreate(currentCPS, connectionsThisSecond int) int {
@@ -197,35 +326,41 @@ func (wsg *WebSocketGenerator) calculateConnectionsToCreate(currentCPS, connecti
 	return connectionsPer100ms
 }
 
-func (wsg *WebSocketGenerator) websocketWorker(workerID int) {
-	logger.Debug("WebSocket worker %d started", workerID)
-	defer logger.Debug("WebSocket worker %d stopped", workerID)
-
-	for {
-		select {
-		case <-wsg.ctx.Done():
-			return
-		case <-wsg.connectionChan:
-			wsg.createConnection()
-		}
+func (wsg *WebSocketGenerator) createConnection(intendedTime time.Time) {
+	if lag := waitForSchedule(wsg.ctx, intendedTime); lag >= lateRequestThreshold {
+		atomic.AddInt64(&wsg.stats.LateConnections, 1)
+		metrics.WebSocketLateConnectionsCounter.Inc()
 	}
-}
-
-func (wsg *WebSocketGenerator) createConnection() {
+	
 	startTime := time.Now()
 	
-	u, err := url.Parse(wsg.targetURL)
+	wsg.settingsMutex.RLock()
+	targetURL := wsg.targetURL
+	wsg.settingsMutex.RUnlock()
+	
+	u, err := url.Parse(targetURL)
 	if err != nil {
-		wsg.recordFailure(time.Since(startTime))
+		wsg.recordFailure(time.Since(startTime), time.Since(intendedTime))
 		logger.Debug("Failed to parse WebSocket URL: %v", err)
 		return
 	}
 	
-	conn, resp, err := wsg.dialer.DialContext(wsg.ctx, u.String(), wsg.headers)
+	headers := wsg.headers
+	if wsg.auth != nil {
+		headers = wsg.headers.Clone()
+		if err := wsg.auth.Authorize(&AuthRequest{Method: http.MethodGet, URL: u, Host: headers.Get("Host"), Header: headers}); err != nil {
+			wsg.recordFailure(time.Since(startTime), time.Since(intendedTime))
+			logger.Debug("Failed to authorize WebSocket connection: %v", err)
+			return
+		}
+	}
+	
+	conn, resp, err := wsg.dialer.DialContext(wsg.ctx, u.String(), headers)
 	connectionTime := time.Since(startTime)
+	responseTime := time.Since(intendedTime)
 	
 	if err != nil {
-		wsg.recordFailure(connectionTime)
+		wsg.recordFailure(connectionTime, responseTime)
 		logger.Debug("WebSocket connection failed: %v", err)
 		if resp != nil {
 			logger.Debug("Response status: %d", resp.StatusCode)
@@ -233,12 +368,29 @@ func (wsg *WebSocketGenerator) createConnection() {
 		return
 	}
 	
-	wsg.recordSuccess(connectionTime)
+	wsg.recordSuccess(connectionTime, responseTime)
+	wsg.recordAddress(remoteIP(conn.RemoteAddr()))
 	atomic.AddInt64(&wsg.stats.ActiveConnections, 1)
 	
 	go wsg.handleConnection(conn)
 }
 
+func (wsg *WebSocketGenerator) recordAddress(address string) {
+	if address == "" {
+		return
+	}
+	wsg.stats.mutex.Lock()
+	wsg.stats.Addresses[address]++
+	wsg.stats.mutex.Unlock()
+	metrics.WebSocketConnectionsByAddressCounter.WithLabelValues(address).Inc()
+}
+
+// recordProxyConnect пишет время открытия туннеля через прокси отдельно от времени подключения.
+func (wsg *WebSocketGenerator) recordProxyConnect(duration time.Duration) {
+	wsg.stats.proxyConnect.Record(duration)
+	metrics.WebSocketProxyConnectHistogram.Observe(duration.Seconds())
+}
+
 func (wsg *WebSocketGenerator) handleConnection(conn *websocket.Conn) {
 	defer func() {
 		conn.Close()
@@ -252,6 +404,13 @@ func (wsg *WebSocketGenerator) handleConnection(conn *websocket.Conn) {
 	connectionCtx, connectionCancel := context.WithTimeout(wsg.ctx, connectionDuration)
 	defer connectionCancel()
 	
+	if wsg.script != nil {
+		// Соединение закрывается по истечении времени жизни, поэтому ответы ждутся без дедлайна чтения.
+		conn.SetReadDeadline(time.Time{})
+		wsg.runScript(connectionCtx, conn, wsg.readFrames(connectionCtx, conn))
+		return
+	}
+	
 	messageData := make([]byte, wsg.messageSize)
 	for i := range messageData {
 		messageData[i] = byte('A' + (i % 26))
@@ -283,6 +442,15 @@ func (wsg *WebSocketGenerator) handleConnection(conn *websocket.Conn) {
 		case <-connectionCtx.Done():
 			return
 		case <-messageTicker.C:
+			if wsg.message != nil {
+				payload, err := wsg.renderMessage()
+				if err != nil {
+					logger.Debug("Failed to render WebSocket message: %v", err)
+					continue
+				}
+				messageData = payload
+			}
+			
 			err := conn.WriteMessage(websocket.TextMessage, messageData)
 			if err != nil {
 				logger.Debug("WebSocket write error: %v", err)
@@ -295,8 +463,26 @@ func (wsg *WebSocketGenerator) handleConnection(conn *websocket.Conn) {
 	}
 }
 
+func (wsg *WebSocketGenerator) renderMessage() ([]byte, error) {
+	if wsg.message.IsStatic() {
+		return []byte(wsg.message.String()), nil
+	}
+	
+	data, err := wsg.templates.NewData(nil)
+	if err != nil {
+		return nil, err
+	}
+	
+	payload, err := wsg.message.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(payload), nil
+}
+
 func (wsg *WebSocketGenerator) getConnectionDuration() time.Duration {
-	switch wsg.pattern {
+	_, pattern, _ := wsg.target()
+	switch pattern {
 	case "constant":
 		return 30*time.Second + time.Duration(rand.Intn(30))*time.Second
 	case "spike":
@@ -312,27 +498,38 @@ func (wsg *WebSocketGenerator) getConnectionDuration() time.Duration {
 	}
 }
 
-func (wsg *WebSocketGenerator) recordSuccess(responseTime time.Duration) {
+func (wsg *WebSocketGenerator) recordSuccess(serviceTime, responseTime time.Duration) {
 	atomic.AddInt64(&wsg.stats.TotalConnections, 1)
 	
 	wsg.stats.mutex.Lock()
-	wsg.stats.TotalResponseTime += responseTime
-	if responseTime < wsg.stats.MinResponseTime {
-		wsg.stats.MinResponseTime = responseTime
+	wsg.stats.TotalResponseTime += serviceTime
+	if serviceTime < wsg.stats.MinResponseTime {
+		wsg.stats.MinResponseTime = serviceTime
 	}
-	if responseTime > wsg.stats.MaxResponseTime {
-		wsg.stats.MaxResponseTime = responseTime
+	if serviceTime > wsg.stats.MaxResponseTime {
+		wsg.stats.MaxResponseTime = serviceTime
 	}
 	wsg.stats.mutex.Unlock()
+	
+	wsg.recordLatency(serviceTime, responseTime)
 }
 
-func (wsg *WebSocketGenerator) recordFailure(responseTime time.Duration) {
+func (wsg *WebSocketGenerator) recordFailure(serviceTime, responseTime time.Duration) {
 	atomic.AddInt64(&wsg.stats.TotalConnections, 1)
 	atomic.AddInt64(&wsg.stats.FailedConnections, 1)
 	
 	wsg.stats.mutex.Lock()
-	wsg.stats.TotalResponseTime += responseTime
+	wsg.stats.TotalResponseTime += serviceTime
 	wsg.stats.mutex.Unlock()
+	
+	wsg.recordLatency(serviceTime, responseTime)
+}
+
+func (wsg *WebSocketGenerator) recordLatency(serviceTime, responseTime time.Duration) {
+	wsg.stats.serviceTime.Record(serviceTime)
+	wsg.stats.latency.Record(responseTime)
+	metrics.WebSocketConnectionServiceTimeHistogram.Observe(serviceTime.Seconds())
+	metrics.WebSocketConnectionTimeHistogram.Observe(responseTime.Seconds())
 }
 
 func (wsg *WebSocketGenerator) statsCollector() {
@@ -350,6 +547,7 @@ func (wsg *WebSocketGenerator) statsCollector() {
 			currentCPS := currentConnections - lastConnections
 			atomic.StoreInt64(&wsg.stats.CurrentCPS, currentCPS)
 			lastConnections = currentConnections
+			wsg.workers.checkBottleneck(atomic.LoadInt64(&wsg.stats.DroppedConnections), atomic.LoadInt64(&wsg.stats.LateConnections))
 		}
 	}
 }
@@ -365,6 +563,19 @@ func (wsg *WebSocketGenerator) GetStats() *WebSocketStats {
 	messagesReceived := atomic.LoadInt64(&wsg.stats.MessagesReceived)
 	currentCPS := atomic.LoadInt64(&wsg.stats.CurrentCPS)
 	
+	addresses := make(map[string]int64)
+	for address, count := range wsg.stats.Addresses {
+		addresses[address] = count
+	}
+	
+	var steps []WebSocketStepStats
+	if wsg.script != nil {
+		steps = make([]WebSocketStepStats, 0, len(wsg.script.steps))
+		for _, step := range wsg.script.steps {
+			steps = append(steps, step.snapshot())
+		}
+	}
+	
 	return &WebSocketStats{
 		TotalConnections:  total,
 		ActiveConnections: active,
@@ -372,13 +583,25 @@ func (wsg *WebSocketGenerator) GetStats() *WebSocketStats {
 		MessagesSent:      messagesSent,
 		MessagesReceived:  messagesReceived,
 		CurrentCPS:        currentCPS,
+		DroppedConnections: atomic.LoadInt64(&wsg.stats.DroppedConnections),
+		LateConnections:   atomic.LoadInt64(&wsg.stats.LateConnections),
+		Workers:           wsg.workers.stats(),
 		TotalResponseTime: wsg.stats.TotalResponseTime,
 		MinResponseTime:   wsg.stats.MinResponseTime,
 		MaxResponseTime:   wsg.stats.MaxResponseTime,
 		StartTime:         wsg.stats.StartTime,
+		Latency:           wsg.stats.latency.Percentiles(),
+		ServiceTime:       wsg.stats.serviceTime.Percentiles(),
+		ProxyConnect:      wsg.stats.proxyConnect.Percentiles(),
+		Addresses:         addresses,
+		Steps:             steps,
 	}
 }
 
+func (wsg *WebSocketGenerator) GetLatencyHistogram() *LatencyHistogram {
+	return wsg.stats.latency.Snapshot()
+}
+
 func (wsg *WebSocketGenerator) GetAverageResponseTime() time.Duration {
 	stats := wsg.GetStats()
 	if stats.TotalConnections == 0 {
@@ -0,0 +1,439 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+const DefaultWebSocketStepTimeout = 10 * time.Second
+
+// Результаты шага скрипта в статистике и метриках.
+const (
+	webSocketStepSuccess = "success"
+	webSocketStepTimeout = "timeout"
+	webSocketStepClosed  = "closed"
+	webSocketStepFailed  = "failed"
+)
+
+// WebSocketScript - диалог, который ведёт каждое соединение вместо отправки заполнителя по таймеру.
+// Шаги выполняются по порядку; после последнего скрипт продолжается с шага Loop, а без него
+// соединение просто держится открытым до конца своего времени жизни.
+type WebSocketScript struct {
+	Variables map[string]string `json:"variables,omitempty"`
+	Steps     []*WebSocketStep  `json:"steps"`
+	Loop      string            `json:"loop,omitempty"`
+}
+
+// WebSocketStep отправляет сообщение по шаблону Send и/или ждёт входящее сообщение, подходящее
+// под Expect, не дольше Timeout. Время от начала шага до подходящего сообщения - round-trip шага.
+// Binary отправляет бинарный фрейм, Base64 - бинарный фрейм из декодированного base64 текста.
+// Extract сохраняет значения из дождавшегося сообщения в {{.Vars.<var>}} для следующих шагов.
+type WebSocketStep struct {
+	Name      string          `json:"name,omitempty"`
+	Send      string          `json:"send,omitempty"`
+	Binary    bool            `json:"binary,omitempty"`
+	Base64    bool            `json:"base64,omitempty"`
+	Expect    *WebSocketMatch `json:"expect,omitempty"`
+	Timeout   Duration        `json:"timeout,omitempty"`
+	Extract   []*Extractor    `json:"extract,omitempty"`
+	ThinkTime *ThinkTime      `json:"thinkTime,omitempty"`
+}
+
+// WebSocketMatch - условие на входящее сообщение, все заданные поля должны выполняться.
+// Frame - тип фрейма ("text" или "binary"), пустое условие подходит под любое сообщение.
+// Неподходящие сообщения (рассылки, пинги приложения) пропускаются.
+type WebSocketMatch struct {
+	Frame    string      `json:"frame,omitempty"`
+	Contains string      `json:"contains,omitempty"`
+	Regex    string      `json:"regex,omitempty"`
+	JSONPath string      `json:"jsonPath,omitempty"`
+	Equals   interface{} `json:"equals,omitempty"`
+	regex    *regexp.Regexp
+	path     []string
+}
+
+// WebSocketStepStats - результаты одного шага скрипта по всем соединениям.
+type WebSocketStepStats struct {
+	Name      string             `json:"name"`
+	Total     int64              `json:"total"`
+	Success   int64              `json:"success"`
+	Timeouts  int64              `json:"timeouts"`
+	Closed    int64              `json:"closed"`
+	Failed    int64              `json:"failed"`
+	RoundTrip LatencyPercentiles `json:"roundTrip"`
+}
+
+type webSocketScript struct {
+	variables map[string]string
+	steps     []*webSocketStep
+	loop      int
+}
+
+type webSocketStep struct {
+	name        string
+	send        *TextTemplate
+	messageType int
+	base64      bool
+	expect      *WebSocketMatch
+	waits       bool
+	timeout     time.Duration
+	extract     []*Extractor
+	thinkTime   *ThinkTime
+	total       int64
+	success     int64
+	timeouts    int64
+	closed      int64
+	failed      int64
+	roundTrip   *LatencyHistogram
+}
+
+type webSocketFrame struct {
+	messageType int
+	data        []byte
+}
+
+func LoadWebSocketScript(path string) (*WebSocketScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WebSocket script file: %v", err)
+	}
+
+	var script WebSocketScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse WebSocket script file: %v", err)
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, fmt.Errorf("WebSocket script %s contains no steps", path)
+	}
+
+	return &script, nil
+}
+
+// SetScript задаёт диалог соединений; сообщения по -websocket-message-interval при этом не отправляются.
+func (wsg *WebSocketGenerator) SetScript(script *WebSocketScript) error {
+	if script == nil || len(script.Steps) == 0 {
+		return fmt.Errorf("WebSocket script has no steps")
+	}
+
+	compiled := &webSocketScript{variables: script.Variables, loop: -1}
+	names := make(map[string]int)
+
+	for i, source := range script.Steps {
+		if source == nil {
+			return fmt.Errorf("WebSocket script step %d is empty", i)
+		}
+
+		step := &webSocketStep{
+			name:        source.Name,
+			messageType: websocket.TextMessage,
+			base64:      source.Base64,
+			expect:      source.Expect,
+			waits:       source.Expect != nil || len(source.Extract) > 0,
+			timeout:     time.Duration(source.Timeout),
+			thinkTime:   source.ThinkTime,
+			roundTrip:   NewLatencyHistogram(),
+		}
+		if step.name == "" {
+			step.name = fmt.Sprintf("step%d", i+1)
+		}
+		if _, exists := names[step.name]; exists {
+			return fmt.Errorf("duplicate WebSocket script step name: %s", step.name)
+		}
+		names[step.name] = i
+
+		if source.Send == "" && !step.waits {
+			return fmt.Errorf("WebSocket script step %s must send or expect a message", step.name)
+		}
+		if source.Send != "" {
+			send, err := wsg.templates.Compile(step.name, source.Send)
+			if err != nil {
+				return fmt.Errorf("WebSocket script step %s: %v", step.name, err)
+			}
+			step.send = send
+		}
+		if source.Binary || source.Base64 {
+			step.messageType = websocket.BinaryMessage
+		}
+		if step.timeout < 0 {
+			return fmt.Errorf("WebSocket script step %s: timeout must be non-negative", step.name)
+		}
+		if step.timeout == 0 {
+			step.timeout = DefaultWebSocketStepTimeout
+		}
+
+		if step.expect != nil {
+			expect := *step.expect
+			if err := expect.compile(); err != nil {
+				return fmt.Errorf("WebSocket script step %s: %v", step.name, err)
+			}
+			step.expect = &expect
+		}
+
+		for _, extractor := range source.Extract {
+			if extractor == nil {
+				continue
+			}
+			e := *extractor
+			if e.Header != "" {
+				return fmt.Errorf("WebSocket script step %s: messages have no headers to extract %s from", step.name, e.Var)
+			}
+			if err := e.compile(); err != nil {
+				return fmt.Errorf("WebSocket script step %s: %v", step.name, err)
+			}
+			step.extract = append(step.extract, &e)
+		}
+
+		compiled.steps = append(compiled.steps, step)
+	}
+
+	if script.Loop != "" {
+		loop, ok := names[script.Loop]
+		if !ok {
+			return fmt.Errorf("WebSocket script loop step %s not found", script.Loop)
+		}
+		compiled.loop = loop
+	}
+
+	wsg.script = compiled
+	return nil
+}
+
+func (m *WebSocketMatch) compile() error {
+	if m.Frame != "" && m.Frame != "text" && m.Frame != "binary" {
+		return fmt.Errorf("invalid expected frame type %q, use text or binary", m.Frame)
+	}
+	if m.Regex != "" {
+		regex, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid expect regex %q: %v", m.Regex, err)
+		}
+		m.regex = regex
+	}
+	if m.JSONPath != "" {
+		m.path = parseJSONPath(m.JSONPath)
+	} else if m.Equals != nil {
+		return fmt.Errorf("expect equals requires jsonPath")
+	}
+	return nil
+}
+
+func (m *WebSocketMatch) matches(frame webSocketFrame, parsed *jsonBody) bool {
+	if m == nil {
+		return true
+	}
+	if m.Frame == "text" && frame.messageType != websocket.TextMessage {
+		return false
+	}
+	if m.Frame == "binary" && frame.messageType != websocket.BinaryMessage {
+		return false
+	}
+	if m.Contains != "" && !bytes.Contains(frame.data, []byte(m.Contains)) {
+		return false
+	}
+	if m.regex != nil && !m.regex.Match(frame.data) {
+		return false
+	}
+	if m.JSONPath != "" {
+		doc, err := parsed.get(frame.data)
+		if err != nil {
+			return false
+		}
+		value, ok := lookupJSONPath(doc, m.path)
+		if !ok {
+			return false
+		}
+		if m.Equals != nil && !jsonValuesEqual(value, m.Equals) {
+			return false
+		}
+	}
+	return true
+}
+
+// readFrames читает все входящие сообщения соединения в канал; канал закрывается, когда соединение закрыто.
+func (wsg *WebSocketGenerator) readFrames(ctx context.Context, conn *websocket.Conn) <-chan webSocketFrame {
+	frames := make(chan webSocketFrame, 16)
+	go func() {
+		defer close(frames)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				if ctx.Err() == nil && websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+					logger.Debug("WebSocket read error: %v", err)
+				}
+				return
+			}
+			atomic.AddInt64(&wsg.stats.MessagesReceived, 1)
+
+			select {
+			case frames <- webSocketFrame{messageType: messageType, data: data}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return frames
+}
+
+// runScript ведёт диалог до конца времени жизни соединения. Провал шага закрывает соединение:
+// продолжать разговор после потерянного ответа обычно бессмысленно.
+func (wsg *WebSocketGenerator) runScript(ctx context.Context, conn *websocket.Conn, frames <-chan webSocketFrame) {
+	script := wsg.script
+
+	data, err := wsg.templates.NewData(copyVars(script.variables))
+	if err != nil {
+		logger.Debug("Failed to get WebSocket script data: %v", err)
+		return
+	}
+
+	for i := 0; ctx.Err() == nil; i++ {
+		if i == len(script.steps) {
+			if script.loop < 0 {
+				drainFrames(ctx, frames)
+				return
+			}
+			i = script.loop
+		}
+
+		step := script.steps[i]
+		if !wsg.runScriptStep(ctx, conn, frames, step, data) {
+			return
+		}
+		sleepContext(ctx, step.thinkTime.Next())
+	}
+}
+
+func (wsg *WebSocketGenerator) runScriptStep(ctx context.Context, conn *websocket.Conn, frames <-chan webSocketFrame, step *webSocketStep, data *TemplateData) bool {
+	startTime := time.Now()
+
+	if step.send != nil {
+		payload, err := step.send.Render(data)
+		if err != nil {
+			wsg.recordScriptStep(step, webSocketStepFailed, 0)
+			logger.Debug("Failed to render WebSocket script step %s: %v", step.name, err)
+			return false
+		}
+
+		message := []byte(payload)
+		if step.base64 {
+			if message, err = base64.StdEncoding.DecodeString(payload); err != nil {
+				wsg.recordScriptStep(step, webSocketStepFailed, 0)
+				logger.Debug("WebSocket script step %s: invalid base64 payload: %v", step.name, err)
+				return false
+			}
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := conn.WriteMessage(step.messageType, message); err != nil {
+			if ctx.Err() == nil {
+				wsg.recordScriptStep(step, webSocketStepFailed, 0)
+				logger.Debug("WebSocket script step %s write error: %v", step.name, err)
+			}
+			return false
+		}
+		atomic.AddInt64(&wsg.stats.MessagesSent, 1)
+	}
+
+	if !step.waits {
+		wsg.recordScriptStep(step, webSocketStepSuccess, 0)
+		return true
+	}
+
+	timer := time.NewTimer(step.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			wsg.recordScriptStep(step, webSocketStepTimeout, 0)
+			logger.Debug("WebSocket script step %s: no matching message within %s", step.name, step.timeout)
+			return false
+		case frame, ok := <-frames:
+			if !ok {
+				if ctx.Err() == nil {
+					wsg.recordScriptStep(step, webSocketStepClosed, 0)
+					logger.Debug("WebSocket script step %s: connection closed while waiting", step.name)
+				}
+				return false
+			}
+
+			var parsed jsonBody
+			if !step.expect.matches(frame, &parsed) {
+				continue
+			}
+
+			for _, extractor := range step.extract {
+				value, ok := extractor.extract(nil, frame.data, &parsed)
+				if !ok {
+					wsg.recordScriptStep(step, webSocketStepFailed, 0)
+					logger.Debug("WebSocket script step %s: failed to extract %s", step.name, extractor.Var)
+					return false
+				}
+				data.Vars[extractor.Var] = value
+			}
+
+			wsg.recordScriptStep(step, webSocketStepSuccess, time.Since(startTime))
+			return true
+		}
+	}
+}
+
+// recordScriptStep учитывает результат шага; roundTrip записывается только для дождавшихся ответа шагов.
+func (wsg *WebSocketGenerator) recordScriptStep(step *webSocketStep, result string, roundTrip time.Duration) {
+	atomic.AddInt64(&step.total, 1)
+	switch result {
+	case webSocketStepSuccess:
+		atomic.AddInt64(&step.success, 1)
+	case webSocketStepTimeout:
+		atomic.AddInt64(&step.timeouts, 1)
+	case webSocketStepClosed:
+		atomic.AddInt64(&step.closed, 1)
+	default:
+		atomic.AddInt64(&step.failed, 1)
+	}
+	metrics.WebSocketScriptStepsCounter.WithLabelValues(step.name, result).Inc()
+
+	if result == webSocketStepSuccess && step.waits {
+		step.roundTrip.Record(roundTrip)
+		metrics.WebSocketScriptRoundTripHistogram.WithLabelValues(step.name).Observe(roundTrip.Seconds())
+	}
+}
+
+func (step *webSocketStep) snapshot() WebSocketStepStats {
+	return WebSocketStepStats{
+		Name:      step.name,
+		Total:     atomic.LoadInt64(&step.total),
+		Success:   atomic.LoadInt64(&step.success),
+		Timeouts:  atomic.LoadInt64(&step.timeouts),
+		Closed:    atomic.LoadInt64(&step.closed),
+		Failed:    atomic.LoadInt64(&step.failed),
+		RoundTrip: step.roundTrip.Percentiles(),
+	}
+}
+
+// drainFrames читает и отбрасывает сообщения, пока соединение живо.
+func drainFrames(ctx context.Context, frames <-chan webSocketFrame) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-frames:
+			if !ok {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package network
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"stresspulse/logger"
+	"stresspulse/metrics"
+)
+
+// Пределы конкурентности по умолчанию.
+const (
+	DefaultHTTPMaxConcurrency      = 1000
+	DefaultGRPCMaxConcurrency      = 1000
+	DefaultWebSocketMaxConcurrency = 500
+	DefaultSSEMaxConcurrency       = 500
+)
+
+const (
+	// Воркер сверх минимума, простоявший без работы workerIdleTimeout, завершается.
+	workerIdleTimeout = 10 * time.Second
+	// Окно, за которое оцениваются опоздания и потери для предупреждения об узком месте.
+	bottleneckWindow = 10 * time.Second
+)
+
+// WorkerPoolStats - состояние пула воркеров генератора.
+type WorkerPoolStats struct {
+	Workers     int  `json:"workers"`
+	Busy        int  `json:"busy"`
+	Queued      int  `json:"queued"`
+	MaxWorkers  int  `json:"maxWorkers"`
+	PeakWorkers int  `json:"peakWorkers"`
+	Saturated   bool `json:"saturated"`
+}
+
+// workerPool раздаёт запланированные запросы воркерам. Воркеры добавляются, когда все заняты
+// и в очереди есть работа, до maxWorkers, и завершаются после простоя до minWorkers.
+type workerPool struct {
+	name       string
+	capSetting string
+	jobs       atomic.Value // chan time.Time, заменяется на больший в resize
+	handle     func(workerID int, intendedTime time.Time)
+	ctx        context.Context
+	minWorkers int64
+	maxWorkers int64
+	workers    int64
+	busy       int64
+	inFlight   int64
+	peak       int64
+	nextID     int64
+	submitted  int64
+	capHits    int64
+	saturated  int32
+
+	lastCheck     time.Time
+	lastDropped   int64
+	lastLate      int64
+	lastCapHits   int64
+	lastSubmitted int64
+}
+
+// newWorkerPool создаёт пул. name - имя генератора в логах и метриках,
+// capSetting - флаг, которым поднимается предел, для текста предупреждения.
+func newWorkerPool(name, capSetting string, queueSize, minWorkers, maxWorkers int, handle func(workerID int, intendedTime time.Time)) *workerPool {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	pool := &workerPool{
+		name:       name,
+		capSetting: capSetting,
+		handle:     handle,
+		minWorkers: int64(minWorkers),
+	}
+	pool.jobs.Store(make(chan time.Time, queueSize))
+	pool.setMaxWorkers(maxWorkers)
+	return pool
+}
+
+func (p *workerPool) queue() chan time.Time {
+	return p.jobs.Load().(chan time.Time)
+}
+
+// resize увеличивает очередь под новый темп; уменьшать её незачем. Вызывается только из
+// горутины, которая вызывает submit, поэтому в старую очередь после замены никто не пишет:
+// оставшиеся в ней запросы переносятся в новую, а закрытие будит воркеров, ждущих старую.
+func (p *workerPool) resize(queueSize int) {
+	old := p.queue()
+	if queueSize <= cap(old) {
+		return
+	}
+	jobs := make(chan time.Time, queueSize)
+	p.jobs.Store(jobs)
+	for moved := true; moved; {
+		select {
+		case intendedTime := <-old:
+			jobs <- intendedTime
+		default:
+			moved = false
+		}
+	}
+	close(old)
+}
+
+// setMaxWorkers меняет предел конкурентности; минимум не превышает предела.
+func (p *workerPool) setMaxWorkers(maxWorkers int) {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	atomic.StoreInt64(&p.maxWorkers, int64(maxWorkers))
+	if atomic.LoadInt64(&p.minWorkers) > int64(maxWorkers) {
+		atomic.StoreInt64(&p.minWorkers, int64(maxWorkers))
+	}
+}
+
+func (p *workerPool) start(ctx context.Context) {
+	p.ctx = ctx
+	p.lastCheck = time.Now()
+	for i := int64(0); i < atomic.LoadInt64(&p.minWorkers); i++ {
+		p.grow()
+	}
+}
+
+// submit ставит запрос в очередь. false означает, что очередь заполнена и запрос потерян.
+// inFlight считает запросы от постановки в очередь до завершения, поэтому новый воркер
+// запускается, как только запросов становится больше, чем воркеров.
+func (p *workerPool) submit(intendedTime time.Time) bool {
+	atomic.AddInt64(&p.submitted, 1)
+	if atomic.AddInt64(&p.inFlight, 1) > atomic.LoadInt64(&p.workers) {
+		if !p.grow() {
+			atomic.AddInt64(&p.capHits, 1)
+		}
+	}
+
+	select {
+	case p.queue() <- intendedTime:
+		return true
+	default:
+		atomic.AddInt64(&p.inFlight, -1)
+		return false
+	}
+}
+
+// grow запускает ещё одного воркера, если предел не достигнут.
+func (p *workerPool) grow() bool {
+	for {
+		workers := atomic.LoadInt64(&p.workers)
+		if workers >= atomic.LoadInt64(&p.maxWorkers) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&p.workers, workers, workers+1) {
+			for {
+				peak := atomic.LoadInt64(&p.peak)
+				if workers+1 <= peak || atomic.CompareAndSwapInt64(&p.peak, peak, workers+1) {
+					break
+				}
+			}
+			go p.worker(int(atomic.AddInt64(&p.nextID, 1) - 1))
+			return true
+		}
+	}
+}
+
+// retire уменьшает число воркеров, если оно выше минимума.
+func (p *workerPool) retire() bool {
+	for {
+		workers := atomic.LoadInt64(&p.workers)
+		if workers <= atomic.LoadInt64(&p.minWorkers) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&p.workers, workers, workers-1) {
+			return true
+		}
+	}
+}
+
+func (p *workerPool) worker(workerID int) {
+	logger.Debug("%s worker %d started", p.name, workerID)
+	defer logger.Debug("%s worker %d stopped", p.name, workerID)
+
+	idle := time.NewTimer(workerIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			atomic.AddInt64(&p.workers, -1)
+			return
+		case intendedTime, ok := <-p.queue():
+			if !ok {
+				// Очередь заменена в resize, следующая итерация читает новую.
+				continue
+			}
+			atomic.AddInt64(&p.busy, 1)
+			p.handle(workerID, intendedTime)
+			atomic.AddInt64(&p.busy, -1)
+			atomic.AddInt64(&p.inFlight, -1)
+
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(workerIdleTimeout)
+		case <-idle.C:
+			if p.retire() {
+				return
+			}
+			idle.Reset(workerIdleTimeout)
+		}
+	}
+}
+
+// checkBottleneck вызывается раз в секунду из statsCollector генератора: обновляет метрики пула
+// и раз в bottleneckWindow предупреждает, если темп не выдерживает сам генератор, а не цель.
+func (p *workerPool) checkBottleneck(dropped, late int64) {
+	workers := atomic.LoadInt64(&p.workers)
+	metrics.WorkerPoolWorkersGauge.WithLabelValues(p.name).Set(float64(workers))
+	metrics.WorkerPoolBusyGauge.WithLabelValues(p.name).Set(float64(atomic.LoadInt64(&p.busy)))
+
+	if time.Since(p.lastCheck) < bottleneckWindow {
+		return
+	}
+	p.lastCheck = time.Now()
+
+	capHits := atomic.LoadInt64(&p.capHits)
+	submitted := atomic.LoadInt64(&p.submitted)
+	newDropped := dropped - p.lastDropped
+	newLate := late - p.lastLate
+	newCapHits := capHits - p.lastCapHits
+	newSubmitted := submitted - p.lastSubmitted
+	p.lastDropped, p.lastLate, p.lastCapHits, p.lastSubmitted = dropped, late, capHits, submitted
+
+	saturated := newCapHits > 0
+	if saturated {
+		atomic.StoreInt32(&p.saturated, 1)
+		metrics.WorkerPoolSaturatedGauge.WithLabelValues(p.name).Set(1)
+	} else {
+		atomic.StoreInt32(&p.saturated, 0)
+		metrics.WorkerPoolSaturatedGauge.WithLabelValues(p.name).Set(0)
+	}
+
+	// Единичные опоздания (меньше 1% запросов окна) бывают при прогреве и не стоят предупреждения.
+	if newDropped == 0 && newLate*100 < newSubmitted {
+		return
+	}
+	if saturated {
+		logger.Warning("%s generator is the bottleneck: all %d workers busy at the concurrency cap, %d dropped and %d late in the last %s; raise %s or lower the rate",
+			p.name, atomic.LoadInt64(&p.maxWorkers), newDropped, newLate, bottleneckWindow, p.capSetting)
+		return
+	}
+	logger.Warning("%s generator is falling behind schedule below its concurrency cap (%d of %d workers): %d dropped and %d late in the last %s; the load generator host is likely CPU or network bound",
+		p.name, workers, atomic.LoadInt64(&p.maxWorkers), newDropped, newLate, bottleneckWindow)
+}
+
+func (p *workerPool) stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Workers:     int(atomic.LoadInt64(&p.workers)),
+		Busy:        int(atomic.LoadInt64(&p.busy)),
+		Queued:      len(p.queue()),
+		MaxWorkers:  int(atomic.LoadInt64(&p.maxWorkers)),
+		PeakWorkers: int(atomic.LoadInt64(&p.peak)),
+		Saturated:   atomic.LoadInt32(&p.saturated) == 1,
+	}
+}
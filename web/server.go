@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"stresspulse/network"
 	"stresspulse/logs"
 	"stresspulse/agent"
+	"stresspulse/control"
 )
 
 type WebServer struct {
@@ -26,7 +29,12 @@ type WebServer struct {
 	memGenerator  *memory.MemoryGenerator
 	httpGenerator *network.HTTPGenerator
 	wsGenerator   *network.WebSocketGenerator
+	sseGenerator  *network.SSEGenerator
 	grpcGenerator *network.GRPCGenerator
+	scenarioGenerator *network.ScenarioGenerator
+	authProvider  network.AuthProvider
+	source        *network.SourceBinder
+	captures      *network.CaptureStore
 	fakeLogGen    *logs.FakeLogGenerator
 	logBuffer     []LogEntry
 	logMutex      sync.RWMutex
@@ -66,6 +74,16 @@ type WebConfiguration struct {
 		Pattern string            `json:"pattern"`
 		Headers map[string]string `json:"headers"`
 		Body    string            `json:"body"`
+		Requests []*network.RequestTemplate `json:"requests"`
+		Checks   []*network.ResponseCheck   `json:"checks"`
+		OpenAPI  struct {
+			Spec json.RawMessage `json:"spec"`
+			network.OpenAPIOptions
+		} `json:"openapi"`
+		GraphQL        *network.GraphQLMix           `json:"graphql"`
+		MaxConcurrency int `json:"maxConcurrency"`
+		Connection     network.HTTPConnectionOptions `json:"connection"`
+		Proxy          network.ProxyOptions          `json:"proxy"`
 	} `json:"http"`
 	WebSocket struct {
 		Enabled         bool   `json:"enabled"`
@@ -74,7 +92,22 @@ type WebConfiguration struct {
 		Pattern         string `json:"pattern"`
 		MessageInterval int    `json:"messageInterval"`
 		MessageSize     int    `json:"messageSize"`
+		Message         string `json:"message"`
+		Script          *network.WebSocketScript `json:"script"`
+		MaxConcurrency  int    `json:"maxConcurrency"`
+		Proxy           network.ProxyOptions `json:"proxy"`
 	} `json:"websocket"`
+	SSE struct {
+		Enabled        bool              `json:"enabled"`
+		URL            string            `json:"url"`
+		CPS            int               `json:"cps"`
+		Pattern        string            `json:"pattern"`
+		Streams        int               `json:"streams"`
+		Hold           network.Duration  `json:"hold"`
+		ReconnectDelay network.Duration  `json:"reconnectDelay"`
+		Headers        map[string]string `json:"headers"`
+		MaxConcurrency int               `json:"maxConcurrency"`
+	} `json:"sse"`
 	GRPC struct {
 		Enabled bool   `json:"enabled"`
 		Address string `json:"address"`
@@ -83,7 +116,24 @@ type WebConfiguration struct {
 		Pattern string `json:"pattern"`
 		Secure  bool   `json:"secure"`
 		Service string `json:"service"`
+		MaxConcurrency int `json:"maxConcurrency"`
 	} `json:"grpc"`
+	Scenario struct {
+		Enabled bool   `json:"enabled"`
+		Users   int    `json:"users"`
+		Pattern string `json:"pattern"`
+		BaseURL string `json:"baseUrl"`
+		network.Scenario
+	} `json:"scenario"`
+	Data struct {
+		Mode string              `json:"mode"`
+		Rows []map[string]string `json:"rows"`
+	} `json:"data"`
+	TLS             network.TLSOptions `json:"tls"`
+	Auth            network.AuthOptions `json:"auth"`
+	DNS             network.ResolveOptions `json:"dns"`
+	Source          network.SourceOptions `json:"source"`
+	Capture         network.CaptureOptions `json:"capture"`
 	FakeLogsEnabled bool   `json:"fakeLogsEnabled"`
 	FakeLogsType    string `json:"fakeLogsType"`
 	Duration        string `json:"duration"`
@@ -106,19 +156,87 @@ type StatsResponse struct {
 		CurrentRPS  int64   `json:"currentRPS"`
 		TargetRPS   int     `json:"targetRPS"`
 		SuccessRate float64 `json:"successRate"`
+		Latency     network.LatencyPercentiles `json:"latency"`
+		ServiceTime network.LatencyPercentiles `json:"serviceTime"`
+		Dropped     int64   `json:"droppedRequests"`
+		Late        int64   `json:"lateRequests"`
+		Workers     network.WorkerPoolStats `json:"workers"`
+		StatusCodes  map[int]int64    `json:"statusCodes"`
+		ErrorClasses map[string]int64 `json:"errorClasses"`
+		Endpoints    []network.EndpointStats `json:"endpoints"`
+		Checks        []network.CheckStats   `json:"checks"`
+		CheckFailures []network.CheckFailure `json:"checkFailures"`
+		Phases            map[string]network.LatencyPercentiles `json:"phases"`
+		Protocols         map[string]int64 `json:"protocols"`
+		Addresses         map[string]int64 `json:"addresses"`
+		NewConnections    int64   `json:"newConnections"`
+		ReusedConnections int64   `json:"reusedConnections"`
+		ConnectionReuse   float64 `json:"connectionReuse"`
+		BytesSent         int64   `json:"bytesSent"`
+		BytesReceived     int64   `json:"bytesReceived"`
 	} `json:"http,omitempty"`
 	WebSocket struct {
 		Enabled           bool    `json:"enabled"`
 		CurrentCPS        int64   `json:"currentCPS"`
 		ActiveConnections int64   `json:"activeConnections"`
 		SuccessRate       float64 `json:"successRate"`
+		Latency           network.LatencyPercentiles `json:"latency"`
+		ServiceTime       network.LatencyPercentiles `json:"serviceTime"`
+		ProxyConnect      network.LatencyPercentiles `json:"proxyConnect"`
+		Addresses         map[string]int64 `json:"addresses"`
+		Dropped           int64   `json:"droppedConnections"`
+		Late              int64   `json:"lateConnections"`
+		Workers           network.WorkerPoolStats `json:"workers"`
+		Steps             []network.WebSocketStepStats `json:"steps,omitempty"`
 	} `json:"websocket,omitempty"`
+	SSE struct {
+		Enabled          bool    `json:"enabled"`
+		CurrentCPS       int64   `json:"currentCPS"`
+		ActiveStreams    int64   `json:"activeStreams"`
+		TotalConnections int64   `json:"totalConnections"`
+		SuccessRate      float64 `json:"successRate"`
+		EventsReceived   int64   `json:"eventsReceived"`
+		Reconnects       int64   `json:"reconnects"`
+		BytesReceived    int64   `json:"bytesReceived"`
+		Latency          network.LatencyPercentiles `json:"latency"`
+		ServiceTime      network.LatencyPercentiles `json:"serviceTime"`
+		InterArrival     network.LatencyPercentiles `json:"interArrival"`
+		EventTypes       map[string]int64 `json:"eventTypes"`
+		Dropped          int64   `json:"droppedConnections"`
+		Late             int64   `json:"lateConnections"`
+		Workers          network.WorkerPoolStats `json:"workers"`
+	} `json:"sse,omitempty"`
 	GRPC struct {
 		Enabled     bool    `json:"enabled"`
 		CurrentRPS  int64   `json:"currentRPS"`
 		TargetRPS   int     `json:"targetRPS"`
 		SuccessRate float64 `json:"successRate"`
+		Latency     network.LatencyPercentiles `json:"latency"`
+		ServiceTime network.LatencyPercentiles `json:"serviceTime"`
+		Dropped     int64   `json:"droppedRequests"`
+		Late        int64   `json:"lateRequests"`
+		Workers     network.WorkerPoolStats `json:"workers"`
+		Addresses   map[string]int64 `json:"addresses"`
 	} `json:"grpc,omitempty"`
+	Scenario struct {
+		Enabled           bool    `json:"enabled"`
+		ActiveUsers       int64   `json:"activeUsers"`
+		TargetUsers       int64   `json:"targetUsers"`
+		CurrentIPS        int64   `json:"currentIPS"`
+		CurrentRPS        int64   `json:"currentRPS"`
+		AvgIPS            float64 `json:"avgIPS"`
+		AvgRPS            float64 `json:"avgRPS"`
+		TotalIterations   int64   `json:"totalIterations"`
+		FailedIterations  int64   `json:"failedIterations"`
+		SuccessRate       float64 `json:"successRate"`
+		Iteration         network.LatencyPercentiles `json:"iteration"`
+		ErrorClasses      map[string]int64          `json:"errorClasses"`
+		Steps             []network.EndpointStats   `json:"steps"`
+		Checks            []network.CheckStats      `json:"checks"`
+		CheckFailures     []network.CheckFailure    `json:"checkFailures"`
+	} `json:"scenario,omitempty"`
+	Auth *network.AuthStats `json:"auth,omitempty"`
+	Sources map[string]network.SourceAddressStats `json:"sources,omitempty"`
 }
 
 func NewWebServer(port int) *WebServer {
@@ -139,9 +257,12 @@ func NewWebServer(port int) *WebServer {
 
 	mux.HandleFunc("/api/start", ws.corsMiddleware(ws.validateJSONMiddleware(ws.handleStart)))
 	mux.HandleFunc("/api/stop", ws.corsMiddleware(ws.handleStop))
+	mux.HandleFunc("/api/run", ws.corsMiddleware(ws.validateJSONMiddleware(ws.handleRun)))
 	mux.HandleFunc("/api/stats", ws.corsMiddleware(ws.handleStats))
 	mux.HandleFunc("/api/logs", ws.corsMiddleware(ws.handleLogs))
 	mux.HandleFunc("/api/config", ws.corsMiddleware(ws.handleConfig))
+	mux.HandleFunc("/api/captures", ws.corsMiddleware(ws.handleCaptures))
+	mux.HandleFunc("/api/import/har", ws.corsMiddleware(ws.handleImportHAR))
 
 	mux.HandleFunc("/api/agents", ws.corsMiddleware(ws.handleAgents))
 	mux.HandleFunc("/api/agents/add", ws.corsMiddleware(ws.validateJSONMiddleware(ws.handleAddAgent)))
@@ -149,6 +270,7 @@ func NewWebServer(port int) *WebServer {
 	mux.HandleFunc("/api/agents/start", ws.corsMiddleware(ws.validateJSONMiddleware(ws.handleStartAgent)))
 	mux.HandleFunc("/api/agents/stop", ws.corsMiddleware(ws.validateJSONMiddleware(ws.handleStopAgent)))
 	mux.HandleFunc("/api/agents/stats", ws.corsMiddleware(ws.handleAgentStats))
+	mux.HandleFunc("/api/agents/stats/aggregate", ws.corsMiddleware(ws.handleAggregateAgentStats))
 
 	ws.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
@@ -163,7 +285,7 @@ func NewWebServer(port int) *WebServer {
 func (ws *WebServer) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == http.MethodOptions {
@@ -177,7 +299,7 @@ func (ws *WebServer) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 func (ws *WebServer) validateJSONMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
 			contentType := r.Header.Get("Content-Type")
 			if contentType != "application/json" && contentType != "" {
 				http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
@@ -268,6 +390,41 @@ func (ws *WebServer) handleStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tlsConfig, err := config.TLS.Build()
+	if err != nil {
+		ws.addLog("error", "Invalid TLS options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid TLS options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	authProvider, err := network.NewAuthProvider(&config.Auth, tlsConfig)
+	if err != nil {
+		ws.addLog("error", "Invalid auth options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid auth options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	resolver, err := network.NewResolver(&config.DNS)
+	if err != nil {
+		ws.addLog("error", "Invalid DNS options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid DNS options: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	source, err := network.NewSourceBinder(&config.Source)
+	if err != nil {
+		ws.addLog("error", "Invalid source addresses: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid source addresses: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	captures, err := network.NewCaptureStore(&config.Capture)
+	if err != nil {
+		ws.addLog("error", "Invalid capture options: %v", err)
+		http.Error(w, fmt.Sprintf("Invalid capture options: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	ws.configMutex.Lock()
 	ws.config = &config
 	ws.configMutex.Unlock()
@@ -281,9 +438,17 @@ func (ws *WebServer) handleStart(w http.ResponseWriter, r *http.Request) {
 	ws.stopAllGenerators()
 
 	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+	ws.authProvider = authProvider
+	ws.source = source
+	ws.captures = captures
 
 	var startErrors []string
 
+	var dataFeeder *network.DataFeeder
+	if len(config.Data.Rows) > 0 {
+		dataFeeder = network.NewDataFeeder(config.Data.Rows, config.Data.Mode)
+	}
+
 	if config.CPU.Enabled {
 		cpuConfig := &cfg.Config{
 			TargetCPUPercent: float64(config.CPU.Load),
@@ -309,8 +474,44 @@ func (ws *WebServer) handleStart(w http.ResponseWriter, r *http.Request) {
 		if config.HTTP.Body != "" {
 			ws.httpGenerator.SetBody(config.HTTP.Body)
 		}
-		ws.httpGenerator.Start(ws.ctx)
-		ws.addLog("success", "HTTP load test started: %s %s at %d RPS", config.HTTP.Method, config.HTTP.URL, config.HTTP.RPS)
+		if dataFeeder != nil {
+			ws.httpGenerator.SetDataFeeder(dataFeeder)
+		}
+		ws.httpGenerator.SetTLSConfig(tlsConfig)
+		ws.httpGenerator.SetAuth(authProvider)
+		ws.httpGenerator.SetResolver(resolver)
+		ws.httpGenerator.SetSource(source)
+		ws.httpGenerator.SetCaptures(captures)
+		if config.HTTP.MaxConcurrency > 0 {
+			ws.httpGenerator.SetMaxConcurrency(config.HTTP.MaxConcurrency)
+		}
+		templatesErr := ws.httpGenerator.SetConnectionOptions(&config.HTTP.Connection)
+		if templatesErr == nil {
+			templatesErr = ws.httpGenerator.SetProxy(&config.HTTP.Proxy)
+		}
+		if templatesErr == nil && len(config.HTTP.Checks) > 0 {
+			templatesErr = ws.httpGenerator.SetChecks(config.HTTP.Checks)
+		}
+		if templatesErr == nil && len(config.HTTP.Requests) > 0 {
+			templatesErr = ws.httpGenerator.SetRequestTemplates(config.HTTP.Requests)
+		}
+		if templatesErr == nil && len(config.HTTP.OpenAPI.Spec) > 0 {
+			var templates []*network.RequestTemplate
+			templates, templatesErr = network.BuildOpenAPIRequests(config.HTTP.OpenAPI.Spec, &config.HTTP.OpenAPI.OpenAPIOptions)
+			if templatesErr == nil {
+				templatesErr = ws.httpGenerator.SetRequestTemplates(templates)
+			}
+		}
+		if templatesErr == nil && config.HTTP.GraphQL != nil {
+			templatesErr = ws.httpGenerator.SetGraphQLOperations(config.HTTP.GraphQL)
+		}
+		if templatesErr != nil {
+			ws.httpGenerator = nil
+			startErrors = append(startErrors, fmt.Sprintf("HTTP: %v", templatesErr))
+		} else {
+			ws.httpGenerator.Start(ws.ctx)
+			ws.addLog("success", "HTTP load test started: %s %s at %d RPS", config.HTTP.Method, config.HTTP.URL, config.HTTP.RPS)
+		}
 	}
 
 	if config.WebSocket.Enabled {
@@ -321,8 +522,54 @@ func (ws *WebServer) handleStart(w http.ResponseWriter, r *http.Request) {
 			time.Duration(config.WebSocket.MessageInterval)*time.Second,
 			config.WebSocket.MessageSize,
 		)
-		ws.wsGenerator.Start(ws.ctx)
-		ws.addLog("success", "WebSocket load test started: %s at %d CPS", config.WebSocket.URL, config.WebSocket.CPS)
+		if dataFeeder != nil {
+			ws.wsGenerator.SetDataFeeder(dataFeeder)
+		}
+		ws.wsGenerator.SetTLSConfig(tlsConfig)
+		ws.wsGenerator.SetAuth(authProvider)
+		ws.wsGenerator.SetResolver(resolver)
+		ws.wsGenerator.SetSource(source)
+		if config.WebSocket.MaxConcurrency > 0 {
+			ws.wsGenerator.SetMaxConcurrency(config.WebSocket.MaxConcurrency)
+		}
+		wsErr := ws.wsGenerator.SetProxy(&config.WebSocket.Proxy)
+		if wsErr == nil && config.WebSocket.Message != "" {
+			wsErr = ws.wsGenerator.SetMessageTemplate(config.WebSocket.Message)
+		}
+		if wsErr == nil && config.WebSocket.Script != nil {
+			wsErr = ws.wsGenerator.SetScript(config.WebSocket.Script)
+		}
+		if wsErr != nil {
+			ws.wsGenerator = nil
+			startErrors = append(startErrors, fmt.Sprintf("WebSocket: %v", wsErr))
+		} else {
+			ws.wsGenerator.Start(ws.ctx)
+			ws.addLog("success", "WebSocket load test started: %s at %d CPS", config.WebSocket.URL, config.WebSocket.CPS)
+		}
+	}
+
+	if config.SSE.Enabled {
+		streams := config.SSE.Streams
+		if streams == 0 {
+			streams = network.DefaultSSEMaxStreams
+		}
+		ws.sseGenerator = network.NewSSEGenerator(config.SSE.URL, config.SSE.CPS, config.SSE.Pattern, streams)
+		if config.SSE.Headers != nil {
+			ws.sseGenerator.SetHeaders(config.SSE.Headers)
+		}
+		ws.sseGenerator.SetHoldDuration(time.Duration(config.SSE.Hold))
+		if config.SSE.ReconnectDelay > 0 {
+			ws.sseGenerator.SetReconnectDelay(time.Duration(config.SSE.ReconnectDelay))
+		}
+		ws.sseGenerator.SetTLSConfig(tlsConfig)
+		ws.sseGenerator.SetAuth(authProvider)
+		ws.sseGenerator.SetResolver(resolver)
+		ws.sseGenerator.SetSource(source)
+		if config.SSE.MaxConcurrency > 0 {
+			ws.sseGenerator.SetMaxConcurrency(config.SSE.MaxConcurrency)
+		}
+		ws.sseGenerator.Start(ws.ctx)
+		ws.addLog("success", "SSE load test started: %s at %d CPS, %d streams", config.SSE.URL, config.SSE.CPS, streams)
 	}
 
 	if config.GRPC.Enabled {
@@ -334,6 +581,16 @@ func (ws *WebServer) handleStart(w http.ResponseWriter, r *http.Request) {
 			config.GRPC.Method,
 			config.GRPC.Secure,
 		)
+		if dataFeeder != nil {
+			ws.grpcGenerator.SetDataFeeder(dataFeeder)
+		}
+		ws.grpcGenerator.SetTLSConfig(tlsConfig)
+		ws.grpcGenerator.SetAuth(authProvider)
+		ws.grpcGenerator.SetResolver(resolver)
+		ws.grpcGenerator.SetSource(source)
+		if config.GRPC.MaxConcurrency > 0 {
+			ws.grpcGenerator.SetMaxConcurrency(config.GRPC.MaxConcurrency)
+		}
 		if err := ws.grpcGenerator.Start(ws.ctx); err != nil {
 			ws.addLog("error", "Failed to start gRPC generator: %v", err)
 			startErrors = append(startErrors, fmt.Sprintf("gRPC: %v", err))
@@ -342,6 +599,28 @@ func (ws *WebServer) handleStart(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if config.Scenario.Enabled {
+		ws.scenarioGenerator = network.NewScenarioGenerator(
+			&config.Scenario.Scenario,
+			config.Scenario.BaseURL,
+			config.Scenario.Users,
+			10*time.Second,
+		)
+		if dataFeeder != nil {
+			ws.scenarioGenerator.SetDataFeeder(dataFeeder)
+		}
+		ws.scenarioGenerator.SetTLSConfig(tlsConfig)
+		ws.scenarioGenerator.SetAuth(authProvider)
+		ws.scenarioGenerator.SetUserPattern(config.Scenario.Pattern)
+		if err := ws.scenarioGenerator.Start(ws.ctx); err != nil {
+			ws.addLog("error", "Failed to start scenario generator: %v", err)
+			startErrors = append(startErrors, fmt.Sprintf("Scenario: %v", err))
+			ws.scenarioGenerator = nil
+		} else {
+			ws.addLog("success", "Scenario started: %s with %d virtual users", config.Scenario.Name, config.Scenario.Users)
+		}
+	}
+
 	if config.FakeLogsEnabled {
 		ws.fakeLogGen = logs.NewFakeLogGenerator(config.FakeLogsType, 1*time.Second, logger.GetLogger())
 		ws.fakeLogGen.Start(ws.ctx)
@@ -383,6 +662,139 @@ func (ws *WebServer) handleStop(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
 }
 
+// handleRun меняет параметры запущенного теста без остановки генераторов.
+func (ws *WebServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !ws.isRunning {
+		http.Error(w, "No stress test is running", http.StatusConflict)
+		return
+	}
+
+	var update control.RunUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		ws.addLog("error", "Invalid JSON in run update: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	generators := &control.Generators{
+		CPU:       ws.cpuGenerator,
+		Memory:    ws.memGenerator,
+		HTTP:      ws.httpGenerator,
+		WebSocket: ws.wsGenerator,
+		SSE:       ws.sseGenerator,
+		GRPC:      ws.grpcGenerator,
+		Scenario:  ws.scenarioGenerator,
+	}
+
+	applied, err := control.Apply(generators, &update)
+	if err != nil {
+		ws.addLog("error", "Run update rejected: %v", err)
+		http.Error(w, fmt.Sprintf("Run update rejected: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Конфигурация заменяется копией, чтобы /api/config и цели в /api/stats показывали новые значения.
+	ws.configMutex.Lock()
+	config := *ws.config
+	applyRunUpdate(&config, &update)
+	ws.config = &config
+	ws.configMutex.Unlock()
+
+	ws.addLog("info", "Run updated: %s", strings.Join(applied, ", "))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "updated",
+		"applied": applied,
+	})
+}
+
+func applyRunUpdate(config *WebConfiguration, update *control.RunUpdate) {
+	if u := update.CPU; u != nil {
+		if u.Load != nil {
+			config.CPU.Load = int(*u.Load)
+		}
+		if u.Pattern != nil {
+			config.CPU.Pattern = *u.Pattern
+		}
+	}
+	if u := update.Memory; u != nil {
+		if u.Target != nil {
+			config.Memory.Target = *u.Target
+		}
+		if u.Pattern != nil {
+			config.Memory.Pattern = *u.Pattern
+		}
+	}
+	if u := update.HTTP; u != nil {
+		if u.URL != nil {
+			config.HTTP.URL = *u.URL
+		}
+		if u.RPS != nil {
+			config.HTTP.RPS = *u.RPS
+		}
+		if u.Pattern != nil {
+			config.HTTP.Pattern = *u.Pattern
+		}
+		if u.MaxConcurrency != nil {
+			config.HTTP.MaxConcurrency = *u.MaxConcurrency
+		}
+	}
+	if u := update.WebSocket; u != nil {
+		if u.URL != nil {
+			config.WebSocket.URL = *u.URL
+		}
+		if u.CPS != nil {
+			config.WebSocket.CPS = *u.CPS
+		}
+		if u.Pattern != nil {
+			config.WebSocket.Pattern = *u.Pattern
+		}
+		if u.MaxConcurrency != nil {
+			config.WebSocket.MaxConcurrency = *u.MaxConcurrency
+		}
+	}
+	if u := update.SSE; u != nil {
+		if u.URL != nil {
+			config.SSE.URL = *u.URL
+		}
+		if u.CPS != nil {
+			config.SSE.CPS = *u.CPS
+		}
+		if u.Pattern != nil {
+			config.SSE.Pattern = *u.Pattern
+		}
+		if u.MaxConcurrency != nil {
+			config.SSE.MaxConcurrency = *u.MaxConcurrency
+		}
+	}
+	if u := update.GRPC; u != nil {
+		if u.RPS != nil {
+			config.GRPC.RPS = *u.RPS
+		}
+		if u.Pattern != nil {
+			config.GRPC.Pattern = *u.Pattern
+		}
+		if u.MaxConcurrency != nil {
+			config.GRPC.MaxConcurrency = *u.MaxConcurrency
+		}
+	}
+	if u := update.Scenario; u != nil {
+		if u.Users != nil {
+			config.Scenario.Users = *u.Users
+			config.Scenario.Stages = nil
+		}
+		if u.Pattern != nil {
+			config.Scenario.Pattern = *u.Pattern
+		}
+	}
+}
+
 func (ws *WebServer) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -415,6 +827,24 @@ func (ws *WebServer) handleStats(w http.ResponseWriter, r *http.Request) {
 		stats.HTTP.CurrentRPS = httpStats.CurrentRPS
 		stats.HTTP.TargetRPS = config.HTTP.RPS
 		stats.HTTP.SuccessRate = ws.httpGenerator.GetSuccessRate()
+		stats.HTTP.Latency = httpStats.Latency
+		stats.HTTP.ServiceTime = httpStats.ServiceTime
+		stats.HTTP.Dropped = httpStats.DroppedRequests
+		stats.HTTP.Late = httpStats.LateRequests
+		stats.HTTP.Workers = httpStats.Workers
+		stats.HTTP.StatusCodes = httpStats.StatusCodes
+		stats.HTTP.ErrorClasses = httpStats.ErrorClasses
+		stats.HTTP.Endpoints = httpStats.Endpoints
+		stats.HTTP.Checks = httpStats.Checks
+		stats.HTTP.CheckFailures = httpStats.CheckFailures
+		stats.HTTP.Phases = httpStats.Phases
+		stats.HTTP.Protocols = httpStats.Protocols
+		stats.HTTP.Addresses = httpStats.Addresses
+		stats.HTTP.NewConnections = httpStats.NewConnections
+		stats.HTTP.ReusedConnections = httpStats.ReusedConnections
+		stats.HTTP.ConnectionReuse = httpStats.ConnectionReuse
+		stats.HTTP.BytesSent = httpStats.BytesSent
+		stats.HTTP.BytesReceived = httpStats.BytesReceived
 	}
 
 	if ws.wsGenerator != nil && config.WebSocket.Enabled {
@@ -423,6 +853,33 @@ func (ws *WebServer) handleStats(w http.ResponseWriter, r *http.Request) {
 		stats.WebSocket.CurrentCPS = wsStats.CurrentCPS
 		stats.WebSocket.ActiveConnections = wsStats.ActiveConnections
 		stats.WebSocket.SuccessRate = ws.wsGenerator.GetSuccessRate()
+		stats.WebSocket.Latency = wsStats.Latency
+		stats.WebSocket.ServiceTime = wsStats.ServiceTime
+		stats.WebSocket.ProxyConnect = wsStats.ProxyConnect
+		stats.WebSocket.Addresses = wsStats.Addresses
+		stats.WebSocket.Dropped = wsStats.DroppedConnections
+		stats.WebSocket.Late = wsStats.LateConnections
+		stats.WebSocket.Workers = wsStats.Workers
+		stats.WebSocket.Steps = wsStats.Steps
+	}
+
+	if ws.sseGenerator != nil && config.SSE.Enabled {
+		sseStats := ws.sseGenerator.GetStats()
+		stats.SSE.Enabled = true
+		stats.SSE.CurrentCPS = sseStats.CurrentCPS
+		stats.SSE.ActiveStreams = sseStats.ActiveStreams
+		stats.SSE.TotalConnections = sseStats.TotalConnections
+		stats.SSE.SuccessRate = ws.sseGenerator.GetSuccessRate()
+		stats.SSE.EventsReceived = sseStats.EventsReceived
+		stats.SSE.Reconnects = sseStats.Reconnects
+		stats.SSE.BytesReceived = sseStats.BytesReceived
+		stats.SSE.Latency = sseStats.Latency
+		stats.SSE.ServiceTime = sseStats.ServiceTime
+		stats.SSE.InterArrival = sseStats.InterArrival
+		stats.SSE.EventTypes = sseStats.EventTypes
+		stats.SSE.Dropped = sseStats.DroppedConnections
+		stats.SSE.Late = sseStats.LateConnections
+		stats.SSE.Workers = sseStats.Workers
 	}
 
 	if ws.grpcGenerator != nil && config.GRPC.Enabled {
@@ -431,6 +888,40 @@ func (ws *WebServer) handleStats(w http.ResponseWriter, r *http.Request) {
 		stats.GRPC.CurrentRPS = grpcStats.CurrentRPS
 		stats.GRPC.TargetRPS = config.GRPC.RPS
 		stats.GRPC.SuccessRate = ws.grpcGenerator.GetSuccessRate()
+		stats.GRPC.Latency = grpcStats.Latency
+		stats.GRPC.ServiceTime = grpcStats.ServiceTime
+		stats.GRPC.Addresses = grpcStats.Addresses
+		stats.GRPC.Dropped = grpcStats.DroppedRequests
+		stats.GRPC.Late = grpcStats.LateRequests
+		stats.GRPC.Workers = grpcStats.Workers
+	}
+
+	if ws.scenarioGenerator != nil && config.Scenario.Enabled {
+		scenarioStats := ws.scenarioGenerator.GetStats()
+		stats.Scenario.Enabled = true
+		stats.Scenario.ActiveUsers = scenarioStats.ActiveUsers
+		stats.Scenario.TargetUsers = scenarioStats.TargetUsers
+		stats.Scenario.CurrentIPS = scenarioStats.CurrentIPS
+		stats.Scenario.CurrentRPS = scenarioStats.CurrentRPS
+		stats.Scenario.AvgIPS = scenarioStats.AvgIPS
+		stats.Scenario.AvgRPS = scenarioStats.AvgRPS
+		stats.Scenario.TotalIterations = scenarioStats.TotalIterations
+		stats.Scenario.FailedIterations = scenarioStats.FailedIterations
+		stats.Scenario.SuccessRate = ws.scenarioGenerator.GetSuccessRate()
+		stats.Scenario.Iteration = scenarioStats.Iteration
+		stats.Scenario.ErrorClasses = scenarioStats.ErrorClasses
+		stats.Scenario.Steps = scenarioStats.Steps
+		stats.Scenario.Checks = scenarioStats.Checks
+		stats.Scenario.CheckFailures = scenarioStats.CheckFailures
+	}
+
+	if ws.authProvider != nil {
+		authStats := ws.authProvider.Stats()
+		stats.Auth = &authStats
+	}
+
+	if ws.source != nil {
+		stats.Sources = ws.source.GetStats()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -474,6 +965,116 @@ func (ws *WebServer) handleConfig(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(config)
 }
 
+// handleCaptures отдаёт сохранённые запросы последнего теста; reason=failed или slow фильтрует по причине.
+// После остановки теста сохранённое остаётся доступным до следующего запуска.
+func (ws *WebServer) handleCaptures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reason := r.URL.Query().Get("reason")
+	if reason != "" && reason != network.CaptureFailed && reason != network.CaptureSlow {
+		http.Error(w, "Invalid reason, expected failed or slow", http.StatusBadRequest)
+		return
+	}
+
+	captures := ws.captures
+	if captures == nil {
+		http.Error(w, "Request capture is not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(captures.Snapshot(reason))
+}
+
+// Максимальный размер загружаемого HAR файла.
+const maxHARUploadSize = 64 << 20
+
+// HARImportResponse - результат импорта HAR: сценарий для поля scenario конфигурации
+// или набор шаблонов для -http-requests.
+type HARImportResponse struct {
+	Imported    int                 `json:"imported"`
+	Skipped     int                 `json:"skipped"`
+	Duration    string              `json:"duration"`
+	RecordedRPS float64             `json:"recordedRps"`
+	Scenario    *network.Scenario   `json:"scenario,omitempty"`
+	RequestMix  *network.RequestMix `json:"requestMix,omitempty"`
+}
+
+// handleImportHAR принимает HAR телом запроса или полем "har" multipart формы.
+// Фильтры и формат передаются параметрами: format, includeHosts, excludeHosts, excludeTypes, skipStatic, keepCookies, name.
+func (ws *WebServer) handleImportHAR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "scenario"
+	}
+	if format != "scenario" && format != "requests" {
+		http.Error(w, "format must be scenario or requests", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHARUploadSize)
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, formErr := r.FormFile("har")
+		if formErr != nil {
+			http.Error(w, fmt.Sprintf("Failed to read HAR: %v", formErr), http.StatusBadRequest)
+			return
+		}
+		data, err = io.ReadAll(file)
+		file.Close()
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read HAR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	recording, err := network.ParseHAR(data, &network.HARImportOptions{
+		IncludeHosts:        network.SplitList(query.Get("includeHosts")),
+		ExcludeHosts:        network.SplitList(query.Get("excludeHosts")),
+		ExcludeContentTypes: network.SplitList(query.Get("excludeTypes")),
+		SkipStatic:          query.Get("skipStatic") == "true",
+		KeepCookies:         query.Get("keepCookies") == "true",
+	})
+	if err != nil {
+		ws.addLog("error", "HAR import failed: %v", err)
+		http.Error(w, fmt.Sprintf("HAR import failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := HARImportResponse{
+		Imported:    len(recording.Requests),
+		Skipped:     recording.Skipped,
+		Duration:    recording.Duration.Round(time.Millisecond).String(),
+		RecordedRPS: recording.RecordedRPS(),
+	}
+	if format == "scenario" {
+		name := query.Get("name")
+		if name == "" {
+			name = "har"
+		}
+		response.Scenario = recording.Scenario(name)
+	} else {
+		response.RequestMix = recording.RequestMix()
+	}
+
+	ws.addLog("info", "HAR imported: %d requests, %d skipped", response.Imported, response.Skipped)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func (ws *WebServer) handleAgents(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -613,6 +1214,16 @@ func (ws *WebServer) handleAgentStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+func (ws *WebServer) handleAggregateAgentStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.agentManager.AggregateLatency(ws.ctx))
+}
+
 func (ws *WebServer) addLog(level, format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	
@@ -668,12 +1279,30 @@ func (ws *WebServer) validateConfig(config *WebConfiguration) error {
 	}
 
 	if config.HTTP.Enabled {
-		if config.HTTP.URL == "" {
+		if config.HTTP.URL == "" && len(config.HTTP.Requests) == 0 && len(config.HTTP.OpenAPI.Spec) == 0 {
 			return fmt.Errorf("HTTP URL cannot be empty")
 		}
+		if len(config.HTTP.Requests) > 0 && len(config.HTTP.OpenAPI.Spec) > 0 {
+			return fmt.Errorf("HTTP requests and OpenAPI spec cannot be used together")
+		}
+		if config.HTTP.GraphQL != nil && (len(config.HTTP.Requests) > 0 || len(config.HTTP.OpenAPI.Spec) > 0) {
+			return fmt.Errorf("HTTP GraphQL operations cannot be combined with requests or OpenAPI spec")
+		}
+		if _, _, err := network.SplitUnixURL(config.HTTP.URL); err != nil {
+			return err
+		}
 		if config.HTTP.RPS <= 0 {
 			return fmt.Errorf("HTTP RPS must be positive")
 		}
+		if config.HTTP.MaxConcurrency < 0 {
+			return fmt.Errorf("HTTP max concurrency must be non-negative")
+		}
+		if err := config.HTTP.Connection.Validate(); err != nil {
+			return err
+		}
+		if err := config.HTTP.Proxy.Validate(); err != nil {
+			return err
+		}
 		validMethods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
 		valid := false
 		for _, method := range validMethods {
@@ -705,12 +1334,21 @@ func (ws *WebServer) validateConfig(config *WebConfiguration) error {
 		if config.WebSocket.CPS <= 0 {
 			return fmt.Errorf("WebSocket CPS must be positive")
 		}
+		if config.WebSocket.MaxConcurrency < 0 {
+			return fmt.Errorf("WebSocket max concurrency must be non-negative")
+		}
+		if err := config.WebSocket.Proxy.Validate(); err != nil {
+			return err
+		}
 		if config.WebSocket.MessageInterval <= 0 {
 			return fmt.Errorf("WebSocket message interval must be positive")
 		}
 		if config.WebSocket.MessageSize <= 0 {
 			return fmt.Errorf("WebSocket message size must be positive")
 		}
+		if config.WebSocket.Script != nil && config.WebSocket.Message != "" {
+			return fmt.Errorf("WebSocket script and message cannot be used together")
+		}
 		validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
 		valid := false
 		for _, pattern := range validPatterns {
@@ -724,6 +1362,32 @@ func (ws *WebServer) validateConfig(config *WebConfiguration) error {
 		}
 	}
 
+	if config.SSE.Enabled {
+		if config.SSE.URL == "" {
+			return fmt.Errorf("SSE URL cannot be empty")
+		}
+		if config.SSE.CPS <= 0 {
+			return fmt.Errorf("SSE CPS must be positive")
+		}
+		if config.SSE.Streams < 0 || config.SSE.MaxConcurrency < 0 {
+			return fmt.Errorf("SSE streams and max concurrency must be non-negative")
+		}
+		if config.SSE.Hold < 0 || config.SSE.ReconnectDelay < 0 {
+			return fmt.Errorf("SSE hold and reconnect delay must be non-negative")
+		}
+		validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+		valid := false
+		for _, pattern := range validPatterns {
+			if config.SSE.Pattern == pattern {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid SSE pattern: %s", config.SSE.Pattern)
+		}
+	}
+
 	if config.GRPC.Enabled {
 		if config.GRPC.Address == "" {
 			return fmt.Errorf("gRPC address cannot be empty")
@@ -731,6 +1395,9 @@ func (ws *WebServer) validateConfig(config *WebConfiguration) error {
 		if config.GRPC.RPS <= 0 {
 			return fmt.Errorf("gRPC RPS must be positive")
 		}
+		if config.GRPC.MaxConcurrency < 0 {
+			return fmt.Errorf("gRPC max concurrency must be non-negative")
+		}
 		validMethods := []string{"health_check", "unary", "server_stream", "client_stream", "bidi_stream"}
 		valid := false
 		for _, method := range validMethods {
@@ -755,6 +1422,28 @@ func (ws *WebServer) validateConfig(config *WebConfiguration) error {
 		}
 	}
 
+	if config.Scenario.Enabled {
+		if config.Scenario.Users <= 0 && len(config.Scenario.Stages) == 0 {
+			return fmt.Errorf("scenario users must be positive")
+		}
+		if config.Scenario.Pattern != "" {
+			validPatterns := []string{"constant", "spike", "cycle", "ramp", "random"}
+			valid := false
+			for _, pattern := range validPatterns {
+				if config.Scenario.Pattern == pattern {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("invalid scenario pattern: %s", config.Scenario.Pattern)
+			}
+		}
+		if len(config.Scenario.Steps) == 0 {
+			return fmt.Errorf("scenario must contain at least one step")
+		}
+	}
+
 	if config.FakeLogsEnabled {
 		validTypes := []string{"java", "web", "microservice", "database", "ecommerce", "generic"}
 		valid := false
@@ -769,6 +1458,20 @@ func (ws *WebServer) validateConfig(config *WebConfiguration) error {
 		}
 	}
 
+	if len(config.Data.Rows) > 0 && config.Data.Mode != "" {
+		validModes := []string{"sequential", "random", "unique"}
+		valid := false
+		for _, mode := range validModes {
+			if config.Data.Mode == mode {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid data mode: %s", config.Data.Mode)
+		}
+	}
+
 	return nil
 }
 
@@ -786,6 +1489,14 @@ func (ws *WebServer) stopAllGenerators() {
 	if ws.httpGenerator != nil {
 		ws.httpGenerator.Stop()
 		ws.httpGenerator = nil
+
+		if ws.captures != nil && ws.captures.File() != "" {
+			if err := ws.captures.WriteFile(ws.captures.File()); err != nil {
+				ws.addLog("error", "Failed to write captures to %s: %v", ws.captures.File(), err)
+			} else {
+				ws.addLog("info", "Captures written to %s", ws.captures.File())
+			}
+		}
 	}
 
 	if ws.wsGenerator != nil {
@@ -793,11 +1504,24 @@ func (ws *WebServer) stopAllGenerators() {
 		ws.wsGenerator = nil
 	}
 
+	if ws.sseGenerator != nil {
+		ws.sseGenerator.Stop()
+		ws.sseGenerator = nil
+	}
+
 	if ws.grpcGenerator != nil {
 		ws.grpcGenerator.Stop()
 		ws.grpcGenerator = nil
 	}
 
+	if ws.scenarioGenerator != nil {
+		ws.scenarioGenerator.Stop()
+		ws.scenarioGenerator = nil
+	}
+
+	ws.authProvider = nil
+	ws.source = nil
+
 	if ws.fakeLogGen != nil {
 		ws.fakeLogGen.Stop()
 		ws.fakeLogGen = nil